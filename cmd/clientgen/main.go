@@ -0,0 +1,350 @@
+// Command clientgen writes the typed Go client in pkg/client from the
+// OpenAPI document built by the openapi package.
+//
+//	go run ./cmd/clientgen -o pkg/client/client_gen.go
+//	go run ./cmd/clientgen -o pkg/client/client_gen.go -check
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hungpv1995/golang_training_2025/internal/openapi"
+)
+
+func main() {
+	output := flag.String("o", "client_gen.go", "file to write")
+	pkg := flag.String("package", "client", "package name of the generated file")
+	check := flag.Bool("check", false, "fail if the file is out of date instead of writing it")
+	flag.Parse()
+
+	src, err := generate(openapi.Spec(), *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *check {
+		current, err := os.ReadFile(*output)
+		if err != nil || !bytes.Equal(current, src) {
+			fmt.Fprintf(os.Stderr, "%s is out of date; run go generate ./pkg/client\n", *output)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator accumulates the generated source and the imports it needs
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	skipped []string
+}
+
+func generate(doc *openapi.Document, pkg string) ([]byte, error) {
+	g := &generator{imports: map[string]bool{"context": true}}
+
+	g.types(doc)
+	g.operations(doc)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen from the OpenAPI document. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	fmt.Fprintf(&out, "import (\n")
+	for _, imp := range sortedKeys(g.imports) {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	if len(g.skipped) > 0 {
+		fmt.Fprintf(&out, "// Operations without a JSON response are not generated: %s\n\n", strings.Join(g.skipped, ", "))
+	}
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not compile: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// types writes a struct for every component schema
+func (g *generator) types(doc *openapi.Document) {
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		schema := doc.Components.Schemas[name]
+		required := make(map[string]bool, len(schema.Required))
+		for _, r := range schema.Required {
+			required[r] = true
+		}
+
+		g.printf("// %s is the %s schema\n", name, name)
+		g.printf("type %s struct {\n", name)
+		for _, prop := range sortedKeys(schema.Properties) {
+			tag := prop
+			if !required[prop] {
+				tag += ",omitempty"
+			}
+			g.printf("\t%s %s `json:%q`\n", exportedName(prop), g.goType(schema.Properties[prop]), tag)
+		}
+		g.printf("}\n\n")
+	}
+}
+
+func (g *generator) goType(schema *openapi.Schema) string {
+	inner, null := schema.Nullable()
+	typ := g.baseType(inner)
+	if null && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") {
+		return "*" + typ
+	}
+	return typ
+}
+
+func (g *generator) baseType(schema *openapi.Schema) string {
+	if name := schema.RefName(); name != "" {
+		return name
+	}
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items)
+	case "object":
+		if schema.AdditionalProperties != nil {
+			return "map[string]" + g.goType(schema.AdditionalProperties)
+		}
+		return "map[string]any"
+	default:
+		return "any"
+	}
+}
+
+type operation struct {
+	method, path string
+	op           *openapi.Operation
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// operations writes a Client method for every operation with a JSON or empty
+// success response
+func (g *generator) operations(doc *openapi.Document) {
+	var ops []operation
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			ops = append(ops, operation{method: strings.ToUpper(method), path: path, op: op})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].op.OperationID < ops[j].op.OperationID })
+
+	for _, o := range ops {
+		name := exportedName(o.op.OperationID)
+		res, ok := g.result(o.op)
+		if !ok {
+			g.skipped = append(g.skipped, o.method+" "+o.path)
+			continue
+		}
+
+		// Header params are left to client options such as WithUserID
+		var params, pathArgs []string
+		var query []string
+		for _, p := range o.op.Parameters {
+			arg := unexportedName(p.Name)
+			switch p.In {
+			case "path":
+				params = append(params, arg+" int")
+				pathArgs = append(pathArgs, arg)
+			case "query":
+				query = append(query, g.queryParam(p, arg))
+				if p.Schema.Type == "integer" {
+					params = append(params, arg+" int")
+				} else {
+					params = append(params, arg+" string")
+				}
+			}
+		}
+
+		bodyArg := "nil"
+		if rb := o.op.RequestBody; rb != nil {
+			typ := g.goType(rb.Content["application/json"].Schema)
+			if rb.Required {
+				params = append(params, "body "+typ)
+				bodyArg = "body"
+			} else {
+				params = append(params, "body *"+typ)
+				bodyArg = "payload"
+			}
+		}
+
+		path := strconv.Quote(o.path)
+		if len(pathArgs) > 0 {
+			g.imports["fmt"] = true
+			path = fmt.Sprintf("fmt.Sprintf(%q, %s)", pathParam.ReplaceAllString(o.path, "%d"), strings.Join(pathArgs, ", "))
+		}
+		queryArg := "nil"
+		if len(query) > 0 {
+			g.imports["net/url"] = true
+			queryArg = "query"
+		}
+
+		g.printf("// %s calls %s %s\n//\n// %s\n", name, o.method, o.path, o.op.Summary)
+		if len(query) > 0 && hasOptional(o.op.Parameters) {
+			g.printf("// Optional query parameters are omitted when zero.\n")
+		}
+		signature := fmt.Sprintf("func (c *Client) %s(%s)", name, strings.Join(append([]string{"ctx context.Context"}, params...), ", "))
+		g.printf("%s %s {\n", signature, res.signature())
+		if bodyArg == "payload" {
+			// A nil pointer must not reach do as a non-nil interface
+			g.printf("\tvar payload any\n\tif body != nil {\n\t\tpayload = body\n\t}\n")
+		}
+		if len(query) > 0 {
+			g.printf("\tquery := url.Values{}\n%s", strings.Join(query, ""))
+		}
+		call := fmt.Sprintf("c.do(ctx, %q, %s, %s, %s", o.method, path, queryArg, bodyArg)
+		switch {
+		case res.typ == "":
+			g.printf("\treturn %s, nil)\n}\n\n", call)
+		case res.slice:
+			g.printf("\tvar out %s\n\tif err := %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n", res.typ, call)
+		default:
+			g.printf("\tvar out %s\n\tif err := %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n}\n\n", res.typ, call)
+		}
+	}
+}
+
+// queryParam returns the statement adding a query parameter; optional ones
+// are skipped when their argument is the zero value
+func (g *generator) queryParam(p openapi.Parameter, arg string) string {
+	value, zero := arg, `""`
+	if p.Schema.Type == "integer" {
+		g.imports["strconv"] = true
+		value, zero = "strconv.Itoa("+arg+")", "0"
+	}
+	set := fmt.Sprintf("query.Set(%q, %s)\n", p.Name, value)
+	if p.Required {
+		return "\t" + set
+	}
+	return fmt.Sprintf("\tif %s != %s {\n\t\t%s\t}\n", arg, zero, set)
+}
+
+func hasOptional(params []openapi.Parameter) bool {
+	for _, p := range params {
+		if p.In == "query" && !p.Required {
+			return true
+		}
+	}
+	return false
+}
+
+type result struct {
+	typ   string
+	slice bool
+}
+
+func (r result) signature() string {
+	switch {
+	case r.typ == "":
+		return "error"
+	case r.slice:
+		return "(" + r.typ + ", error)"
+	default:
+		return "(*" + r.typ + ", error)"
+	}
+}
+
+// result picks the Go result type from the first 2xx response. Only named
+// schemas and lists of them are supported; anything else is skipped.
+func (g *generator) result(op *openapi.Operation) (result, bool) {
+	for _, code := range sortedKeys(op.Responses) {
+		status, _ := strconv.Atoi(code)
+		if status < 200 || status >= 300 {
+			continue
+		}
+		resp := op.Responses[code]
+		if status == http.StatusNoContent || len(resp.Content) == 0 {
+			return result{}, true
+		}
+		media, ok := resp.Content["application/json"]
+		if !ok || media.Schema == nil {
+			return result{}, false
+		}
+		if name := media.Schema.RefName(); name != "" {
+			return result{typ: name}, true
+		}
+		if media.Schema.Type == "array" && media.Schema.Items.RefName() != "" {
+			return result{typ: "[]" + media.Schema.Items.RefName(), slice: true}, true
+		}
+		return result{}, false
+	}
+	return result{}, false
+}
+
+// initialisms are kept upper-case in generated names, as golint expects
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API", "ms": "MS"}
+
+// exportedName turns snake_case and camelCase names into Go identifiers,
+// e.g. "request_id" into "RequestID" and "createAPIKey" into "CreateAPIKey"
+func exportedName(name string) string {
+	var out strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if upper, ok := initialisms[part]; ok {
+			out.WriteString(upper)
+			continue
+		}
+		runes := []rune(part)
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		out.WriteString(string(runes))
+	}
+	return out.String()
+}
+
+func unexportedName(name string) string {
+	exported := exportedName(name)
+	if upper, ok := initialisms[strings.ToLower(exported)]; ok && upper == exported {
+		return strings.ToLower(exported)
+	}
+	runes := []rune(exported)
+	runes[0] = unicode.ToLower(runes[0])
+	// Parameters such as "type" would not compile as argument names
+	if token.IsKeyword(string(runes)) {
+		return string(runes) + "_"
+	}
+	return string(runes)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
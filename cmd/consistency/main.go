@@ -0,0 +1,101 @@
+// Command consistency compares every post in Postgres with its Elasticsearch
+// document and cached Redis entry and prints the drift found as JSON. It
+// exits with status 2 when drift remains, so it can alert from cron.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/clients"
+	"github.com/hungpv1995/golang_training_2025/internal/config"
+	"github.com/hungpv1995/golang_training_2025/internal/consistency"
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/search"
+)
+
+// exitDrift is the exit status when drift was found and not repaired
+const exitDrift = 2
+
+func main() {
+	// Connection settings are the server's, from the same file, env vars or flags
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	repair := fs.Bool("repair", false, "reindex or remove drifted documents and evict drifted cache entries")
+	afterID := fs.Int("after-id", 0, "start after this post ID")
+	limit := fs.Int("limit", 0, "check at most this many posts; 0 checks every post")
+	batchSize := fs.Int("batch-size", consistency.DefaultBatchSize, "posts compared at a time")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
+	// The report goes to stdout, so logs go to stderr
+	logger, _, err := logging.New(cfg.Logging.Policy(), os.Stderr)
+	if err != nil {
+		log.Fatal("Failed to initialize logging: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := clients.OpenPostgres(cfg.Database)
+	if err != nil {
+		fatal(logger, "failed to initialize database", err)
+	}
+	esClient, esTransport, err := clients.OpenElasticsearch(cfg.Elasticsearch)
+	if err != nil {
+		fatal(logger, "failed to initialize elasticsearch", err)
+	}
+
+	// Only a Redis cache is shared; a memory cache lives in each server replica
+	// and is checked through POST /admin/consistency instead
+	var redisClient *redis.Client
+	var postCache cache.Cache
+	if cfg.Cache.Backend == cache.BackendRedis {
+		if redisClient, err = clients.OpenRedis(cfg.Redis); err != nil {
+			fatal(logger, "failed to initialize redis", err)
+		}
+		if postCache, err = cache.New(cfg.Cache.Policy(), redisClient, logger); err != nil {
+			fatal(logger, "failed to initialize cache", err)
+		}
+	} else {
+		logger.Info("cache is not checked", slog.String("backend", cfg.Cache.Backend))
+	}
+
+	checker := consistency.NewChecker(repository.NewPostRepository(db, logger), search.NewElasticSearch(esClient, cfg.Elasticsearch.IndexPolicy(), logger), postCache, logger)
+	report, err := checker.Run(ctx, consistency.Options{
+		AfterID:   *afterID,
+		Limit:     *limit,
+		BatchSize: *batchSize,
+		Repair:    *repair,
+	})
+	clients.Close(logger, db, redisClient, esTransport)
+	if err != nil {
+		fatal(logger, "consistency check failed", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatal(logger, "failed to write report", err)
+	}
+	if len(report.Drift) > report.Repaired {
+		os.Exit(exitDrift)
+	}
+}
+
+// fatal logs a failure and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
@@ -0,0 +1,140 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/activity")
+
+// Config controls how long the activity log is kept
+type Config struct {
+	// Retention is the age past which entries are pruned; 0 keeps them forever
+	Retention time.Duration
+	// Archive moves pruned entries to activity_logs_archive instead of deleting them
+	Archive        bool
+	PruneInterval  time.Duration
+	PruneBatchSize int
+}
+
+// DefaultConfig returns the retention policy used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Retention:      90 * 24 * time.Hour,
+		PruneInterval:  time.Hour,
+		PruneBatchSize: 1000,
+	}
+}
+
+// Pruner removes activity log entries past the retention period in the
+// background. Every replica prunes; each batch skips rows another one holds.
+type Pruner struct {
+	repo   *repository.ActivityRepository
+	cfg    Config
+	logger *slog.Logger
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewPruner(repo *repository.ActivityRepository, cfg Config, logger *slog.Logger) *Pruner {
+	return &Pruner{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start prunes once and then every PruneInterval, until Shutdown. Nothing
+// runs when retention is disabled.
+func (p *Pruner) Start() {
+	if p.cfg.Retention <= 0 {
+		return
+	}
+	p.wg.Add(1)
+	go p.pruneLoop()
+}
+
+// Shutdown stops the prune loop, waiting for a running prune to finish
+func (p *Pruner) Shutdown(ctx context.Context) error {
+	p.once.Do(func() { close(p.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pruner) pruneLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.PruneInterval)
+		if _, err := p.Prune(ctx); err != nil {
+			p.logger.ErrorContext(ctx, "failed to prune activity logs", slog.Any("error", err))
+		}
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Prune removes, in batches, every entry older than the retention period and
+// returns how many were removed
+func (p *Pruner) Prune(ctx context.Context) (pruned int, err error) {
+	ctx, span := tracer.Start(ctx, "activity.Prune")
+	defer tracing.End(span, &err)
+
+	result := "deleted"
+	if p.cfg.Archive {
+		result = "archived"
+	}
+	defer func() {
+		if pruned > 0 {
+			metrics.ActivityPruned.WithLabelValues(result).Add(float64(pruned))
+			p.logger.InfoContext(ctx, "pruned activity logs", slog.Int("entries", pruned), slog.Bool("archived", p.cfg.Archive))
+		}
+	}()
+
+	cutoff := time.Now().Add(-p.cfg.Retention)
+	for {
+		n, err := p.repo.PruneActivity(ctx, cutoff, p.cfg.PruneBatchSize, p.cfg.Archive)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to prune entries before %s: %w", cutoff.Format(time.RFC3339), err)
+		}
+		pruned += n
+		if n < p.cfg.PruneBatchSize {
+			return pruned, nil
+		}
+
+		select {
+		case <-p.stop:
+			return pruned, nil
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, keyPrefix+prefix+"_") {
+		t.Errorf("key %q does not start with %q", key, keyPrefix+prefix+"_")
+	}
+	if got, ok := ParseKey(key); !ok || got != prefix {
+		t.Errorf("ParseKey(generated) = %q, %v; want %q, true", got, ok, prefix)
+	}
+	if !matches(key, hash) {
+		t.Error("generated key does not match its hash")
+	}
+	if matches(key+"x", hash) {
+		t.Error("altered key matches the hash")
+	}
+
+	other, otherPrefix, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key || otherPrefix == prefix {
+		t.Error("two generated keys share a key or prefix")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		key        string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{key: "bk_0a1b2c3d_secret", wantPrefix: "0a1b2c3d", wantOK: true},
+		{key: "bk_0a1b2c3d_secret_with_underscores", wantPrefix: "0a1b2c3d", wantOK: true},
+		{key: ""},
+		{key: "admin-token"},
+		{key: "bk_"},
+		{key: "bk_0a1b2c3d"},
+		{key: "bk_0a1b2c3d_"},
+		{key: "bk__secret"},
+		{key: "xx_0a1b2c3d_secret"},
+	}
+	for _, tt := range tests {
+		prefix, ok := ParseKey(tt.key)
+		if prefix != tt.wantPrefix || ok != tt.wantOK {
+			t.Errorf("ParseKey(%q) = %q, %v; want %q, %v", tt.key, prefix, ok, tt.wantPrefix, tt.wantOK)
+		}
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	// SHA-256 of "abc"
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashKey("abc"); got != want {
+		t.Errorf("HashKey(abc) = %s, want %s", got, want)
+	}
+}
+
+func TestCanGrant(t *testing.T) {
+	manager := &Principal{KeyID: 1, Name: "manager", Scopes: []string{ScopeKeysManage, ScopePostsRead}}
+	admin := &Principal{KeyID: 2, Name: "root", Scopes: []string{ScopeAdmin}}
+	adminToken := &Principal{Name: "admin", Scopes: Scopes}
+
+	tests := []struct {
+		name  string
+		p     *Principal
+		scope string
+		want  bool
+	}{
+		{name: "held scope", p: manager, scope: ScopePostsRead, want: true},
+		{name: "own management scope", p: manager, scope: ScopeKeysManage, want: true},
+		{name: "scope not held", p: manager, scope: ScopePostsWrite, want: false},
+		{name: "admin not held", p: manager, scope: ScopeAdmin, want: false},
+		{name: "admin key grants anything", p: admin, scope: ScopeWebhooksManage, want: true},
+		{name: "admin token grants admin", p: adminToken, scope: ScopeAdmin, want: true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.CanGrant(tt.scope); got != tt.want {
+			t.Errorf("%s: CanGrant(%q) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
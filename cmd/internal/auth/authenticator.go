@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+)
+
+// ErrInvalidKey is returned for unknown, revoked, expired or malformed keys
+var ErrInvalidKey = errors.New("invalid API key")
+
+// lastUsedInterval throttles last_used_at writes to one per key per interval
+const lastUsedInterval = time.Minute
+
+// Authenticator resolves API keys to principals
+type Authenticator struct {
+	repo       *repository.APIKeyRepository
+	adminToken string
+	timeout    time.Duration
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	lastUsed map[int]time.Time
+	wg       sync.WaitGroup
+}
+
+// NewAuthenticator builds an authenticator. adminToken, when set, is accepted
+// as a key with every scope so the first real keys can be created. timeout
+// bounds each last-used write.
+func NewAuthenticator(repo *repository.APIKeyRepository, adminToken string, timeout time.Duration, logger *slog.Logger) *Authenticator {
+	return &Authenticator{
+		repo:       repo,
+		adminToken: adminToken,
+		timeout:    timeout,
+		logger:     logger,
+		lastUsed:   make(map[int]time.Time),
+	}
+}
+
+// Authenticate returns the principal for key
+func (a *Authenticator) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if a.adminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(a.adminToken)) == 1 {
+		return &Principal{Name: "admin", Scopes: Scopes}, nil
+	}
+
+	prefix, ok := ParseKey(key)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	stored, hash, err := a.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// revoked_at may lie in the future while a rotated key is in its grace period
+	revoked := stored.RevokedAt != nil && !now.Before(*stored.RevokedAt)
+	expired := stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt)
+	if !matches(key, hash) || revoked || expired {
+		return nil, ErrInvalidKey
+	}
+
+	a.touch(ctx, stored.ID, now)
+
+	return &Principal{
+		KeyID:  stored.ID,
+		Name:   stored.Name,
+		Scopes: stored.Scopes,
+	}, nil
+}
+
+// touch records that a key was used, at most once per lastUsedInterval, in
+// the background so authentication never waits on the write
+func (a *Authenticator) touch(ctx context.Context, keyID int, now time.Time) {
+	a.mu.Lock()
+	if last, ok := a.lastUsed[keyID]; ok && now.Sub(last) < lastUsedInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastUsed[keyID] = now
+	a.mu.Unlock()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.timeout)
+		defer cancel()
+		if err := a.repo.TouchAPIKey(ctx, keyID, now); err != nil {
+			a.logger.WarnContext(ctx, "failed to record api key use", slog.Int("key_id", keyID), slog.Any("error", err))
+		}
+	}()
+}
+
+// Shutdown waits for pending last-used writes. Call it after the HTTP server
+// has stopped and before the database is closed.
+func (a *Authenticator) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Supported cache backends
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendNoop   = "noop"
+)
+
+// Entity types with their own TTL
+const (
+	EntityPost = "post"
+)
+
+// Results recorded in the cache_operations_total metric
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+// Cache is the contract every cache backend implements. A post's reaction
+// counts are cached next to it as counters, so reacting does not evict the
+// post: GetPost returns the post with the current counters.
+type Cache interface {
+	GetPost(ctx context.Context, postID int) (*models.Post, error)
+	SetPost(ctx context.Context, post *models.Post) error
+	SetPosts(ctx context.Context, posts []*models.Post) error
+	InvalidatePost(ctx context.Context, postID int) error
+	// IncrReaction adjusts a cached reaction counter; nothing is cached
+	// when the post is not
+	IncrReaction(ctx context.Context, postID int, reaction string, delta int) error
+	Ping(ctx context.Context) error
+}
+
+// Config describes the cache policy shared by all backends
+type Config struct {
+	Backend string
+	// Namespace is prepended to every key, e.g. "staging" gives "staging:post:1"
+	Namespace string
+	// TTLs holds the expiration per entity type; DefaultTTL is used for the rest
+	TTLs       map[string]time.Duration
+	DefaultTTL time.Duration
+	// Jitter adds a random duration in [0, Jitter) to every TTL so that
+	// entries written together do not expire together
+	Jitter time.Duration
+	Codec  string
+	// CompressThreshold gzips encoded values larger than this many bytes; 0 disables compression
+	CompressThreshold int
+	// MaxEntries caps the posts held by the memory backend, evicting the
+	// least recently used; 0 means no limit
+	MaxEntries int
+}
+
+// DefaultConfig returns the policy used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Backend:    BackendRedis,
+		TTLs:       map[string]time.Duration{EntityPost: 5 * time.Minute},
+		DefaultTTL: 5 * time.Minute,
+		Codec:      CodecJSON,
+		MaxEntries: 10000,
+	}
+}
+
+// New builds the cache backend selected by cfg. The Redis client is only
+// required for the redis backend.
+func New(cfg Config, client *redis.Client, logger *slog.Logger) (Cache, error) {
+	codec, err := NewCodec(cfg.Codec, cfg.CompressThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Backend {
+	case BackendRedis, "":
+		if client == nil {
+			return nil, fmt.Errorf("redis cache backend requires a redis client")
+		}
+		return NewRedisCache(client, cfg, codec, logger), nil
+	case BackendMemory:
+		return NewMemoryCache(cfg, codec, logger), nil
+	case BackendNoop:
+		return NoopCache{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
+
+// key builds the namespaced cache key for an entity
+func (cfg Config) key(entity string, id int) string {
+	if cfg.Namespace == "" {
+		return fmt.Sprintf("%s:%d", entity, id)
+	}
+	return fmt.Sprintf("%s:%s:%d", strings.TrimSuffix(cfg.Namespace, ":"), entity, id)
+}
+
+// reactionsKey is the key of a post's reaction counters, next to the post's key
+func (cfg Config) reactionsKey(postID int) string {
+	return cfg.key(EntityPost, postID) + ":reactions"
+}
+
+// ttl returns the expiration for an entity type with jitter applied
+func (cfg Config) ttl(entity string) time.Duration {
+	ttl, ok := cfg.TTLs[entity]
+	if !ok {
+		ttl = cfg.DefaultTTL
+	}
+	if cfg.Jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(cfg.Jitter)))
+	}
+	return ttl
+}
+
+// observe counts one cache operation by backend, operation and result
+func observe(backend, operation, result string) {
+	metrics.CacheOperations.WithLabelValues(backend, operation, result).Inc()
+}
+
+// writeResult maps the error of a write operation to its metric result
+func writeResult(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultOK
+}
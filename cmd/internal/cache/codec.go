@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Supported serialization codecs
+const (
+	CodecJSON    = "json"
+	CodecMsgpack = "msgpack"
+)
+
+// gzipMagic is the header every gzip stream starts with. Neither JSON objects
+// nor msgpack maps can start with it, so it marks compressed values.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Codec serializes cached values
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// NewCodec returns the codec by name, wrapped with gzip compression for
+// values larger than compressThreshold bytes when the threshold is positive
+func NewCodec(name string, compressThreshold int) (Codec, error) {
+	var codec Codec
+	switch name {
+	case CodecJSON, "":
+		codec = jsonCodec{}
+	case CodecMsgpack:
+		codec = msgpackCodec{}
+	default:
+		return nil, fmt.Errorf("unknown cache codec %q", name)
+	}
+
+	if compressThreshold > 0 {
+		codec = gzipCodec{inner: codec, threshold: compressThreshold}
+	}
+	return codec, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec reuses the json struct tags so both codecs produce the same field names
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+type gzipCodec struct {
+	inner     Codec
+	threshold int
+}
+
+func (c gzipCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil || len(data) <= c.threshold {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Unmarshal(data []byte, v interface{}) error {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return c.inner.Unmarshal(data, v)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decompress value: %w", err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return c.inner.Unmarshal(raw, v)
+}
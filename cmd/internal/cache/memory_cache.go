@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// memorySweepInterval is how often expired entries are dropped even if
+// nobody reads them
+const memorySweepInterval = time.Minute
+
+// memoryEntry is a cached post with its reaction counters, which expire with it
+type memoryEntry struct {
+	key       string
+	data      []byte
+	reactions map[string]int
+	expiresAt time.Time
+}
+
+// MemoryCache is an in-process cache for local development and single-replica
+// deployments. Values are stored encoded so callers never share pointers.
+// At most cfg.MaxEntries posts are kept; the least recently used go first.
+type MemoryCache struct {
+	mu sync.Mutex
+	// entries indexes lru, whose front is the most recently used entry
+	entries   map[string]*list.Element
+	lru       *list.List
+	lastSweep time.Time
+	cfg       Config
+	codec     Codec
+	logger    *slog.Logger
+}
+
+func NewMemoryCache(cfg Config, codec Codec, logger *slog.Logger) *MemoryCache {
+	return &MemoryCache{
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+		lastSweep: time.Now(),
+		cfg:       cfg,
+		codec:     codec,
+		logger:    logger,
+	}
+}
+
+// GetPost retrieves a post from memory
+func (c *MemoryCache) GetPost(ctx context.Context, postID int) (*models.Post, error) {
+	cacheKey := c.cfg.key(EntityPost, postID)
+
+	// Expiry is checked and acted on under one lock, so a SetPost racing
+	// with the read of an expired entry is never deleted
+	c.mu.Lock()
+	elem, ok := c.entries[cacheKey]
+	if !ok {
+		c.mu.Unlock()
+		observe(BackendMemory, "get", resultMiss)
+		c.logger.DebugContext(ctx, "cache miss", slog.String("key", cacheKey))
+		return nil, nil // Cache miss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		c.mu.Unlock()
+		observe(BackendMemory, "get", resultMiss)
+		c.logger.DebugContext(ctx, "cache entry expired", slog.String("key", cacheKey))
+		return nil, nil // Expired
+	}
+	c.lru.MoveToFront(elem)
+	data := entry.data
+	reactions := make(map[string]int, len(entry.reactions))
+	for reaction, n := range entry.reactions {
+		reactions[reaction] = n
+	}
+	c.mu.Unlock()
+
+	var post models.Post
+	if err := c.codec.Unmarshal(data, &post); err != nil {
+		observe(BackendMemory, "get", resultError)
+		return nil, fmt.Errorf("failed to unmarshal cached data: %w", err)
+	}
+	post.Reactions = reactions
+
+	observe(BackendMemory, "get", resultHit)
+	c.logger.DebugContext(ctx, "cache hit", slog.String("key", cacheKey))
+	return &post, nil
+}
+
+// SetPost stores a post in memory with the configured post TTL
+func (c *MemoryCache) SetPost(ctx context.Context, post *models.Post) error {
+	data, err := c.codec.Marshal(post)
+	observe(BackendMemory, "set", writeResult(err))
+	if err != nil {
+		return fmt.Errorf("failed to marshal post: %w", err)
+	}
+
+	now := time.Now()
+	entry := &memoryEntry{
+		key:       c.cfg.key(EntityPost, post.ID),
+		data:      data,
+		reactions: make(map[string]int, len(post.Reactions)),
+		expiresAt: now.Add(c.cfg.ttl(EntityPost)),
+	}
+	for reaction, n := range post.Reactions {
+		entry.reactions[reaction] = n
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries {
+		c.remove(c.lru.Back())
+		observe(BackendMemory, "evict", resultOK)
+	}
+	return nil
+}
+
+// SetPosts stores several posts in memory
+func (c *MemoryCache) SetPosts(ctx context.Context, posts []*models.Post) error {
+	for _, post := range posts {
+		if err := c.SetPost(ctx, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidatePost removes a post from memory
+func (c *MemoryCache) InvalidatePost(ctx context.Context, postID int) error {
+	c.mu.Lock()
+	if elem, ok := c.entries[c.cfg.key(EntityPost, postID)]; ok {
+		c.remove(elem)
+	}
+	c.mu.Unlock()
+	observe(BackendMemory, "invalidate", resultOK)
+
+	return nil
+}
+
+// IncrReaction adjusts a reaction counter of a cached post
+func (c *MemoryCache) IncrReaction(ctx context.Context, postID int, reaction string, delta int) error {
+	c.mu.Lock()
+	if elem, ok := c.entries[c.cfg.key(EntityPost, postID)]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.reactions[reaction] += delta
+		if entry.reactions[reaction] <= 0 {
+			delete(entry.reactions, reaction)
+		}
+	}
+	c.mu.Unlock()
+	observe(BackendMemory, "incr", resultOK)
+
+	return nil
+}
+
+// Ping always succeeds for the in-memory cache
+func (c *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// remove drops an entry; the caller holds mu
+func (c *MemoryCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*memoryEntry).key)
+	c.lru.Remove(elem)
+}
+
+// sweep drops expired entries at most once per memorySweepInterval, so
+// posts nobody reads again do not stay in memory; the caller holds mu
+func (c *MemoryCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < memorySweepInterval {
+		return
+	}
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if now.After(elem.Value.(*memoryEntry).expiresAt) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+	c.lastSweep = now
+}
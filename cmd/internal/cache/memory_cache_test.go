@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+func newTestMemoryCache(t *testing.T, cfg Config) *MemoryCache {
+	t.Helper()
+	codec, err := NewCodec(CodecJSON, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMemoryCache(cfg, codec, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.MaxEntries = 2
+	c := newTestMemoryCache(t, cfg)
+
+	for id := 1; id <= 2; id++ {
+		if err := c.SetPost(ctx, &models.Post{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading post 1 makes post 2 the least recently used
+	if post, _ := c.GetPost(ctx, 1); post == nil {
+		t.Fatal("post 1 missing before eviction")
+	}
+	if err := c.SetPost(ctx, &models.Post{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int]bool{1: true, 2: false, 3: true} {
+		post, err := c.GetPost(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := post != nil; got != want {
+			t.Errorf("post %d cached = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.TTLs[EntityPost] = time.Hour
+	c := newTestMemoryCache(t, cfg)
+
+	if err := c.SetPost(ctx, &models.Post{ID: 1, Reactions: map[string]int{"like": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.IncrReaction(ctx, 1, "like", 1); err != nil {
+		t.Fatal(err)
+	}
+	post, err := c.GetPost(ctx, 1)
+	if err != nil || post == nil {
+		t.Fatalf("GetPost = %v, %v; want the post", post, err)
+	}
+	if post.Reactions["like"] != 3 {
+		t.Errorf("likes = %d, want 3", post.Reactions["like"])
+	}
+
+	// Expire the entry, then check that a read drops it and a sweep drops the rest
+	c.mu.Lock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*memoryEntry).expiresAt = time.Now().Add(-time.Second)
+	}
+	c.mu.Unlock()
+	if post, _ := c.GetPost(ctx, 1); post != nil {
+		t.Error("expired post returned")
+	}
+	if len(c.entries) != 0 || c.lru.Len() != 0 {
+		t.Errorf("expired entry kept: %d entries, %d in lru", len(c.entries), c.lru.Len())
+	}
+
+	if err := c.SetPost(ctx, &models.Post{ID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	c.lru.Front().Value.(*memoryEntry).expiresAt = time.Now().Add(-time.Second)
+	c.lastSweep = time.Now().Add(-2 * memorySweepInterval)
+	c.mu.Unlock()
+	if err := c.SetPost(ctx, &models.Post{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.entries[c.cfg.key(EntityPost, 2)]; ok {
+		t.Error("sweep kept an expired entry")
+	}
+}
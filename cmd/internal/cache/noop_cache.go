@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// NoopCache disables caching: every lookup is a miss and writes are dropped
+type NoopCache struct{}
+
+func (NoopCache) GetPost(ctx context.Context, postID int) (*models.Post, error) {
+	return nil, nil
+}
+
+func (NoopCache) SetPost(ctx context.Context, post *models.Post) error {
+	return nil
+}
+
+func (NoopCache) SetPosts(ctx context.Context, posts []*models.Post) error {
+	return nil
+}
+
+func (NoopCache) InvalidatePost(ctx context.Context, postID int) error {
+	return nil
+}
+
+func (NoopCache) IncrReaction(ctx context.Context, postID int, reaction string, delta int) error {
+	return nil
+}
+
+func (NoopCache) Ping(ctx context.Context) error {
+	return nil
+}
@@ -2,56 +2,142 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/cache")
+
+// reactionsSentinel keeps the counters hash of a post without reactions, so
+// an empty hash always means nothing is cached
+const reactionsSentinel = "_"
+
+// incrReactionScript adjusts a counter only while the post's counters are
+// cached; creating the hash here would cache a partial set of counts.
+//
+// KEYS[1] counters hash, ARGV[1] reaction, ARGV[2] delta
+var incrReactionScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+  return 0
+end
+if redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2]) <= 0 then
+  redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
 type RedisCache struct {
 	client *redis.Client
-	ctx    context.Context
+	cfg    Config
+	codec  Codec
+	logger *slog.Logger
 }
 
-func NewRedisCache(client *redis.Client) *RedisCache {
+func NewRedisCache(client *redis.Client, cfg Config, codec Codec, logger *slog.Logger) *RedisCache {
 	return &RedisCache{
 		client: client,
-		ctx:    context.Background(),
+		cfg:    cfg,
+		codec:  codec,
+		logger: logger,
 	}
 }
 
 // GetPost retrieves a post from cache
-func (c *RedisCache) GetPost(postID int) (*models.Post, error) {
-	cacheKey := fmt.Sprintf("post:%d", postID)
-
-	data, err := c.client.Get(c.ctx, cacheKey).Result()
+func (c *RedisCache) GetPost(ctx context.Context, postID int) (_ *models.Post, err error) {
+	cacheKey := c.cfg.key(EntityPost, postID)
+	ctx, span := startSpan(ctx, "GetPost", "GET", attribute.String("cache.key", cacheKey))
+	defer tracing.End(span, &err)
+
+	var get *redis.StringCmd
+	var counters *redis.MapStringStringCmd
+	// Errors, a miss included, are read from each command below
+	c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, cacheKey)
+		counters = pipe.HGetAll(ctx, c.cfg.reactionsKey(postID))
+		return nil
+	})
+	data, err := get.Bytes()
 	if err == redis.Nil {
+		observe(BackendRedis, "get", resultMiss)
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+		c.logger.DebugContext(ctx, "cache miss", slog.String("key", cacheKey))
 		return nil, nil // Cache miss
 	}
 	if err != nil {
+		observe(BackendRedis, "get", resultError)
 		return nil, fmt.Errorf("failed to get from cache: %w", err)
 	}
 
 	var post models.Post
-	if err := json.Unmarshal([]byte(data), &post); err != nil {
+	if err := c.codec.Unmarshal(data, &post); err != nil {
+		observe(BackendRedis, "get", resultError)
 		return nil, fmt.Errorf("failed to unmarshal cached data: %w", err)
 	}
+	// The counters are newer than the copy in the post unless they expired
+	if values := counters.Val(); len(values) > 0 {
+		post.Reactions = parseReactions(values)
+	}
 
+	observe(BackendRedis, "get", resultHit)
+	span.SetAttributes(attribute.Bool("cache.hit", true))
+	c.logger.DebugContext(ctx, "cache hit", slog.String("key", cacheKey))
 	return &post, nil
 }
 
-// SetPost stores a post in cache with TTL
-func (c *RedisCache) SetPost(post *models.Post, ttl time.Duration) error {
-	cacheKey := fmt.Sprintf("post:%d", post.ID)
+// SetPost stores a post in cache with the configured post TTL
+func (c *RedisCache) SetPost(ctx context.Context, post *models.Post) (err error) {
+	defer func() { observe(BackendRedis, "set", writeResult(err)) }()
+
+	cacheKey := c.cfg.key(EntityPost, post.ID)
+	ctx, span := startSpan(ctx, "SetPost", "SET", attribute.String("cache.key", cacheKey))
+	defer tracing.End(span, &err)
 
-	data, err := json.Marshal(post)
+	data, err := c.codec.Marshal(post)
 	if err != nil {
 		return fmt.Errorf("failed to marshal post: %w", err)
 	}
 
-	if err := c.client.Set(c.ctx, cacheKey, data, ttl).Err(); err != nil {
+	ttl := c.cfg.ttl(EntityPost)
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, cacheKey, data, ttl)
+		c.setReactions(ctx, pipe, post, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set cache: %w", err)
+	}
+
+	return nil
+}
+
+// SetPosts stores several posts with a single pipelined round trip
+func (c *RedisCache) SetPosts(ctx context.Context, posts []*models.Post) (err error) {
+	defer func() { observe(BackendRedis, "set_many", writeResult(err)) }()
+	ctx, span := startSpan(ctx, "SetPosts", "SET", attribute.Int("cache.entries", len(posts)))
+	defer tracing.End(span, &err)
+
+	pipe := c.client.Pipeline()
+	for _, post := range posts {
+		data, err := c.codec.Marshal(post)
+		if err != nil {
+			return fmt.Errorf("failed to marshal post %d: %w", post.ID, err)
+		}
+		ttl := c.cfg.ttl(EntityPost)
+		pipe.Set(ctx, c.cfg.key(EntityPost, post.ID), data, ttl)
+		c.setReactions(ctx, pipe, post, ttl)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
@@ -59,17 +145,70 @@ func (c *RedisCache) SetPost(post *models.Post, ttl time.Duration) error {
 }
 
 // InvalidatePost removes a post from cache
-func (c *RedisCache) InvalidatePost(postID int) error {
-	cacheKey := fmt.Sprintf("post:%d", postID)
+func (c *RedisCache) InvalidatePost(ctx context.Context, postID int) (err error) {
+	cacheKey := c.cfg.key(EntityPost, postID)
+	ctx, span := startSpan(ctx, "InvalidatePost", "DEL", attribute.String("cache.key", cacheKey))
+	defer tracing.End(span, &err)
 
-	if err := c.client.Del(c.ctx, cacheKey).Err(); err != nil {
+	err = c.client.Del(ctx, cacheKey, c.cfg.reactionsKey(postID)).Err()
+	observe(BackendRedis, "invalidate", writeResult(err))
+	if err != nil {
 		return fmt.Errorf("failed to invalidate cache: %w", err)
 	}
 
 	return nil
 }
 
+// IncrReaction adjusts a reaction counter of a cached post
+func (c *RedisCache) IncrReaction(ctx context.Context, postID int, reaction string, delta int) (err error) {
+	cacheKey := c.cfg.reactionsKey(postID)
+	ctx, span := startSpan(ctx, "IncrReaction", "HINCRBY", attribute.String("cache.key", cacheKey))
+	defer tracing.End(span, &err)
+
+	err = incrReactionScript.Run(ctx, c.client, []string{cacheKey}, reaction, delta).Err()
+	observe(BackendRedis, "incr", writeResult(err))
+	if err != nil {
+		return fmt.Errorf("failed to update reaction counter: %w", err)
+	}
+
+	return nil
+}
+
+// setReactions replaces the counters of a post with its reaction counts
+func (c *RedisCache) setReactions(ctx context.Context, pipe redis.Pipeliner, post *models.Post, ttl time.Duration) {
+	key := c.cfg.reactionsKey(post.ID)
+	values := []any{reactionsSentinel, 0}
+	for reaction, n := range post.Reactions {
+		values = append(values, reaction, n)
+	}
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+}
+
+// parseReactions reads a counters hash, skipping the sentinel
+func parseReactions(values map[string]string) map[string]int {
+	counts := make(map[string]int, len(values))
+	for reaction, v := range values {
+		n, err := strconv.Atoi(v)
+		if reaction == reactionsSentinel || err != nil || n <= 0 {
+			continue
+		}
+		counts[reaction] = n
+	}
+	return counts
+}
+
 // Ping checks if Redis is available
-func (c *RedisCache) Ping() error {
-	return c.client.Ping(c.ctx).Err()
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// startSpan starts a client span for one Redis command
+func startSpan(ctx context.Context, operation, command string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemRedis, semconv.DBOperation(command))
+	return tracer.Start(ctx, "RedisCache."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
 }
@@ -0,0 +1,330 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/indexing"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/lib/pq"
+)
+
+// Channel is the notification channel of the posts triggers
+const Channel = "posts_changed"
+
+// pingInterval is how often an idle connection is checked, as lib/pq
+// advises, and a failed catch-up retried
+const pingInterval = 90 * time.Second
+
+// lockInterval is how often a replica checks that it still holds the indexer
+// lock, or tries to take it over from one that went away
+const lockInterval = 15 * time.Second
+
+// Bounds on the work done for each change and each catch-up query
+const (
+	applyTimeout = 5 * time.Second
+	queryTimeout = 30 * time.Second
+)
+
+// timestampLayout parses the TIMESTAMP values sent by json_build_object
+const timestampLayout = "2006-01-02T15:04:05.999999999"
+
+// Config controls change data capture from the posts triggers
+type Config struct {
+	Enabled bool
+	// MinReconnectInterval and MaxReconnectInterval bound the backoff between
+	// attempts to reconnect the listening connection
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+	// CatchUpOverlap rescans changes this long before the last one seen, for
+	// transactions that committed after later ones
+	CatchUpOverlap time.Duration
+	// CatchUpBatchSize is the number of changes read per query when catching up
+	CatchUpBatchSize int
+	// DeletionRetention is how long tombstones of deleted posts are kept
+	DeletionRetention time.Duration
+}
+
+// DefaultConfig returns the change capture settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Enabled:              true,
+		MinReconnectInterval: time.Second,
+		MaxReconnectInterval: time.Minute,
+		CatchUpOverlap:       time.Minute,
+		CatchUpBatchSize:     500,
+		DeletionRetention:    7 * 24 * time.Hour,
+	}
+}
+
+// notification is the payload sent by posts_notify()
+type notification struct {
+	Op string `json:"op"`
+	ID int    `json:"id"`
+	At string `json:"at"`
+}
+
+// Listener invalidates cached posts and reindexes them whenever a row of
+// posts changes, through the API or not. Every replica listens, so each
+// clears its own cache, but only the one holding the indexer lock reindexes,
+// so a change is indexed once however many replicas run.
+type Listener struct {
+	dsn     string
+	changes *repository.ChangeRepository
+	cache   cache.Cache
+	indexer *indexing.Queue
+	cfg     Config
+	logger  *slog.Logger
+
+	// last is the latest change handled, lock the indexer lock while this
+	// replica holds it, and followed the last change seen while another
+	// replica did; only the loop goroutine uses them
+	last     repository.PostChange
+	lock     *repository.IndexerLock
+	followed repository.PostChange
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewListener(dsn string, changes *repository.ChangeRepository, cache cache.Cache, indexer *indexing.Queue, cfg Config, logger *slog.Logger) *Listener {
+	return &Listener{
+		dsn:     dsn,
+		changes: changes,
+		cache:   cache,
+		indexer: indexer,
+		cfg:     cfg,
+		logger:  logger,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start listens for changes until Shutdown. Changes made while the
+// connection is down are caught up on after it is re-established.
+func (l *Listener) Start(ctx context.Context) error {
+	now, err := l.changes.Now(ctx)
+	if err != nil {
+		return err
+	}
+	l.last = repository.PostChange{At: now}
+	l.followed = l.last
+	l.lead(ctx)
+	l.pruneDeletions(ctx)
+
+	listener := pq.NewListener(l.dsn, l.cfg.MinReconnectInterval, l.cfg.MaxReconnectInterval, l.logEvent)
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		l.unlock()
+		return fmt.Errorf("failed to listen on %s: %w", Channel, err)
+	}
+
+	l.wg.Add(1)
+	go l.loop(listener)
+	return nil
+}
+
+// Shutdown stops listening and waits for the change being handled
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Listener) loop(listener *pq.Listener) {
+	defer l.wg.Done()
+	defer listener.Close()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	lock := time.NewTicker(lockInterval)
+	defer lock.Stop()
+	defer l.unlock()
+
+	behind := false
+	for {
+		select {
+		case n := <-listener.Notify:
+			// nil follows a reconnect; notifications may have been lost
+			if n == nil {
+				behind = !l.catchUp(l.last)
+				continue
+			}
+			l.handle(n.Extra)
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				l.logger.Debug("change listener ping failed", slog.Any("error", err))
+			}
+			if behind {
+				behind = !l.catchUp(l.last)
+			}
+		case <-lock.C:
+			ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+			if took := l.lead(ctx); took {
+				// The previous holder may have stopped before indexing
+				// what it was notified of
+				behind = !l.catchUp(l.followed)
+			}
+			cancel()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *Listener) handle(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil || n.ID == 0 {
+		l.logger.Warn("ignoring malformed post change notification", slog.String("payload", payload))
+		return
+	}
+	metrics.PostChanges.WithLabelValues("notify").Inc()
+	l.apply(n.ID)
+
+	at, err := time.Parse(timestampLayout, n.At)
+	if err != nil {
+		l.logger.Warn("post change notification without a valid time", slog.String("payload", payload))
+		return
+	}
+	if change := (repository.PostChange{PostID: n.ID, At: at}); after(change, l.last) {
+		l.last = change
+	}
+}
+
+// catchUp reapplies every change since shortly before since. It reports
+// false when the scan failed and should be retried.
+func (l *Listener) catchUp(since repository.PostChange) bool {
+	cursor := repository.PostChange{At: since.At.Add(-l.cfg.CatchUpOverlap)}
+	total := 0
+	for {
+		select {
+		case <-l.stop:
+			return true
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		changes, err := l.changes.ChangesAfter(ctx, cursor, l.cfg.CatchUpBatchSize)
+		cancel()
+		if err != nil {
+			l.logger.Error("failed to catch up on post changes", slog.Any("error", err))
+			return false
+		}
+		for _, change := range changes {
+			l.apply(change.PostID)
+			cursor = change
+		}
+		total += len(changes)
+		metrics.PostChanges.WithLabelValues("catch_up").Add(float64(len(changes)))
+		if len(changes) < l.cfg.CatchUpBatchSize {
+			break
+		}
+	}
+
+	if after(cursor, l.last) {
+		l.last = cursor
+	}
+	l.logger.Info("caught up on post changes", slog.Int("changes", total))
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	l.pruneDeletions(ctx)
+	return true
+}
+
+// apply drops the cached post and, on the replica holding the indexer lock,
+// queues it for reindexing; the indexer removes posts that no longer exist
+func (l *Listener) apply(postID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), applyTimeout)
+	defer cancel()
+	if err := l.cache.InvalidatePost(ctx, postID); err != nil {
+		l.logger.Warn("failed to invalidate changed post", slog.Int("post_id", postID), slog.Any("error", err))
+	}
+	if l.lock != nil {
+		l.indexer.Enqueue(ctx, postID)
+	}
+}
+
+// lead checks that this replica still holds the indexer lock, or tries to
+// take it. It reports whether the lock was just taken.
+func (l *Listener) lead(ctx context.Context) bool {
+	if l.lock != nil {
+		if l.lock.Held(ctx) {
+			return false
+		}
+		l.logger.Warn("lost the indexer lock, another replica will reindex changes")
+		l.unlock()
+	}
+
+	lock, err := l.changes.TryIndexerLock(ctx)
+	if err != nil {
+		l.logger.Warn("failed to take the indexer lock", slog.Any("error", err))
+		return false
+	}
+	if lock == nil {
+		l.followed = l.last
+		return false
+	}
+	l.lock = lock
+	l.logger.Info("took the indexer lock, reindexing changes on this replica")
+	return true
+}
+
+// unlock releases the indexer lock if this replica holds it
+func (l *Listener) unlock() {
+	if l.lock == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), applyTimeout)
+	defer cancel()
+	if err := l.lock.Release(ctx); err != nil {
+		l.logger.Debug("failed to release the indexer lock", slog.Any("error", err))
+	}
+	l.lock = nil
+}
+
+func (l *Listener) pruneDeletions(ctx context.Context) {
+	n, err := l.changes.PruneDeletions(ctx, l.last.At.Add(-l.cfg.DeletionRetention))
+	if err != nil {
+		l.logger.Warn("failed to prune post deletions", slog.Any("error", err))
+		return
+	}
+	if n > 0 {
+		l.logger.Debug("pruned post deletions", slog.Int64("deleted", n))
+	}
+}
+
+func (l *Listener) logEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		l.logger.Warn("change listener disconnected", slog.Any("error", err))
+	case pq.ListenerEventReconnected:
+		l.logger.Info("change listener reconnected")
+	case pq.ListenerEventConnectionAttemptFailed:
+		l.logger.Warn("change listener failed to reconnect", slog.Any("error", err))
+	}
+}
+
+// after reports whether change a comes after b
+func after(a, b repository.PostChange) bool {
+	if !a.At.Equal(b.At) {
+		return a.At.After(b.At)
+	}
+	return a.PostID > b.PostID
+}
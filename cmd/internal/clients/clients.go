@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/hungpv1995/golang_training_2025/internal/config"
+)
+
+// OpenPostgres connects to the database and checks that it answers
+func OpenPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	// Configure connection pool
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// OpenRedis connects to Redis and checks that it answers
+func OpenRedis(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+	})
+
+	// Test connection
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// OpenElasticsearch connects to the cluster and checks that it answers. The
+// transport is returned so its connections can be closed.
+func OpenElasticsearch(cfg config.ElasticsearchConfig) (*elasticsearch.Client, *http.Transport, error) {
+	// Own the transport so its connections can be closed on shutdown
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	esCfg := elasticsearch.Config{
+		Addresses: cfg.URLs,
+		Transport: transport,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		// Retry on overload and gateway errors, e.g. 429 Too Many Requests
+		RetryOnStatus: cfg.RetryOnStatus,
+		RetryBackoff:  func(i int) time.Duration { return time.Duration(i) * cfg.RetryBackoff },
+		MaxRetries:    cfg.MaxRetries,
+	}
+
+	client, err := elasticsearch.NewClient(esCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Test connection
+	res, err := client.Info()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil, fmt.Errorf("elasticsearch connection error: %s", res.String())
+	}
+
+	return client, transport, nil
+}
+
+// Close closes Postgres, Redis and Elasticsearch connections in that order.
+// Redis and Elasticsearch may be nil when they were not opened.
+func Close(logger *slog.Logger, db *sql.DB, redisClient *redis.Client, esTransport *http.Transport) {
+	if err := db.Close(); err != nil {
+		logger.Error("failed to close database", slog.Any("error", err))
+	}
+	if redisClient != nil {
+		if err := redisClient.Close(); err != nil {
+			logger.Error("failed to close redis", slog.Any("error", err))
+		}
+	}
+	if esTransport != nil {
+		esTransport.CloseIdleConnections()
+	}
+}
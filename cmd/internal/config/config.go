@@ -0,0 +1,642 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hungpv1995/golang_training_2025/internal/activity"
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/cdc"
+	"github.com/hungpv1995/golang_training_2025/internal/events"
+	"github.com/hungpv1995/golang_training_2025/internal/indexing"
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+	"github.com/hungpv1995/golang_training_2025/internal/retry"
+	"github.com/hungpv1995/golang_training_2025/internal/search"
+	"github.com/hungpv1995/golang_training_2025/internal/stats"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/hungpv1995/golang_training_2025/internal/warmup"
+	"github.com/hungpv1995/golang_training_2025/internal/webhooks"
+)
+
+// Config is the full application configuration.
+// Values are resolved in order: defaults, config file, environment, flags.
+type Config struct {
+	Server        ServerConfig        `yaml:"server" toml:"server"`
+	Database      DatabaseConfig      `yaml:"database" toml:"database"`
+	Redis         RedisConfig         `yaml:"redis" toml:"redis"`
+	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch" toml:"elasticsearch"`
+	Cache         CacheConfig         `yaml:"cache" toml:"cache"`
+	Warmup        WarmupConfig        `yaml:"warmup" toml:"warmup"`
+	Indexing      IndexingConfig      `yaml:"indexing" toml:"indexing"`
+	Startup       StartupConfig       `yaml:"startup" toml:"startup"`
+	Health        HealthConfig        `yaml:"health" toml:"health"`
+	Timeouts      TimeoutsConfig      `yaml:"timeouts" toml:"timeouts"`
+	Tracing       TracingConfig       `yaml:"tracing" toml:"tracing"`
+	Logging       LoggingConfig       `yaml:"logging" toml:"logging"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit" toml:"rate_limit"`
+	Auth          AuthConfig          `yaml:"auth" toml:"auth"`
+	Stats         StatsConfig         `yaml:"stats" toml:"stats"`
+	Activity      ActivityConfig      `yaml:"activity" toml:"activity"`
+	Webhooks      WebhooksConfig      `yaml:"webhooks" toml:"webhooks"`
+	Events        EventsConfig        `yaml:"events" toml:"events"`
+	CDC           CDCConfig           `yaml:"cdc" toml:"cdc"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port" toml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout bounds draining HTTP requests and indexing jobs on SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type DatabaseConfig struct {
+	Host            string        `yaml:"host" toml:"host"`
+	Port            int           `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	Name            string        `yaml:"name" toml:"name"`
+	SSLMode         string        `yaml:"ssl_mode" toml:"ssl_mode"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+}
+
+type RedisConfig struct {
+	Addr         string `yaml:"addr" toml:"addr"`
+	Password     string `yaml:"password" toml:"password"`
+	DB           int    `yaml:"db" toml:"db"`
+	PoolSize     int    `yaml:"pool_size" toml:"pool_size"`
+	MinIdleConns int    `yaml:"min_idle_conns" toml:"min_idle_conns"`
+}
+
+type ElasticsearchConfig struct {
+	URLs          []string      `yaml:"urls" toml:"urls"`
+	Username      string        `yaml:"username" toml:"username"`
+	Password      string        `yaml:"password" toml:"password"`
+	MaxRetries    int           `yaml:"max_retries" toml:"max_retries"`
+	RetryOnStatus []int         `yaml:"retry_on_status" toml:"retry_on_status"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`
+	// Shards and Replicas apply to indices created from now on
+	Shards   int `yaml:"shards" toml:"shards"`
+	Replicas int `yaml:"replicas" toml:"replicas"`
+}
+
+type CacheConfig struct {
+	Backend           string        `yaml:"backend" toml:"backend"`
+	Namespace         string        `yaml:"namespace" toml:"namespace"`
+	PostTTL           time.Duration `yaml:"post_ttl" toml:"post_ttl"`
+	DefaultTTL        time.Duration `yaml:"default_ttl" toml:"default_ttl"`
+	Jitter            time.Duration `yaml:"ttl_jitter" toml:"ttl_jitter"`
+	Codec             string        `yaml:"codec" toml:"codec"`
+	CompressThreshold int           `yaml:"compress_threshold" toml:"compress_threshold"`
+	MaxEntries        int           `yaml:"max_entries" toml:"max_entries"`
+}
+
+type WarmupConfig struct {
+	Enabled     bool          `yaml:"enabled" toml:"enabled"`
+	Strategy    string        `yaml:"strategy" toml:"strategy"`
+	Limit       int           `yaml:"limit" toml:"limit"`
+	BatchSize   int           `yaml:"batch_size" toml:"batch_size"`
+	Concurrency int           `yaml:"concurrency" toml:"concurrency"`
+	Timeout     time.Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// StartupConfig controls retries while dependencies come up and whether
+// pending migrations are applied first
+type StartupConfig struct {
+	RetryAttempts  int           `yaml:"retry_attempts" toml:"retry_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+	Migrate        bool          `yaml:"migrate" toml:"migrate"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+type IndexingConfig struct {
+	Workers        int           `yaml:"workers" toml:"workers"`
+	QueueSize      int           `yaml:"queue_size" toml:"queue_size"`
+	RetryInterval  time.Duration `yaml:"retry_interval" toml:"retry_interval"`
+	RetryBatchSize int           `yaml:"retry_batch_size" toml:"retry_batch_size"`
+	JobTimeout     time.Duration `yaml:"job_timeout" toml:"job_timeout"`
+}
+
+// TimeoutsConfig bounds a whole request and each downstream call within it
+type TimeoutsConfig struct {
+	Request  time.Duration `yaml:"request" toml:"request"`
+	Database time.Duration `yaml:"database" toml:"database"`
+	Cache    time.Duration `yaml:"cache" toml:"cache"`
+	Search   time.Duration `yaml:"search" toml:"search"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+// RateLimitConfig holds limits in the "<count>/<s|m|h>[:<burst>]" notation;
+// Routes entries look like "POST /posts=10/m:5"
+type RateLimitConfig struct {
+	Enabled bool     `yaml:"enabled" toml:"enabled"`
+	Backend string   `yaml:"backend" toml:"backend"`
+	Default string   `yaml:"default" toml:"default"`
+	Routes  []string `yaml:"routes" toml:"routes"`
+	// AuthFailures limits failed API key attempts per IP; empty disables it
+	AuthFailures string `yaml:"auth_failures" toml:"auth_failures"`
+	TrustProxy   bool   `yaml:"trust_proxy" toml:"trust_proxy"`
+}
+
+// AuthConfig controls API key authentication. Key management and admin
+// routes always require a key; Enabled extends that to the posts API.
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// AdminToken is accepted as a key with every scope, for bootstrapping
+	AdminToken string `yaml:"admin_token" toml:"admin_token"`
+}
+
+// StatsConfig controls post view counting and trending posts, kept in Redis
+type StatsConfig struct {
+	Enabled        bool          `yaml:"enabled" toml:"enabled"`
+	FlushInterval  time.Duration `yaml:"flush_interval" toml:"flush_interval"`
+	FlushBatchSize int           `yaml:"flush_batch_size" toml:"flush_batch_size"`
+	BotUserAgents  []string      `yaml:"bot_user_agents" toml:"bot_user_agents"`
+}
+
+// ActivityConfig controls how long the activity log is kept
+type ActivityConfig struct {
+	Retention      time.Duration `yaml:"retention" toml:"retention"`
+	Archive        bool          `yaml:"archive" toml:"archive"`
+	PruneInterval  time.Duration `yaml:"prune_interval" toml:"prune_interval"`
+	PruneBatchSize int           `yaml:"prune_batch_size" toml:"prune_batch_size"`
+}
+
+// WebhooksConfig controls how webhook deliveries are sent and retried
+type WebhooksConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	BatchSize      int           `yaml:"batch_size" toml:"batch_size"`
+	Workers        int           `yaml:"workers" toml:"workers"`
+	RequestTimeout time.Duration `yaml:"request_timeout" toml:"request_timeout"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+	// AllowPrivateAddresses lets webhooks target internal hosts, for local development
+	AllowPrivateAddresses bool `yaml:"allow_private_addresses" toml:"allow_private_addresses"`
+}
+
+// EventsConfig controls the post event stream on /events, fed through Redis
+type EventsConfig struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled"`
+	MaxLen       int           `yaml:"max_len" toml:"max_len"`
+	Heartbeat    time.Duration `yaml:"heartbeat" toml:"heartbeat"`
+	ClientBuffer int           `yaml:"client_buffer" toml:"client_buffer"`
+}
+
+// CDCConfig controls change data capture from the posts triggers
+type CDCConfig struct {
+	Enabled              bool          `yaml:"enabled" toml:"enabled"`
+	MinReconnectInterval time.Duration `yaml:"min_reconnect_interval" toml:"min_reconnect_interval"`
+	MaxReconnectInterval time.Duration `yaml:"max_reconnect_interval" toml:"max_reconnect_interval"`
+	CatchUpOverlap       time.Duration `yaml:"catch_up_overlap" toml:"catch_up_overlap"`
+	CatchUpBatchSize     int           `yaml:"catch_up_batch_size" toml:"catch_up_batch_size"`
+	DeletionRetention    time.Duration `yaml:"deletion_retention" toml:"deletion_retention"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	indexPolicy := search.DefaultIndexConfig()
+	cachePolicy := cache.DefaultConfig()
+	warmupPolicy := warmup.DefaultConfig()
+	indexingPolicy := indexing.DefaultConfig()
+	tracingPolicy := tracing.DefaultConfig()
+	loggingPolicy := logging.DefaultConfig()
+	rateLimitPolicy := ratelimit.DefaultConfig()
+	statsPolicy := stats.DefaultConfig()
+	activityPolicy := activity.DefaultConfig()
+	webhooksPolicy := webhooks.DefaultConfig()
+	eventsPolicy := events.DefaultConfig()
+	cdcPolicy := cdc.DefaultConfig()
+
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "bloguser",
+			Password:        "blogpass",
+			Name:            "blogdb",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Redis: RedisConfig{
+			Addr:         "localhost:6379",
+			PoolSize:     10,
+			MinIdleConns: 5,
+		},
+		Elasticsearch: ElasticsearchConfig{
+			URLs:          []string{"http://localhost:9200"},
+			MaxRetries:    3,
+			RetryOnStatus: []int{502, 503, 504, 429},
+			RetryBackoff:  100 * time.Millisecond,
+			Shards:        indexPolicy.Shards,
+			Replicas:      indexPolicy.Replicas,
+		},
+		Cache: CacheConfig{
+			Backend:           cachePolicy.Backend,
+			PostTTL:           cachePolicy.TTLs[cache.EntityPost],
+			DefaultTTL:        cachePolicy.DefaultTTL,
+			Jitter:            cachePolicy.Jitter,
+			Codec:             cachePolicy.Codec,
+			CompressThreshold: cachePolicy.CompressThreshold,
+			MaxEntries:        cachePolicy.MaxEntries,
+		},
+		Warmup: WarmupConfig{
+			Enabled:     warmupPolicy.Enabled,
+			Strategy:    warmupPolicy.Strategy,
+			Limit:       warmupPolicy.Limit,
+			BatchSize:   warmupPolicy.BatchSize,
+			Concurrency: warmupPolicy.Concurrency,
+			Timeout:     warmupPolicy.Timeout,
+		},
+		Indexing: IndexingConfig{
+			Workers:        indexingPolicy.Workers,
+			QueueSize:      indexingPolicy.QueueSize,
+			RetryInterval:  indexingPolicy.RetryInterval,
+			RetryBatchSize: indexingPolicy.RetryBatchSize,
+			JobTimeout:     indexingPolicy.JobTimeout,
+		},
+		Startup: StartupConfig{
+			RetryAttempts:  10,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     5 * time.Second,
+		},
+		Timeouts: TimeoutsConfig{
+			Request:  10 * time.Second,
+			Database: 3 * time.Second,
+			Cache:    200 * time.Millisecond,
+			Search:   2 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingPolicy.Exporter,
+			Endpoint:    tracingPolicy.Endpoint,
+			Insecure:    tracingPolicy.Insecure,
+			ServiceName: tracingPolicy.ServiceName,
+			SampleRatio: tracingPolicy.SampleRatio,
+		},
+		Logging: LoggingConfig{
+			Level:  loggingPolicy.Level,
+			Format: loggingPolicy.Format,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: rateLimitPolicy.Enabled,
+			Backend: rateLimitPolicy.Backend,
+			Routes: []string{
+				"POST /posts=10/m",
+				"GET /posts/search=5/s:10",
+				"POST /posts/{id}/comments=5/m",
+				"POST /posts/{id}/reactions=30/m",
+			},
+			AuthFailures: "10/m",
+			TrustProxy:   rateLimitPolicy.TrustProxy,
+		},
+		Stats: StatsConfig{
+			Enabled:        statsPolicy.Enabled,
+			FlushInterval:  statsPolicy.FlushInterval,
+			FlushBatchSize: statsPolicy.FlushBatchSize,
+			BotUserAgents:  statsPolicy.BotUserAgents,
+		},
+		Activity: ActivityConfig{
+			Retention:      activityPolicy.Retention,
+			Archive:        activityPolicy.Archive,
+			PruneInterval:  activityPolicy.PruneInterval,
+			PruneBatchSize: activityPolicy.PruneBatchSize,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   webhooksPolicy.PollInterval,
+			BatchSize:      webhooksPolicy.BatchSize,
+			Workers:        webhooksPolicy.Workers,
+			RequestTimeout: webhooksPolicy.RequestTimeout,
+			MaxAttempts:    webhooksPolicy.MaxAttempts,
+			InitialBackoff: webhooksPolicy.InitialBackoff,
+			MaxBackoff:     webhooksPolicy.MaxBackoff,
+		},
+		Events: EventsConfig{
+			Enabled:      eventsPolicy.Enabled,
+			MaxLen:       eventsPolicy.MaxLen,
+			Heartbeat:    eventsPolicy.Heartbeat,
+			ClientBuffer: eventsPolicy.ClientBuffer,
+		},
+		CDC: CDCConfig{
+			Enabled:              cdcPolicy.Enabled,
+			MinReconnectInterval: cdcPolicy.MinReconnectInterval,
+			MaxReconnectInterval: cdcPolicy.MaxReconnectInterval,
+			CatchUpOverlap:       cdcPolicy.CatchUpOverlap,
+			CatchUpBatchSize:     cdcPolicy.CatchUpBatchSize,
+			DeletionRetention:    cdcPolicy.DeletionRetention,
+		},
+	}
+}
+
+// Load resolves the configuration from an optional file (--config or
+// CONFIG_FILE), the environment and the flags in args, then validates it.
+// Every setting gets a flag on fs, so callers can register their own flags
+// on fs before calling Load.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		fs.Var(&flagValue{setting: s, values: flagValues}, s.name, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flagValues[s.name]; ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("invalid value for --%s: %w", s.name, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file format %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the configuration with every secret masked
+func (c *Config) Redacted() *Config {
+	cp := *c
+	for _, s := range cp.settings() {
+		if target, ok := s.target.(*string); ok && s.secret && *target != "" {
+			*target = "********"
+		}
+	}
+	return &cp
+}
+
+// Print writes the effective configuration as YAML with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return enc.Close()
+}
+
+// DSN returns the lib/pq connection string
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(c.Host), c.Port, quoteDSN(c.User), quoteDSN(c.Password), quoteDSN(c.Name), c.SSLMode)
+}
+
+// quoteDSN quotes a connection string value so spaces and quotes survive
+func quoteDSN(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// Policy converts the cache section into the cache package configuration
+func (c CacheConfig) Policy() cache.Config {
+	policy := cache.DefaultConfig()
+	policy.Backend = c.Backend
+	policy.Namespace = c.Namespace
+	policy.TTLs[cache.EntityPost] = c.PostTTL
+	policy.DefaultTTL = c.DefaultTTL
+	policy.Jitter = c.Jitter
+	policy.Codec = c.Codec
+	policy.CompressThreshold = c.CompressThreshold
+	policy.MaxEntries = c.MaxEntries
+	return policy
+}
+
+// Policy converts the warm-up section into the warmup package configuration
+func (c WarmupConfig) Policy() warmup.Config {
+	return warmup.Config{
+		Enabled:     c.Enabled,
+		Strategy:    c.Strategy,
+		Limit:       c.Limit,
+		BatchSize:   c.BatchSize,
+		Concurrency: c.Concurrency,
+		Timeout:     c.Timeout,
+	}
+}
+
+// Policy converts the indexing section into the indexing package configuration
+func (c IndexingConfig) Policy() indexing.Config {
+	return indexing.Config{
+		Workers:        c.Workers,
+		QueueSize:      c.QueueSize,
+		RetryInterval:  c.RetryInterval,
+		RetryBatchSize: c.RetryBatchSize,
+		JobTimeout:     c.JobTimeout,
+	}
+}
+
+// RetryPolicy converts the startup section into a retry policy
+func (c StartupConfig) RetryPolicy() retry.Policy {
+	return retry.Policy{
+		Attempts:       c.RetryAttempts,
+		InitialBackoff: c.InitialBackoff,
+		MaxBackoff:     c.MaxBackoff,
+	}
+}
+
+// Policy converts the tracing section into the tracing package configuration
+func (c TracingConfig) Policy() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Exporter,
+		Endpoint:    c.Endpoint,
+		Insecure:    c.Insecure,
+		ServiceName: c.ServiceName,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// Policy converts the logging section into the logging package configuration
+func (c LoggingConfig) Policy() logging.Config {
+	return logging.Config{
+		Level:  c.Level,
+		Format: c.Format,
+	}
+}
+
+// Policy parses the rate limit section into the ratelimit package configuration
+func (c RateLimitConfig) Policy() (ratelimit.Config, error) {
+	policy := ratelimit.DefaultConfig()
+	policy.Enabled = c.Enabled
+	policy.Backend = c.Backend
+	policy.TrustProxy = c.TrustProxy
+
+	if c.Default != "" {
+		limit, err := ratelimit.ParseLimit(c.Default)
+		if err != nil {
+			return policy, err
+		}
+		policy.Default = &limit
+	}
+	if c.AuthFailures != "" {
+		limit, err := ratelimit.ParseLimit(c.AuthFailures)
+		if err != nil {
+			return policy, err
+		}
+		policy.AuthFailures = &limit
+	}
+
+	var errs []error
+	for _, spec := range c.Routes {
+		rule, err := ratelimit.ParseRule(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+
+	return policy, errors.Join(errs...)
+}
+
+// Policy converts the stats section into the stats package configuration.
+// Visitors are told apart by IP as the rate limiter does, so the proxy
+// setting is shared with it.
+func (c StatsConfig) Policy(trustProxy bool) stats.Config {
+	return stats.Config{
+		Enabled:        c.Enabled,
+		FlushInterval:  c.FlushInterval,
+		FlushBatchSize: c.FlushBatchSize,
+		BotUserAgents:  c.BotUserAgents,
+		TrustProxy:     trustProxy,
+	}
+}
+
+// Policy converts the activity section into the activity package configuration
+func (c ActivityConfig) Policy() activity.Config {
+	return activity.Config{
+		Retention:      c.Retention,
+		Archive:        c.Archive,
+		PruneInterval:  c.PruneInterval,
+		PruneBatchSize: c.PruneBatchSize,
+	}
+}
+
+// Policy converts the webhooks section into the webhooks package configuration
+func (c WebhooksConfig) Policy() webhooks.Config {
+	return webhooks.Config{
+		PollInterval:   c.PollInterval,
+		BatchSize:      c.BatchSize,
+		Workers:        c.Workers,
+		RequestTimeout: c.RequestTimeout,
+		MaxAttempts:    c.MaxAttempts,
+		InitialBackoff: c.InitialBackoff,
+		MaxBackoff:     c.MaxBackoff,
+
+		AllowPrivateAddresses: c.AllowPrivateAddresses,
+	}
+}
+
+// Policy converts the events section into the events package configuration
+func (c EventsConfig) Policy() events.Config {
+	return events.Config{
+		Enabled:      c.Enabled,
+		MaxLen:       c.MaxLen,
+		Heartbeat:    c.Heartbeat,
+		ClientBuffer: c.ClientBuffer,
+	}
+}
+
+// Policy converts the cdc section into the cdc package configuration
+func (c CDCConfig) Policy() cdc.Config {
+	return cdc.Config{
+		Enabled:              c.Enabled,
+		MinReconnectInterval: c.MinReconnectInterval,
+		MaxReconnectInterval: c.MaxReconnectInterval,
+		CatchUpOverlap:       c.CatchUpOverlap,
+		CatchUpBatchSize:     c.CatchUpBatchSize,
+		DeletionRetention:    c.DeletionRetention,
+	}
+}
+
+// IndexPolicy converts the elasticsearch section into the settings of new indices
+func (c ElasticsearchConfig) IndexPolicy() search.IndexConfig {
+	return search.IndexConfig{
+		Shards:   c.Shards,
+		Replicas: c.Replicas,
+	}
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds one config field to its environment variable and flag
+type setting struct {
+	name   string // flag name
+	env    string
+	usage  string
+	target interface{} // pointer to the config field
+	secret bool
+}
+
+// settings lists every configurable field. Adding a field here is enough to
+// make it loadable from the environment and the command line.
+func (c *Config) settings() []setting {
+	return []setting{
+		{name: "server-port", env: "SERVER_PORT", usage: "HTTP listen port", target: &c.Server.Port},
+		{name: "server-read-timeout", env: "SERVER_READ_TIMEOUT", usage: "maximum time to read a request", target: &c.Server.ReadTimeout},
+		{name: "server-read-header-timeout", env: "SERVER_READ_HEADER_TIMEOUT", usage: "maximum time to read request headers", target: &c.Server.ReadHeaderTimeout},
+		{name: "server-write-timeout", env: "SERVER_WRITE_TIMEOUT", usage: "maximum time to write a response", target: &c.Server.WriteTimeout},
+		{name: "server-idle-timeout", env: "SERVER_IDLE_TIMEOUT", usage: "keep-alive idle timeout", target: &c.Server.IdleTimeout},
+		{name: "server-shutdown-timeout", env: "SERVER_SHUTDOWN_TIMEOUT", usage: "time allowed to drain requests and indexing on shutdown", target: &c.Server.ShutdownTimeout},
+
+		{name: "db-host", env: "DB_HOST", usage: "PostgreSQL host", target: &c.Database.Host},
+		{name: "db-port", env: "DB_PORT", usage: "PostgreSQL port", target: &c.Database.Port},
+		{name: "db-user", env: "DB_USER", usage: "PostgreSQL user", target: &c.Database.User},
+		{name: "db-password", env: "DB_PASSWORD", usage: "PostgreSQL password", target: &c.Database.Password, secret: true},
+		{name: "db-name", env: "DB_NAME", usage: "PostgreSQL database name", target: &c.Database.Name},
+		{name: "db-ssl-mode", env: "DB_SSL_MODE", usage: "PostgreSQL sslmode", target: &c.Database.SSLMode},
+		{name: "db-max-open-conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open database connections", target: &c.Database.MaxOpenConns},
+		{name: "db-max-idle-conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum idle database connections", target: &c.Database.MaxIdleConns},
+		{name: "db-conn-max-lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum lifetime of a database connection", target: &c.Database.ConnMaxLifetime},
+
+		{name: "redis-addr", env: "REDIS_ADDR", usage: "Redis address", target: &c.Redis.Addr},
+		{name: "redis-password", env: "REDIS_PASSWORD", usage: "Redis password", target: &c.Redis.Password, secret: true},
+		{name: "redis-db", env: "REDIS_DB", usage: "Redis database number", target: &c.Redis.DB},
+		{name: "redis-pool-size", env: "REDIS_POOL_SIZE", usage: "Redis connection pool size", target: &c.Redis.PoolSize},
+		{name: "redis-min-idle-conns", env: "REDIS_MIN_IDLE_CONNS", usage: "minimum idle Redis connections", target: &c.Redis.MinIdleConns},
+
+		{name: "es-urls", env: "ELASTICSEARCH_URL", usage: "comma-separated Elasticsearch URLs", target: &c.Elasticsearch.URLs},
+		{name: "es-username", env: "ELASTICSEARCH_USERNAME", usage: "Elasticsearch username", target: &c.Elasticsearch.Username},
+		{name: "es-password", env: "ELASTICSEARCH_PASSWORD", usage: "Elasticsearch password", target: &c.Elasticsearch.Password, secret: true},
+		{name: "es-max-retries", env: "ELASTICSEARCH_MAX_RETRIES", usage: "Elasticsearch request retries", target: &c.Elasticsearch.MaxRetries},
+		{name: "es-retry-on-status", env: "ELASTICSEARCH_RETRY_ON_STATUS", usage: "comma-separated HTTP statuses to retry", target: &c.Elasticsearch.RetryOnStatus},
+		{name: "es-retry-backoff", env: "ELASTICSEARCH_RETRY_BACKOFF", usage: "backoff step between Elasticsearch retries", target: &c.Elasticsearch.RetryBackoff},
+		{name: "es-shards", env: "ELASTICSEARCH_SHARDS", usage: "primary shards of new search indices", target: &c.Elasticsearch.Shards},
+		{name: "es-replicas", env: "ELASTICSEARCH_REPLICAS", usage: "replicas of new search indices", target: &c.Elasticsearch.Replicas},
+
+		{name: "cache-backend", env: "CACHE_BACKEND", usage: "cache backend: redis, memory or noop", target: &c.Cache.Backend},
+		{name: "cache-namespace", env: "CACHE_NAMESPACE", usage: "prefix for every cache key", target: &c.Cache.Namespace},
+		{name: "cache-post-ttl", env: "CACHE_POST_TTL", usage: "TTL for cached posts", target: &c.Cache.PostTTL},
+		{name: "cache-default-ttl", env: "CACHE_DEFAULT_TTL", usage: "TTL for entity types without their own setting", target: &c.Cache.DefaultTTL},
+		{name: "cache-ttl-jitter", env: "CACHE_TTL_JITTER", usage: "random extra TTL added to each entry", target: &c.Cache.Jitter},
+		{name: "cache-codec", env: "CACHE_CODEC", usage: "cache codec: json or msgpack", target: &c.Cache.Codec},
+		{name: "cache-compress-threshold", env: "CACHE_COMPRESS_THRESHOLD", usage: "gzip cached values larger than this many bytes, 0 disables", target: &c.Cache.CompressThreshold},
+		{name: "cache-max-entries", env: "CACHE_MAX_ENTRIES", usage: "posts kept by the memory cache, 0 for no limit", target: &c.Cache.MaxEntries},
+
+		{name: "warmup-enabled", env: "WARMUP_ENABLED", usage: "warm the cache on startup", target: &c.Warmup.Enabled},
+		{name: "warmup-strategy", env: "WARMUP_STRATEGY", usage: "which posts to preload", target: &c.Warmup.Strategy},
+		{name: "warmup-limit", env: "WARMUP_LIMIT", usage: "number of posts to preload", target: &c.Warmup.Limit},
+		{name: "warmup-batch-size", env: "WARMUP_BATCH_SIZE", usage: "posts per pipelined batch", target: &c.Warmup.BatchSize},
+		{name: "warmup-concurrency", env: "WARMUP_CONCURRENCY", usage: "batches in flight at once", target: &c.Warmup.Concurrency},
+		{name: "warmup-timeout", env: "WARMUP_TIMEOUT", usage: "report ready after this long even if warm-up is incomplete", target: &c.Warmup.Timeout},
+
+		{name: "indexing-workers", env: "INDEXING_WORKERS", usage: "background Elasticsearch indexing workers", target: &c.Indexing.Workers},
+		{name: "indexing-queue-size", env: "INDEXING_QUEUE_SIZE", usage: "indexing jobs buffered in memory", target: &c.Indexing.QueueSize},
+		{name: "indexing-retry-interval", env: "INDEXING_RETRY_INTERVAL", usage: "how often persisted indexing jobs are replayed", target: &c.Indexing.RetryInterval},
+		{name: "indexing-retry-batch-size", env: "INDEXING_RETRY_BATCH_SIZE", usage: "persisted indexing jobs replayed at a time", target: &c.Indexing.RetryBatchSize},
+		{name: "indexing-job-timeout", env: "INDEXING_JOB_TIMEOUT", usage: "time allowed to load and index one post", target: &c.Indexing.JobTimeout},
+
+		{name: "startup-retry-attempts", env: "STARTUP_RETRY_ATTEMPTS", usage: "connection attempts per dependency at startup", target: &c.Startup.RetryAttempts},
+		{name: "startup-initial-backoff", env: "STARTUP_INITIAL_BACKOFF", usage: "wait after the first failed connection attempt", target: &c.Startup.InitialBackoff},
+		{name: "startup-max-backoff", env: "STARTUP_MAX_BACKOFF", usage: "maximum wait between connection attempts", target: &c.Startup.MaxBackoff},
+		{name: "startup-migrate", env: "STARTUP_MIGRATE", usage: "apply pending database migrations before serving", target: &c.Startup.Migrate},
+
+		{name: "health-check-timeout", env: "HEALTH_CHECK_TIMEOUT", usage: "timeout for each readiness dependency check", target: &c.Health.CheckTimeout},
+		{name: "health-cache-ttl", env: "HEALTH_CACHE_TTL", usage: "how long readiness results are reused", target: &c.Health.CacheTTL},
+
+		{name: "timeout-request", env: "TIMEOUT_REQUEST", usage: "deadline for a whole HTTP request", target: &c.Timeouts.Request},
+		{name: "timeout-database", env: "TIMEOUT_DATABASE", usage: "deadline for each database call", target: &c.Timeouts.Database},
+		{name: "timeout-cache", env: "TIMEOUT_CACHE", usage: "deadline for each cache call", target: &c.Timeouts.Cache},
+		{name: "timeout-search", env: "TIMEOUT_SEARCH", usage: "deadline for each Elasticsearch call", target: &c.Timeouts.Search},
+
+		{name: "rate-limit-enabled", env: "RATE_LIMIT_ENABLED", usage: "enforce per-client rate limits", target: &c.RateLimit.Enabled},
+		{name: "rate-limit-backend", env: "RATE_LIMIT_BACKEND", usage: "rate limiter backend: redis or memory", target: &c.RateLimit.Backend},
+		{name: "rate-limit-default", env: "RATE_LIMIT_DEFAULT", usage: "limit for routes without a rule, e.g. 100/s:200; empty leaves them unlimited", target: &c.RateLimit.Default},
+		{name: "rate-limit-routes", env: "RATE_LIMIT_ROUTES", usage: "comma-separated route limits, e.g. \"POST /posts=10/m:5\"", target: &c.RateLimit.Routes},
+		{name: "rate-limit-auth-failures", env: "RATE_LIMIT_AUTH_FAILURES", usage: "failed API key attempts allowed per IP, e.g. 10/m; empty disables", target: &c.RateLimit.AuthFailures},
+		{name: "rate-limit-trust-proxy", env: "RATE_LIMIT_TRUST_PROXY", usage: "take client IPs from X-Forwarded-For and users from X-User-ID", target: &c.RateLimit.TrustProxy},
+
+		{name: "auth-enabled", env: "AUTH_ENABLED", usage: "require API keys with the right scopes on the posts API", target: &c.Auth.Enabled},
+		{name: "auth-admin-token", env: "AUTH_ADMIN_TOKEN", usage: "bootstrap token accepted as an API key with every scope", target: &c.Auth.AdminToken, secret: true},
+
+		{name: "stats-enabled", env: "STATS_ENABLED", usage: "count post views in Redis and serve trending posts", target: &c.Stats.Enabled},
+		{name: "stats-flush-interval", env: "STATS_FLUSH_INTERVAL", usage: "how often view counts are written to post_stats", target: &c.Stats.FlushInterval},
+		{name: "stats-flush-batch-size", env: "STATS_FLUSH_BATCH_SIZE", usage: "posts written to post_stats per statement", target: &c.Stats.FlushBatchSize},
+		{name: "stats-bot-user-agents", env: "STATS_BOT_USER_AGENTS", usage: "comma-separated user agent substrings whose views are not counted", target: &c.Stats.BotUserAgents},
+
+		{name: "activity-retention", env: "ACTIVITY_RETENTION", usage: "age past which activity log entries are pruned; 0 keeps them forever", target: &c.Activity.Retention},
+		{name: "activity-archive", env: "ACTIVITY_ARCHIVE", usage: "move pruned activity log entries to activity_logs_archive instead of deleting them", target: &c.Activity.Archive},
+		{name: "activity-prune-interval", env: "ACTIVITY_PRUNE_INTERVAL", usage: "how often the activity log is pruned", target: &c.Activity.PruneInterval},
+		{name: "activity-prune-batch-size", env: "ACTIVITY_PRUNE_BATCH_SIZE", usage: "activity log entries pruned per statement", target: &c.Activity.PruneBatchSize},
+		{name: "webhooks-poll-interval", env: "WEBHOOKS_POLL_INTERVAL", usage: "how often due webhook deliveries are looked for", target: &c.Webhooks.PollInterval},
+		{name: "webhooks-batch-size", env: "WEBHOOKS_BATCH_SIZE", usage: "webhook deliveries claimed per query", target: &c.Webhooks.BatchSize},
+		{name: "webhooks-workers", env: "WEBHOOKS_WORKERS", usage: "webhook deliveries sent at once by each replica", target: &c.Webhooks.Workers},
+		{name: "webhooks-request-timeout", env: "WEBHOOKS_REQUEST_TIMEOUT", usage: "timeout for each webhook request", target: &c.Webhooks.RequestTimeout},
+		{name: "webhooks-max-attempts", env: "WEBHOOKS_MAX_ATTEMPTS", usage: "requests made before a webhook delivery is marked failed", target: &c.Webhooks.MaxAttempts},
+		{name: "webhooks-initial-backoff", env: "WEBHOOKS_INITIAL_BACKOFF", usage: "delay before the first webhook retry; doubles with every attempt", target: &c.Webhooks.InitialBackoff},
+		{name: "webhooks-max-backoff", env: "WEBHOOKS_MAX_BACKOFF", usage: "longest delay between webhook retries", target: &c.Webhooks.MaxBackoff},
+		{name: "webhooks-allow-private-addresses", env: "WEBHOOKS_ALLOW_PRIVATE_ADDRESSES", usage: "let webhooks reach loopback, private and link-local addresses (local development only)", target: &c.Webhooks.AllowPrivateAddresses},
+		{name: "events-enabled", env: "EVENTS_ENABLED", usage: "stream post changes on /events through Redis", target: &c.Events.Enabled},
+		{name: "events-max-len", env: "EVENTS_MAX_LEN", usage: "approximate number of events kept in Redis for Last-Event-ID resume", target: &c.Events.MaxLen},
+		{name: "events-heartbeat", env: "EVENTS_HEARTBEAT", usage: "how often an idle event stream sends a keep-alive comment", target: &c.Events.Heartbeat},
+		{name: "events-client-buffer", env: "EVENTS_CLIENT_BUFFER", usage: "events queued per client before a slow client is disconnected", target: &c.Events.ClientBuffer},
+		{name: "cdc-enabled", env: "CDC_ENABLED", usage: "invalidate and reindex posts however they change, notified by the posts triggers", target: &c.CDC.Enabled},
+		{name: "cdc-min-reconnect-interval", env: "CDC_MIN_RECONNECT_INTERVAL", usage: "first delay before reconnecting the change listener", target: &c.CDC.MinReconnectInterval},
+		{name: "cdc-max-reconnect-interval", env: "CDC_MAX_RECONNECT_INTERVAL", usage: "longest delay between change listener reconnection attempts", target: &c.CDC.MaxReconnectInterval},
+		{name: "cdc-catch-up-overlap", env: "CDC_CATCH_UP_OVERLAP", usage: "how far before the last change seen the catch-up scan starts", target: &c.CDC.CatchUpOverlap},
+		{name: "cdc-catch-up-batch-size", env: "CDC_CATCH_UP_BATCH_SIZE", usage: "changes read per query when catching up", target: &c.CDC.CatchUpBatchSize},
+		{name: "cdc-deletion-retention", env: "CDC_DELETION_RETENTION", usage: "how long tombstones of deleted posts are kept for catching up", target: &c.CDC.DeletionRetention},
+
+		{name: "log-level", env: "LOG_LEVEL", usage: "initial log level: debug, info, warn or error", target: &c.Logging.Level},
+		{name: "log-format", env: "LOG_FORMAT", usage: "log format: json or text", target: &c.Logging.Format},
+
+		{name: "tracing-exporter", env: "TRACING_EXPORTER", usage: "span exporter: none, stdout or otlp", target: &c.Tracing.Exporter},
+		{name: "tracing-endpoint", env: "TRACING_ENDPOINT", usage: "OTLP/HTTP collector host:port", target: &c.Tracing.Endpoint},
+		{name: "tracing-insecure", env: "TRACING_INSECURE", usage: "send OTLP over plain HTTP", target: &c.Tracing.Insecure},
+		{name: "tracing-service-name", env: "TRACING_SERVICE_NAME", usage: "service.name reported on spans", target: &c.Tracing.ServiceName},
+		{name: "tracing-sample-ratio", env: "TRACING_SAMPLE_RATIO", usage: "fraction of new traces recorded, 0 to 1", target: &c.Tracing.SampleRatio},
+	}
+}
+
+// set parses value into the setting's field
+func (s setting) set(value string) error {
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*target = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*target = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*target = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		*target = d
+	case *[]string:
+		*target = splitList(value)
+	case *[]int:
+		var ints []int
+		for _, part := range splitList(value) {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("%q is not an integer", part)
+			}
+			ints = append(ints, n)
+		}
+		*target = ints
+	default:
+		return fmt.Errorf("unsupported setting type %T", s.target)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// flagValue records flag values so they can be applied after the config
+// file and environment, giving flags the highest precedence
+type flagValue struct {
+	setting setting
+	values  map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.setting.name]
+}
+
+func (f *flagValue) Set(value string) error {
+	if err := f.setting.validate(value); err != nil {
+		return err
+	}
+	f.values[f.setting.name] = value
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be passed as --flag without a value
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.setting.target.(*bool)
+	return ok
+}
+
+// validate checks that value parses for the setting without storing it
+func (s setting) validate(value string) error {
+	scratch := setting{target: reflect.New(reflect.TypeOf(s.target).Elem()).Interface()}
+	return scratch.set(value)
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/hungpv1995/golang_training_2025/internal/warmup"
+)
+
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "database.port must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.Name != "", "database.name is required")
+	check(sslModes[c.Database.SSLMode], "database.ssl_mode %q is not a valid sslmode", c.Database.SSLMode)
+	check(c.Database.MaxOpenConns > 0, "database.max_open_conns must be positive, got %d", c.Database.MaxOpenConns)
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative, got %d", c.Database.MaxIdleConns)
+	check(c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+
+	check(c.Redis.Addr != "", "redis.addr is required")
+	check(c.Redis.DB >= 0, "redis.db must not be negative, got %d", c.Redis.DB)
+	check(c.Redis.PoolSize > 0, "redis.pool_size must be positive, got %d", c.Redis.PoolSize)
+	check(c.Redis.MinIdleConns >= 0 && c.Redis.MinIdleConns <= c.Redis.PoolSize,
+		"redis.min_idle_conns must be between 0 and redis.pool_size (%d), got %d", c.Redis.PoolSize, c.Redis.MinIdleConns)
+
+	check(len(c.Elasticsearch.URLs) > 0, "elasticsearch.urls needs at least one URL")
+	for _, raw := range c.Elasticsearch.URLs {
+		u, err := url.Parse(raw)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"elasticsearch.urls contains an invalid URL %q", raw)
+	}
+	check(c.Elasticsearch.MaxRetries >= 0, "elasticsearch.max_retries must not be negative, got %d", c.Elasticsearch.MaxRetries)
+	for _, status := range c.Elasticsearch.RetryOnStatus {
+		check(status >= 400 && status < 600, "elasticsearch.retry_on_status contains %d, expected an HTTP error status", status)
+	}
+	check(c.Elasticsearch.RetryBackoff >= 0, "elasticsearch.retry_backoff must not be negative")
+	check(c.Elasticsearch.Shards > 0, "elasticsearch.shards must be positive, got %d", c.Elasticsearch.Shards)
+	check(c.Elasticsearch.Replicas >= 0, "elasticsearch.replicas must not be negative, got %d", c.Elasticsearch.Replicas)
+
+	switch c.Cache.Backend {
+	case cache.BackendRedis, cache.BackendMemory, cache.BackendNoop:
+	default:
+		check(false, "cache.backend must be one of redis, memory, noop, got %q", c.Cache.Backend)
+	}
+	switch c.Cache.Codec {
+	case cache.CodecJSON, cache.CodecMsgpack:
+	default:
+		check(false, "cache.codec must be json or msgpack, got %q", c.Cache.Codec)
+	}
+	check(c.Cache.PostTTL > 0, "cache.post_ttl must be positive")
+	check(c.Cache.DefaultTTL > 0, "cache.default_ttl must be positive")
+	check(c.Cache.Jitter >= 0, "cache.ttl_jitter must not be negative")
+	check(c.Cache.CompressThreshold >= 0, "cache.compress_threshold must not be negative")
+	check(c.Cache.MaxEntries >= 0, "cache.max_entries must not be negative")
+
+	check(c.Warmup.Strategy == warmup.StrategyRecent || c.Warmup.Strategy == warmup.StrategyViewed,
+		"warmup.strategy must be %q or %q, got %q", warmup.StrategyRecent, warmup.StrategyViewed, c.Warmup.Strategy)
+	check(c.Warmup.Strategy != warmup.StrategyViewed || c.Stats.Enabled, "warmup.strategy %q requires stats.enabled", warmup.StrategyViewed)
+	check(c.Warmup.Limit >= 0, "warmup.limit must not be negative, got %d", c.Warmup.Limit)
+	check(c.Warmup.BatchSize > 0, "warmup.batch_size must be positive, got %d", c.Warmup.BatchSize)
+	check(c.Warmup.Concurrency > 0, "warmup.concurrency must be positive, got %d", c.Warmup.Concurrency)
+	check(c.Warmup.Timeout >= 0, "warmup.timeout must not be negative")
+
+	check(c.Indexing.Workers > 0, "indexing.workers must be positive, got %d", c.Indexing.Workers)
+	check(c.Indexing.QueueSize >= 0, "indexing.queue_size must not be negative, got %d", c.Indexing.QueueSize)
+	check(c.Indexing.RetryInterval > 0, "indexing.retry_interval must be positive")
+	check(c.Indexing.RetryBatchSize > 0, "indexing.retry_batch_size must be positive, got %d", c.Indexing.RetryBatchSize)
+	check(c.Indexing.JobTimeout > 0, "indexing.job_timeout must be positive")
+
+	check(c.Startup.RetryAttempts > 0, "startup.retry_attempts must be positive, got %d", c.Startup.RetryAttempts)
+	check(c.Startup.InitialBackoff >= 0, "startup.initial_backoff must not be negative")
+	check(c.Startup.MaxBackoff >= c.Startup.InitialBackoff, "startup.max_backoff must not be less than startup.initial_backoff")
+
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be positive")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl must not be negative")
+
+	check(c.Timeouts.Request > 0, "timeouts.request must be positive")
+	check(c.Timeouts.Database > 0, "timeouts.database must be positive")
+	check(c.Timeouts.Cache > 0, "timeouts.cache must be positive")
+	check(c.Timeouts.Search > 0, "timeouts.search must be positive")
+	check(c.Server.WriteTimeout == 0 || c.Timeouts.Request < c.Server.WriteTimeout,
+		"timeouts.request (%s) must be shorter than server.write_timeout (%s) so timeouts can still be reported", c.Timeouts.Request, c.Server.WriteTimeout)
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		check(false, "tracing.exporter must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	_, err := logging.ParseLevel(c.Logging.Level)
+	check(err == nil, "logging.level must be one of debug, info, warn, error, got %q", c.Logging.Level)
+	check(c.Logging.Format == logging.FormatJSON || c.Logging.Format == logging.FormatText,
+		"logging.format must be json or text, got %q", c.Logging.Format)
+
+	check(c.RateLimit.Backend == ratelimit.BackendRedis || c.RateLimit.Backend == ratelimit.BackendMemory,
+		"rate_limit.backend must be redis or memory, got %q", c.RateLimit.Backend)
+	if _, err := c.RateLimit.Policy(); err != nil {
+		check(false, "rate_limit: %v", err)
+	}
+
+	check(c.Stats.FlushInterval > 0, "stats.flush_interval must be positive")
+	check(c.Stats.FlushBatchSize > 0, "stats.flush_batch_size must be positive, got %d", c.Stats.FlushBatchSize)
+
+	check(c.Activity.Retention >= 0, "activity.retention must not be negative")
+	check(c.Activity.PruneInterval > 0, "activity.prune_interval must be positive")
+	check(c.Activity.PruneBatchSize > 0, "activity.prune_batch_size must be positive, got %d", c.Activity.PruneBatchSize)
+
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive, got %d", c.Webhooks.BatchSize)
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive, got %d", c.Webhooks.Workers)
+	check(c.Webhooks.RequestTimeout > 0, "webhooks.request_timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive, got %d", c.Webhooks.MaxAttempts)
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initial_backoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.max_backoff must not be shorter than webhooks.initial_backoff")
+
+	check(c.Events.MaxLen > 0, "events.max_len must be positive, got %d", c.Events.MaxLen)
+	check(c.Events.Heartbeat > 0, "events.heartbeat must be positive")
+	check(c.Events.ClientBuffer > 0, "events.client_buffer must be positive, got %d", c.Events.ClientBuffer)
+
+	check(c.CDC.MinReconnectInterval > 0, "cdc.min_reconnect_interval must be positive")
+	check(c.CDC.MaxReconnectInterval >= c.CDC.MinReconnectInterval, "cdc.max_reconnect_interval must not be shorter than cdc.min_reconnect_interval")
+	check(c.CDC.CatchUpOverlap >= 0, "cdc.catch_up_overlap must not be negative")
+	check(c.CDC.CatchUpBatchSize > 0, "cdc.catch_up_batch_size must be positive, got %d", c.CDC.CatchUpBatchSize)
+	check(c.CDC.DeletionRetention > 0, "cdc.deletion_retention must be positive")
+
+	check(c.Auth.AdminToken == "" || len(c.Auth.AdminToken) >= 32,
+		"auth.admin_token must be at least 32 characters")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
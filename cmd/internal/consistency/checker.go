@@ -0,0 +1,290 @@
+package consistency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/search"
+)
+
+// DefaultBatchSize is the number of posts compared at a time when Options
+// does not set one
+const DefaultBatchSize = 200
+
+// orphanBatch is the number of indexed IDs read at a time when looking for
+// documents of deleted posts
+const orphanBatch = 500
+
+// Options bound one check
+type Options struct {
+	// AfterID starts the check after this post ID
+	AfterID int
+	// Limit stops the check after this many posts; 0 checks every post
+	Limit int
+	// BatchSize is the number of posts read and compared at a time
+	BatchSize int
+	// Repair reindexes or removes drifted documents and evicts drifted cache entries
+	Repair bool
+}
+
+// Checker compares posts in Postgres, the source of truth, with their
+// Elasticsearch documents and cached entries
+type Checker struct {
+	repo   *repository.PostRepository
+	search *search.ElasticSearch
+	cache  cache.Cache // nil when the cache is not checked
+	logger *slog.Logger
+}
+
+func NewChecker(repo *repository.PostRepository, search *search.ElasticSearch, cache cache.Cache, logger *slog.Logger) *Checker {
+	return &Checker{
+		repo:   repo,
+		search: search,
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// Hash returns the SHA-256 of a post's title, content, tags, language and
+// reaction count, the fields kept in every copy
+func Hash(post models.PostSnapshot) string {
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	data, _ := json.Marshal(post)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Run walks posts in ID order and reports every one whose copies drifted,
+// along with indexed documents of posts that no longer exist
+func (c *Checker) Run(ctx context.Context, opts Options) (*models.ConsistencyReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	report := &models.ConsistencyReport{
+		StartedAt:    time.Now().UTC(),
+		Repair:       opts.Repair,
+		CacheChecked: c.cache != nil,
+		Drift:        []models.PostDrift{},
+	}
+
+	cursor := opts.AfterID
+	for {
+		size := opts.BatchSize
+		if opts.Limit > 0 {
+			size = min(size, opts.Limit-report.Checked)
+		}
+		posts, err := c.repo.ListPostsAfter(ctx, cursor, size)
+		if err != nil {
+			return nil, err
+		}
+
+		// The last batch also covers documents above the highest post ID
+		end := len(posts) < size
+		upTo := 0
+		if !end {
+			upTo = posts[len(posts)-1].ID
+		}
+		if err := c.checkPosts(ctx, posts, opts.Repair, report); err != nil {
+			return nil, err
+		}
+		if err := c.checkOrphans(ctx, posts, cursor, upTo, opts.Repair, report); err != nil {
+			return nil, err
+		}
+		report.Checked += len(posts)
+
+		if end {
+			break
+		}
+		cursor = upTo
+		if opts.Limit > 0 && report.Checked >= opts.Limit {
+			report.NextAfterID = cursor
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	c.logger.InfoContext(ctx, "consistency check finished",
+		slog.Int("checked", report.Checked),
+		slog.Int("search_drift", report.SearchDrift),
+		slog.Int("cache_drift", report.CacheDrift),
+		slog.Int("repaired", report.Repaired),
+		slog.Duration("elapsed", report.FinishedAt.Sub(report.StartedAt)),
+	)
+	return report, nil
+}
+
+// checkPosts compares one batch of posts with their documents and cached entries
+func (c *Checker) checkPosts(ctx context.Context, posts []*models.Post, repair bool, report *models.ConsistencyReport) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	docs, err := c.search.GetDocuments(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		drift := models.PostDrift{
+			PostID: post.ID,
+			Hash:   Hash(search.Snapshot(post)),
+		}
+		if doc, ok := docs[post.ID]; !ok {
+			drift.Search = models.DriftMissing
+		} else if hash := Hash(doc); hash != drift.Hash {
+			drift.Search = models.DriftStale
+			drift.SearchHash = hash
+		}
+		cached, err := c.cachedHash(ctx, post.ID)
+		if err != nil {
+			return err
+		}
+		if cached != "" && cached != drift.Hash {
+			drift.Cache = models.DriftStale
+			drift.CacheHash = cached
+		}
+
+		if drift.Search == "" && drift.Cache == "" {
+			continue
+		}
+		if repair {
+			c.repair(ctx, &drift)
+		}
+		record(report, drift)
+	}
+	return nil
+}
+
+// checkOrphans reports documents with an ID above afterID and at most upTo
+// (no bound when 0) whose post is not in Postgres
+func (c *Checker) checkOrphans(ctx context.Context, posts []*models.Post, afterID, upTo int, repair bool, report *models.ConsistencyReport) error {
+	known := make(map[int]bool, len(posts))
+	for _, post := range posts {
+		known[post.ID] = true
+	}
+
+	var candidates []int
+	for {
+		ids, err := c.search.DocumentIDsAfter(ctx, afterID, upTo, orphanBatch)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if !known[id] {
+				candidates = append(candidates, id)
+			}
+		}
+		if len(ids) < orphanBatch {
+			break
+		}
+		afterID = ids[len(ids)-1]
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Posts created since the batch was read are not orphans
+	created, err := c.repo.GetPostsByIDs(ctx, candidates)
+	if err != nil {
+		return err
+	}
+	for _, post := range created {
+		known[post.ID] = true
+	}
+
+	for _, id := range candidates {
+		if known[id] {
+			continue
+		}
+		drift := models.PostDrift{PostID: id, Search: models.DriftOrphan}
+		cached, err := c.cachedHash(ctx, id)
+		if err != nil {
+			return err
+		}
+		if cached != "" {
+			drift.Cache = models.DriftOrphan
+			drift.CacheHash = cached
+		}
+		if repair {
+			c.repair(ctx, &drift)
+		}
+		record(report, drift)
+	}
+	return nil
+}
+
+// cachedHash returns the hash of the cached copy of a post, or "" when
+// nothing is cached or the cache is not checked
+func (c *Checker) cachedHash(ctx context.Context, postID int) (string, error) {
+	if c.cache == nil {
+		return "", nil
+	}
+	post, err := c.cache.GetPost(ctx, postID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cached post %d: %w", postID, err)
+	}
+	if post == nil {
+		return "", nil
+	}
+	return Hash(search.Snapshot(post)), nil
+}
+
+// repair evicts the cached entry and indexes the post as it is now in
+// Postgres, which may have changed since it was compared. A post that no
+// longer exists is removed from the index.
+func (c *Checker) repair(ctx context.Context, drift *models.PostDrift) {
+	err := c.fix(ctx, drift)
+	if err != nil {
+		drift.Error = err.Error()
+		c.logger.WarnContext(ctx, "failed to repair post", slog.Int("post_id", drift.PostID), slog.Any("error", err))
+		return
+	}
+	drift.Repaired = true
+}
+
+func (c *Checker) fix(ctx context.Context, drift *models.PostDrift) error {
+	if drift.Cache != "" {
+		if err := c.cache.InvalidatePost(ctx, drift.PostID); err != nil {
+			return err
+		}
+	}
+	if drift.Search == "" {
+		return nil
+	}
+
+	post, err := c.repo.GetPostByID(ctx, drift.PostID)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		return c.search.DeletePost(ctx, drift.PostID)
+	}
+	if err != nil {
+		return err
+	}
+	return c.search.IndexPost(ctx, post)
+}
+
+func record(report *models.ConsistencyReport, drift models.PostDrift) {
+	if drift.Search != "" {
+		report.SearchDrift++
+	}
+	if drift.Cache != "" {
+		report.CacheDrift++
+	}
+	if drift.Repaired {
+		report.Repaired++
+	}
+	report.Drift = append(report.Drift, drift)
+}
@@ -0,0 +1,33 @@
+package consistency
+
+import (
+	"testing"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+func TestHash(t *testing.T) {
+	base := models.PostSnapshot{Title: "Hello", Content: "World", Tags: []string{"go"}, Language: "en", ReactionCount: 2}
+	if Hash(base) != Hash(base) {
+		t.Fatal("Hash is not deterministic")
+	}
+	if Hash(models.PostSnapshot{Title: "Hello"}) != Hash(models.PostSnapshot{Title: "Hello", Tags: []string{}}) {
+		t.Error("nil and empty tags hash differently")
+	}
+
+	changes := map[string]func(*models.PostSnapshot){
+		"title":          func(s *models.PostSnapshot) { s.Title = "Hi" },
+		"content":        func(s *models.PostSnapshot) { s.Content = "There" },
+		"tags":           func(s *models.PostSnapshot) { s.Tags = []string{"rust"} },
+		"language":       func(s *models.PostSnapshot) { s.Language = "vi" },
+		"reaction count": func(s *models.PostSnapshot) { s.ReactionCount = 3 },
+	}
+	for field, change := range changes {
+		changed := base
+		changed.Tags = append([]string(nil), base.Tags...)
+		change(&changed)
+		if Hash(changed) == Hash(base) {
+			t.Errorf("changing the %s does not change the hash", field)
+		}
+	}
+}
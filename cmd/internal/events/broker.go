@@ -0,0 +1,233 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// publishScript appends an event to the stream and announces it with its
+// stream ID in one step, so every replica sees events in stream order
+var publishScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], '*', 'event', ARGV[2])
+redis.call('PUBLISH', KEYS[2], id .. ' ' .. ARGV[2])
+return id
+`)
+
+// Broker publishes post events to Redis and fans the events published by
+// every replica out to the clients connected to this one
+type Broker struct {
+	client *redis.Client
+	cfg    Config
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// Subscription receives the live events matching its filter
+type Subscription struct {
+	broker  *Broker
+	filter  Filter
+	events  chan models.PostEvent
+	dropped bool
+}
+
+func NewBroker(client *redis.Client, cfg Config, logger *slog.Logger) *Broker {
+	return &Broker{
+		client: client,
+		cfg:    cfg,
+		logger: logger,
+		subs:   make(map[*Subscription]struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start listens on the Redis channel until Shutdown. go-redis resubscribes
+// after a disconnect; events published meanwhile are only in the stream.
+func (b *Broker) Start() {
+	pubsub := b.client.Subscribe(context.Background(), Channel)
+	b.wg.Add(1)
+	go b.receiveLoop(pubsub)
+}
+
+// Shutdown stops listening and ends every subscription, so open streams
+// finish and their clients reconnect to another replica
+func (b *Broker) Shutdown(ctx context.Context) error {
+	b.once.Do(func() { close(b.stop) })
+
+	b.mu.Lock()
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.events)
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Broker) receiveLoop(pubsub *redis.PubSub) {
+	defer b.wg.Done()
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			event, err := decodeMessage(msg.Payload)
+			if err != nil {
+				b.logger.Warn("ignoring malformed post event", slog.Any("error", err))
+				continue
+			}
+			b.broadcast(event)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// broadcast hands event to every matching subscription, dropping those
+// whose buffer is full rather than blocking the others
+func (b *Broker) broadcast(event models.PostEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.filter.Match(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.dropped = true
+			delete(b.subs, s)
+			close(s.events)
+			metrics.EventStreamDropped.Inc()
+		}
+	}
+}
+
+// Publish records event in the stream and sends it to every replica. The
+// stream ID is assigned by Redis and returned.
+func (b *Broker) Publish(ctx context.Context, event models.PostEvent) (string, error) {
+	event.ID = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode post event: %w", err)
+	}
+	id, err := publishScript.Run(ctx, b.client, []string{StreamKey, Channel}, b.cfg.MaxLen, data).Text()
+	if err != nil {
+		return "", fmt.Errorf("failed to publish post event: %w", err)
+	}
+	return id, nil
+}
+
+// Since returns up to count events recorded after the event with lastID,
+// oldest first
+func (b *Broker) Since(ctx context.Context, lastID string, count int64) ([]models.PostEvent, error) {
+	entries, err := b.client.XRangeN(ctx, StreamKey, "("+lastID, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post events: %w", err)
+	}
+
+	events := make([]models.PostEvent, 0, len(entries))
+	for _, entry := range entries {
+		data, _ := entry.Values["event"].(string)
+		var event models.PostEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode post event %s: %w", entry.ID, err)
+		}
+		event.ID = entry.ID
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Subscribe starts receiving live events matching filter. The caller must
+// Close the subscription.
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	s := &Subscription{
+		broker: b,
+		filter: filter,
+		events: make(chan models.PostEvent, b.cfg.ClientBuffer),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(s.events)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Clients returns the number of open subscriptions
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Events returns the live events. It is closed when the subscription ends.
+func (s *Subscription) Events() <-chan models.PostEvent {
+	return s.events
+}
+
+// Dropped reports whether the subscription was ended because its client fell
+// behind. Only meaningful once Events is closed.
+func (s *Subscription) Dropped() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// decodeMessage parses "<stream ID> <event JSON>" sent by publishScript
+func decodeMessage(payload string) (models.PostEvent, error) {
+	var event models.PostEvent
+	id, data, ok := strings.Cut(payload, " ")
+	if !ok || !ValidID(id) {
+		return event, errors.New("post event has no stream ID")
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, err
+	}
+	event.ID = id
+	return event, nil
+}
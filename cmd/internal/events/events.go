@@ -0,0 +1,84 @@
+package events
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// Redis keys shared by every replica
+const (
+	// StreamKey holds recent events for Last-Event-ID resume
+	StreamKey = "events:posts"
+	// Channel carries live events to every replica
+	Channel = "events:posts:live"
+)
+
+// Config controls the post event stream
+type Config struct {
+	Enabled bool
+	// MaxLen bounds the Redis stream; clients resuming from an older event
+	// miss the events trimmed since
+	MaxLen int
+	// Heartbeat is how often an idle stream sends a comment so proxies keep it open
+	Heartbeat time.Duration
+	// ClientBuffer is the number of events queued per client. A client that
+	// falls further behind is disconnected and resumes with Last-Event-ID.
+	ClientBuffer int
+}
+
+// DefaultConfig returns the stream settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      true,
+		MaxLen:       10000,
+		Heartbeat:    15 * time.Second,
+		ClientBuffer: 64,
+	}
+}
+
+// Filter selects the events sent to one client. Empty fields match everything.
+type Filter struct {
+	Tag string
+	// Author is the API key name or X-User-ID that made the change
+	Author string
+}
+
+// Match reports whether event passes the filter
+func (f Filter) Match(event models.PostEvent) bool {
+	if f.Author != "" && event.Actor != f.Author {
+		return false
+	}
+	if f.Tag != "" && (event.Post == nil || !slices.Contains(event.Post.Tags, f.Tag)) {
+		return false
+	}
+	return true
+}
+
+var idPattern = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// ValidID reports whether id is a Redis stream ID such as 1710498600000-0
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// After reports whether stream ID a comes after b. Both must be valid.
+func After(a, b string) bool {
+	aMs, aSeq := splitID(a)
+	bMs, bSeq := splitID(b)
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
+
+func splitID(id string) (ms, seq uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ = strconv.ParseUint(msPart, 10, 64)
+	seq, _ = strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
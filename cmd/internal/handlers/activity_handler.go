@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+)
+
+type ActivityHandler struct {
+	activity *repository.ActivityRepository
+	timeouts Timeouts
+	logger   *slog.Logger
+}
+
+func NewActivityHandler(activity *repository.ActivityRepository, timeouts Timeouts, logger *slog.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		activity: activity,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// ListActivity handles GET /activity?action=&actor=&since=&until=&page=&per_page=
+func (h *ActivityHandler) ListActivity(w http.ResponseWriter, r *http.Request) {
+	filter, ok := activityFilter(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, filter)
+}
+
+// ListPostActivity handles GET /posts/:id/activity with the filters of
+// ListActivity. Deleted posts keep their history.
+func (h *ActivityHandler) ListPostActivity(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	filter, ok := activityFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.PostID = postID
+	h.list(w, r, filter)
+}
+
+func (h *ActivityHandler) list(w http.ResponseWriter, r *http.Request, filter models.ActivityFilter) {
+	page, perPage, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	entries, total, err := h.activity.ListActivity(dbCtx, filter, page, perPage)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list activity", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list activity")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ActivityPage{
+		Entries: entries,
+		Page:    page,
+		PerPage: perPage,
+		Total:   total,
+	})
+}
+
+// activityFilter reads the action, actor, since and until parameters,
+// answering 400 when they are invalid
+func activityFilter(w http.ResponseWriter, r *http.Request) (models.ActivityFilter, bool) {
+	query := r.URL.Query()
+	filter := models.ActivityFilter{
+		Action: query.Get("action"),
+		Actor:  query.Get("actor"),
+	}
+	if filter.Action != "" && !validActivityAction(filter.Action) {
+		writeError(r.Context(), w, http.StatusBadRequest, "action must be one of "+strings.Join(models.ActivityActions, ", "))
+		return filter, false
+	}
+
+	for _, p := range []struct {
+		name   string
+		target *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, p.name+" must be an RFC 3339 time such as 2024-03-15T10:00:00Z")
+			return filter, false
+		}
+		*p.target = t
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		writeError(r.Context(), w, http.StatusBadRequest, "since must be before until")
+		return filter, false
+	}
+
+	return filter, true
+}
+
+func validActivityAction(action string) bool {
+	for _, a := range models.ActivityActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// requestActor identifies who is making a change, for the activity log: the
+// X-User-ID of a trusted proxy, else the API key name, with the client IP
+// address
+func requestActor(r *http.Request, trustProxy bool) models.Actor {
+	name, key := requestCaller(r, trustProxy)
+	if key != nil {
+		name = key.Name
+	}
+	return models.Actor{
+		Name: truncate(name, 100),
+		IP:   truncate(ratelimit.ClientIP(r, trustProxy), 64),
+	}
+}
+
+// truncate cuts s to the n characters of the column it is stored in, on a
+// rune boundary so the result stays valid UTF-8
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
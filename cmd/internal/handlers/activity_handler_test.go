@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "alice", n: 100, want: "alice"},
+		{s: "alice", n: 3, want: "ali"},
+		{s: "phở bò", n: 3, want: "phở"},
+		{s: "phở", n: 0, want: ""},
+		{s: "", n: 5, want: ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRequestActor(t *testing.T) {
+	// 132 characters but well over 100 bytes: a byte cut would split a rune
+	key := &auth.Principal{KeyID: 3, Name: strings.Repeat("Khóa ứng dụng di động ", 6)}
+
+	r := httptest.NewRequest("POST", "/posts", nil)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), key))
+	name := requestActor(r, false).Name
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) != 100 || !strings.HasPrefix(key.Name, name) {
+		t.Fatalf("actor = %q, want the first 100 characters of the key name", name)
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		trustProxy bool
+		want       string
+	}{
+		{name: "header from a direct client is ignored", userID: "alice", want: name},
+		{name: "trusted proxy user comes first", userID: "alice", trustProxy: true, want: "alice"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/posts", nil)
+		r.Header.Set(UserIDHeader, tt.userID)
+		r = r.WithContext(auth.WithPrincipal(r.Context(), key))
+		if got := requestActor(r, tt.trustProxy).Name; got != tt.want {
+			t.Errorf("%s: actor = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
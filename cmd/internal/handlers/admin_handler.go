@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hungpv1995/golang_training_2025/internal/consistency"
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+)
+
+// Posts compared per call to POST /admin/consistency, so a check fits in the
+// request timeout; cmd/consistency walks every post
+const (
+	defaultConsistencyLimit = 1000
+	maxConsistencyLimit     = 10000
+)
+
+// LogLevel is the body of GET and PUT /admin/log-level
+type LogLevel struct {
+	Level string `json:"level" validate:"required"`
+}
+
+type AdminHandler struct {
+	checker  *consistency.Checker
+	logger   *slog.Logger
+	logLevel *slog.LevelVar
+}
+
+func NewAdminHandler(checker *consistency.Checker, logger *slog.Logger, logLevel *slog.LevelVar) *AdminHandler {
+	return &AdminHandler{
+		checker:  checker,
+		logger:   logger,
+		logLevel: logLevel,
+	}
+}
+
+// GetLogLevel handles GET /admin/log-level
+func (h *AdminHandler) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	h.writeLogLevel(w)
+}
+
+// SetLogLevel handles PUT /admin/log-level with a body like {"level": "debug"}
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevel
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	previous := h.logLevel.Level()
+	h.logLevel.Set(level)
+	h.logger.InfoContext(r.Context(), "log level changed",
+		slog.String("from", previous.String()),
+		slog.String("to", level.String()),
+	)
+
+	h.writeLogLevel(w)
+}
+
+// CheckConsistency handles POST /admin/consistency?after_id=0&limit=1000&repair=true.
+// It compares posts with their search documents and this replica's cache,
+// repairing drift when asked; next_after_id continues the walk.
+func (h *AdminHandler) CheckConsistency(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := consistency.Options{Limit: defaultConsistencyLimit}
+	if v := query.Get("after_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(r.Context(), w, http.StatusBadRequest, "after_id must be a non-negative integer")
+			return
+		}
+		opts.AfterID = n
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxConsistencyLimit {
+			writeError(r.Context(), w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxConsistencyLimit))
+			return
+		}
+		opts.Limit = n
+	}
+	if v := query.Get("repair"); v != "" {
+		repair, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "repair must be true or false")
+			return
+		}
+		opts.Repair = repair
+	}
+
+	report, err := h.checker.Run(r.Context(), opts)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "consistency check failed", slog.Any("error", err))
+		writeOperationError(r.Context(), w, err, http.StatusInternalServerError, "Consistency check failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *AdminHandler) writeLogLevel(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LogLevel{
+		Level: strings.ToLower(h.logLevel.Level().String()),
+	})
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+// maxRotationGrace bounds how long a rotated key stays valid
+const maxRotationGrace = 7 * 24 * time.Hour
+
+type APIKeyHandler struct {
+	repo     *repository.APIKeyRepository
+	timeouts Timeouts
+	logger   *slog.Logger
+}
+
+func NewAPIKeyHandler(repo *repository.APIKeyRepository, timeouts Timeouts, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		repo:     repo,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// CreateAPIKey handles POST /api-keys. The key is only ever returned here.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAPIKeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	var errs validation.Errors
+	for i, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			errs = append(errs, validation.FieldError{
+				Field:   fmt.Sprintf("scopes[%d]", i),
+				Code:    validation.CodeInvalidValue,
+				Message: "must be one of " + strings.Join(auth.Scopes, ", "),
+			})
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errs = append(errs, validation.FieldError{Field: "expires_at", Code: validation.CodeInvalidValue, Message: "must be in the future"})
+	}
+	if len(errs) > 0 {
+		writeValidationError(r.Context(), w, errs)
+		return
+	}
+	if errs := ungrantableScopes(r, req.Scopes); len(errs) > 0 {
+		writeErrorResponse(r.Context(), w, http.StatusForbidden, ErrorResponse{
+			Error:  "Cannot grant scopes you do not hold",
+			Fields: errs,
+		})
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to generate api key", slog.Any("error", err))
+		writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	created, err := h.repo.CreateAPIKey(dbCtx, &req, prefix, hash)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to create api key", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "api key created",
+		slog.Int("key_id", created.ID),
+		slog.String("name", created.Name),
+		slog.Any("scopes", created.Scopes),
+		slog.String("created_by", principalName(r)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreatedAPIKey{APIKey: *created, Key: key})
+}
+
+// ListAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	keys, err := h.repo.ListAPIKeys(dbCtx)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list api keys", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RotateAPIKey handles POST /api-keys/:id/rotate with an optional
+// {"grace_period": "1h"} during which the old key keeps working
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	var req models.RotateAPIKeyRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+	var grace time.Duration
+	if req.GracePeriod != "" {
+		grace, err = time.ParseDuration(req.GracePeriod)
+		if err != nil || grace < 0 || grace > maxRotationGrace {
+			writeError(r.Context(), w, http.StatusBadRequest, "grace_period must be a duration between 0s and "+maxRotationGrace.String())
+			return
+		}
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+
+	// The replacement inherits the old key's scopes, so rotating is granting them
+	existing, err := h.repo.GetAPIKey(dbCtx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "API key not found or already revoked")
+		} else {
+			h.logger.ErrorContext(r.Context(), "failed to get api key", slog.Int("key_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to rotate API key")
+		}
+		return
+	}
+	if errs := ungrantableScopes(r, existing.Scopes); len(errs) > 0 {
+		writeErrorResponse(r.Context(), w, http.StatusForbidden, ErrorResponse{
+			Error:  "Cannot rotate a key with scopes you do not hold",
+			Fields: errs,
+		})
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to generate api key", slog.Any("error", err))
+		writeError(r.Context(), w, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+
+	rotated, err := h.repo.RotateAPIKey(dbCtx, id, prefix, hash, grace)
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "API key not found or already revoked")
+		} else {
+			h.logger.ErrorContext(r.Context(), "failed to rotate api key", slog.Int("key_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to rotate API key")
+		}
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "api key rotated",
+		slog.Int("key_id", id),
+		slog.Int("new_key_id", rotated.ID),
+		slog.Duration("grace_period", grace),
+		slog.String("rotated_by", principalName(r)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreatedAPIKey{APIKey: *rotated, Key: key})
+}
+
+// RevokeAPIKey handles DELETE /api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	if err := h.repo.RevokeAPIKey(dbCtx, id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "API key not found or already revoked")
+		} else {
+			h.logger.ErrorContext(r.Context(), "failed to revoke api key", slog.Int("key_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to revoke API key")
+		}
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "api key revoked", slog.Int("key_id", id), slog.String("revoked_by", principalName(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ungrantableScopes returns a field error for every scope the caller may not
+// grant, so a keys:manage key cannot mint keys more powerful than itself
+func ungrantableScopes(r *http.Request, scopes []string) validation.Errors {
+	p := auth.PrincipalFrom(r.Context())
+	var errs validation.Errors
+	for i, scope := range scopes {
+		if p == nil || !p.CanGrant(scope) {
+			errs = append(errs, validation.FieldError{
+				Field:   fmt.Sprintf("scopes[%d]", i),
+				Code:    validation.CodeNotPermitted,
+				Message: "not held by the calling key",
+			})
+		}
+	}
+	return errs
+}
+
+func principalName(r *http.Request) string {
+	if p := auth.PrincipalFrom(r.Context()); p != nil {
+		return p.Name
+	}
+	return ""
+}
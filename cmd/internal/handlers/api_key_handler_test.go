@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+)
+
+func TestCreateAPIKeyRejectsUngrantableScopes(t *testing.T) {
+	// The repository is nil: a rejected request must never reach it
+	h := NewAPIKeyHandler(nil, Timeouts{Database: time.Second}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	manager := &auth.Principal{KeyID: 7, Name: "manager", Scopes: []string{auth.ScopeKeysManage, auth.ScopePostsRead}}
+
+	r := httptest.NewRequest("POST", "/api-keys", strings.NewReader(`{"name": "escalate", "scopes": ["posts:read", "admin", "posts:write"]}`))
+	r = r.WithContext(auth.WithPrincipal(r.Context(), manager))
+	w := httptest.NewRecorder()
+	h.CreateAPIKey(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, f := range resp.Fields {
+		fields = append(fields, f.Field+"="+f.Code)
+	}
+	if got, want := strings.Join(fields, ","), "scopes[1]=not_permitted,scopes[2]=not_permitted"; got != want {
+		t.Errorf("fields = %s, want %s", got, want)
+	}
+}
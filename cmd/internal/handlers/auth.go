@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+)
+
+// AuthFailureLimit throttles failed API key attempts per client IP
+type AuthFailureLimit struct {
+	Limiter    ratelimit.Limiter
+	Limit      ratelimit.Limit
+	TrustProxy bool
+}
+
+// Authorizer authenticates API keys and enforces scopes on routes
+type Authorizer struct {
+	authn *auth.Authenticator
+	// enforce makes Require reject anonymous and under-scoped requests;
+	// RequireAlways ignores it
+	enforce bool
+	// failures is nil when failed attempts are not limited
+	failures *AuthFailureLimit
+	timeouts Timeouts
+	logger   *slog.Logger
+}
+
+func NewAuthorizer(authn *auth.Authenticator, enforce bool, failures *AuthFailureLimit, timeouts Timeouts, logger *slog.Logger) *Authorizer {
+	return &Authorizer{
+		authn:    authn,
+		enforce:  enforce,
+		failures: failures,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Authenticate resolves an "Authorization: ApiKey <key>" header into the
+// request's principal. Requests without the header pass through anonymously;
+// a key that is present but invalid is rejected with 401. An IP that keeps
+// presenting invalid keys gets 429 before its keys are even looked up.
+func (a *Authorizer) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if a.failureLimited(w, r) {
+			return
+		}
+
+		scheme, key, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "ApiKey") || key == "" {
+			a.recordFailure(r)
+			unauthorized(w, r, "Authorization header must be \"ApiKey <key>\"")
+			return
+		}
+
+		dbCtx, cancel := withTimeout(r.Context(), a.timeouts.Database)
+		principal, err := a.authn.Authenticate(dbCtx, strings.TrimSpace(key))
+		cancel()
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidKey) {
+				a.logger.ErrorContext(r.Context(), "failed to authenticate api key", slog.Any("error", err))
+				writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to authenticate")
+				return
+			}
+			a.recordFailure(r)
+			unauthorized(w, r, "Invalid API key")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// failureKey is the bucket of failed attempts from the client's IP. The
+// IP, not X-User-ID, is used: a guesser can change the header at will.
+func (a *Authorizer) failureKey(r *http.Request) string {
+	return "auth-failures:ip:" + ratelimit.ClientIP(r, a.failures.TrustProxy)
+}
+
+// failureLimited answers 429 when the client has used up its failed
+// attempts. A limiter failure lets the request through.
+func (a *Authorizer) failureLimited(w http.ResponseWriter, r *http.Request) bool {
+	if a.failures == nil {
+		return false
+	}
+	result, err := a.failures.Limiter.Check(r.Context(), a.failureKey(r), a.failures.Limit)
+	if err != nil {
+		a.logger.ErrorContext(r.Context(), "auth failure limit check failed", slog.Any("error", err))
+		return false
+	}
+	if result.Allowed {
+		return false
+	}
+	result.SetHeaders(w.Header())
+	metrics.RateLimited.WithLabelValues(r.Method, ratelimit.NormalizeRoute(routeTemplate(r))).Inc()
+	writeError(r.Context(), w, http.StatusTooManyRequests, "Too many failed authentication attempts")
+	return true
+}
+
+// recordFailure takes a token from the client's failed attempts
+func (a *Authorizer) recordFailure(r *http.Request) {
+	if a.failures == nil {
+		return
+	}
+	if _, err := a.failures.Limiter.Allow(r.Context(), a.failureKey(r), a.failures.Limit); err != nil {
+		a.logger.ErrorContext(r.Context(), "failed to record auth failure", slog.Any("error", err))
+	}
+}
+
+// Require protects a route with scope when authentication is enforced
+func (a *Authorizer) Require(scope string, next http.HandlerFunc) http.Handler {
+	if !a.enforce {
+		return next
+	}
+	return a.RequireAlways(scope, next)
+}
+
+// RequireAlways protects a route with scope even when authentication is not
+// enforced for the rest of the API, e.g. key management and admin routes
+func (a *Authorizer) RequireAlways(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFrom(r.Context())
+		if principal == nil {
+			unauthorized(w, r, "API key required")
+			return
+		}
+		if !principal.HasScope(scope) {
+			writeError(r.Context(), w, http.StatusForbidden, "API key lacks the "+scope+" scope")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `ApiKey realm="blog-api"`)
+	writeError(r.Context(), w, http.StatusUnauthorized, message)
+}
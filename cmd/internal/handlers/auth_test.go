@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+)
+
+func TestAuthenticateLimitsFailedAttempts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	failures := &AuthFailureLimit{
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Limit:   ratelimit.Limit{Rate: 1.0 / 60, Burst: 3},
+	}
+	authorizer := NewAuthorizer(auth.NewAuthenticator(nil, "admin-token", time.Second, logger), false, failures, Timeouts{Database: time.Second}, logger)
+	handler := authorizer.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(header, remoteAddr string) int {
+		r := httptest.NewRequest("GET", "/posts/1", nil)
+		r.RemoteAddr = remoteAddr
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	steps := []struct {
+		name   string
+		header string
+		addr   string
+		want   int
+	}{
+		{name: "valid key", header: "ApiKey admin-token", addr: "192.0.2.1:1", want: http.StatusNoContent},
+		{name: "first failure", header: "ApiKey guess-1", addr: "192.0.2.1:1", want: http.StatusUnauthorized},
+		{name: "malformed header counts", header: "Bearer x", addr: "192.0.2.1:2", want: http.StatusUnauthorized},
+		{name: "last failure", header: "ApiKey guess-2", addr: "192.0.2.1:3", want: http.StatusUnauthorized},
+		{name: "guess after the limit", header: "ApiKey guess-3", addr: "192.0.2.1:1", want: http.StatusTooManyRequests},
+		{name: "valid key is not even checked", header: "ApiKey admin-token", addr: "192.0.2.1:1", want: http.StatusTooManyRequests},
+		{name: "anonymous requests pass", addr: "192.0.2.1:1", want: http.StatusNoContent},
+		{name: "other addresses are not affected", header: "ApiKey admin-token", addr: "192.0.2.2:1", want: http.StatusNoContent},
+	}
+	for _, step := range steps {
+		if got := request(step.header, step.addr); got != step.want {
+			t.Errorf("%s: status %d, want %d", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+// UserIDHeader identifies the end user on whose behalf a request is made. It
+// is only honoured from a trusted proxy, which authenticates the user and
+// strips the header from client requests.
+const UserIDHeader = "X-User-ID"
+
+// Pagination defaults for comment listings. maxPage keeps the offset
+// (page-1)*per_page far from overflowing.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	maxPage        = 10000
+)
+
+type CommentHandler struct {
+	comments   *repository.CommentRepository
+	cache      cache.Cache
+	trustProxy bool // take comment authors from the X-User-ID of the proxy
+	timeouts   Timeouts
+	logger     *slog.Logger
+}
+
+func NewCommentHandler(comments *repository.CommentRepository, cache cache.Cache, trustProxy bool, timeouts Timeouts, logger *slog.Logger) *CommentHandler {
+	return &CommentHandler{
+		comments:   comments,
+		cache:      cache,
+		trustProxy: trustProxy,
+		timeouts:   timeouts,
+		logger:     logger,
+	}
+}
+
+// ListComments handles GET /posts/:id/comments?page=1&per_page=20
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	page, perPage, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	threads, total, err := h.comments.ListThreads(dbCtx, postID, page, perPage)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list comments", slog.Int("post_id", postID), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list comments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CommentPage{
+		Comments: threads,
+		Page:     page,
+		PerPage:  perPage,
+		Total:    total,
+	})
+}
+
+// CreateComment handles POST /posts/:id/comments. New comments are pending
+// until a moderator approves them.
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	authorID, ok := requireUser(w, r, h.trustProxy)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comment, err := h.comments.CreateComment(dbCtx, postID, authorID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPostNotFound):
+			writeError(r.Context(), w, http.StatusNotFound, "Post not found")
+		case errors.Is(err, repository.ErrInvalidParent):
+			writeValidationError(r.Context(), w, validation.Errors{{
+				Field:   "parent_id",
+				Code:    validation.CodeInvalidValue,
+				Message: "must be a comment on this post",
+			}})
+		default:
+			h.logger.ErrorContext(r.Context(), "failed to create comment", slog.Int("post_id", postID), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to create comment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// UpdateComment handles PUT /comments/:id. Only the author may edit, and the
+// edited comment goes back to the moderation queue.
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id, authorized := h.authorizeAuthor(w, r)
+	if !authorized {
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comment, countChanged, err := h.comments.UpdateComment(dbCtx, id, req.Body)
+	if err != nil {
+		h.writeCommentError(dbCtx, w, r, id, err, "Failed to update comment")
+		return
+	}
+	if countChanged {
+		h.invalidatePost(r.Context(), comment.PostID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteComment handles DELETE /comments/:id. Only the author may delete;
+// the comment stays as a placeholder so its replies keep their thread.
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, authorized := h.authorizeAuthor(w, r)
+	if !authorized {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comment, countChanged, err := h.comments.DeleteComment(dbCtx, id)
+	if err != nil {
+		h.writeCommentError(dbCtx, w, r, id, err, "Failed to delete comment")
+		return
+	}
+	if countChanged {
+		h.invalidatePost(r.Context(), comment.PostID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListModerationQueue handles GET /comments/moderation?status=pending&page=1
+func (h *CommentHandler) ListModerationQueue(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.CommentPending
+	}
+	if !validCommentStatus(status) {
+		writeError(r.Context(), w, http.StatusBadRequest, "status must be one of "+strings.Join(models.CommentStatuses, ", "))
+		return
+	}
+	page, perPage, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comments, total, err := h.comments.ListByStatus(dbCtx, status, page, perPage)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list moderation queue", slog.String("status", status), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list comments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.CommentPage{
+		Comments: comments,
+		Page:     page,
+		PerPage:  perPage,
+		Total:    total,
+	})
+}
+
+// ModerateComment handles PUT /comments/:id/status with {"status": "approved"}
+func (h *CommentHandler) ModerateComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	var req models.ModerateCommentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validCommentStatus(req.Status) {
+		writeValidationError(r.Context(), w, validation.Errors{{
+			Field:   "status",
+			Code:    validation.CodeInvalidValue,
+			Message: "must be one of " + strings.Join(models.CommentStatuses, ", "),
+		}})
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comment, countChanged, err := h.comments.SetStatus(dbCtx, id, req.Status)
+	if err != nil {
+		h.writeCommentError(dbCtx, w, r, id, err, "Failed to moderate comment")
+		return
+	}
+	if countChanged {
+		h.invalidatePost(r.Context(), comment.PostID)
+	}
+
+	h.logger.InfoContext(r.Context(), "comment moderated",
+		slog.Int("comment_id", id),
+		slog.String("status", req.Status),
+		slog.String("moderator", principalName(r)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// authorizeAuthor parses the comment ID and checks that the caller wrote it
+func (h *CommentHandler) authorizeAuthor(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid comment ID")
+		return 0, false
+	}
+	userID, ok := requireUser(w, r, h.trustProxy)
+	if !ok {
+		return 0, false
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	comment, err := h.comments.GetComment(dbCtx, id)
+	if err != nil {
+		h.writeCommentError(dbCtx, w, r, id, err, "Failed to get comment")
+		return 0, false
+	}
+	if comment.AuthorID != userID {
+		writeError(r.Context(), w, http.StatusForbidden, "Only the author can change this comment")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *CommentHandler) writeCommentError(ctx context.Context, w http.ResponseWriter, r *http.Request, id int, err error, message string) {
+	if errors.Is(err, repository.ErrCommentNotFound) {
+		writeError(r.Context(), w, http.StatusNotFound, "Comment not found")
+		return
+	}
+	h.logger.ErrorContext(r.Context(), strings.ToLower(message[:1])+message[1:], slog.Int("comment_id", id), slog.Any("error", err))
+	writeOperationError(ctx, w, err, http.StatusInternalServerError, message)
+}
+
+// invalidatePost drops the cached post so readers see the new comment count.
+// The change is committed, so this runs even if the client has gone away.
+func (h *CommentHandler) invalidatePost(ctx context.Context, postID int) {
+	cacheCtx, cancel := withTimeout(context.WithoutCancel(ctx), h.timeouts.Cache)
+	defer cancel()
+	if err := h.cache.InvalidatePost(cacheCtx, postID); err != nil {
+		h.logger.WarnContext(cacheCtx, "failed to invalidate cached post", slog.Int("post_id", postID), slog.Any("error", err))
+	}
+}
+
+// requestUser identifies the end user a request acts for: "user:" and the
+// X-User-ID set by a trusted proxy, else "key:" and the calling API key. The
+// prefixes keep a proxied user from passing for a key, and clients talking to
+// the API directly cannot claim to be someone else by sending the header.
+func requestUser(r *http.Request, trustProxy bool) string {
+	userID, key := requestCaller(r, trustProxy)
+	switch {
+	case userID != "":
+		return "user:" + userID
+	case key == nil:
+		return ""
+	case key.KeyID == 0:
+		return "key:admin"
+	default:
+		return "key:" + strconv.Itoa(key.KeyID)
+	}
+}
+
+// requestCaller returns who a request acts for, in the order every handler
+// uses: the X-User-ID of a trusted proxy, else the calling API key. A proxy
+// acting through its own key still names the user.
+func requestCaller(r *http.Request, trustProxy bool) (userID string, key *auth.Principal) {
+	if userID := ratelimit.TrustedUserID(r, trustProxy); userID != "" {
+		return userID, nil
+	}
+	return "", auth.PrincipalFrom(r.Context())
+}
+
+// requireUser returns the user a request acts for, answering 401 without one
+func requireUser(w http.ResponseWriter, r *http.Request, trustProxy bool) (string, bool) {
+	userID := requestUser(r, trustProxy)
+	if userID == "" || len(userID) > 100 {
+		writeError(r.Context(), w, http.StatusUnauthorized, "An API key, or "+UserIDHeader+" from a trusted proxy, is required")
+		return "", false
+	}
+	return userID, true
+}
+
+// pagination reads page and per_page, answering 400 when they are invalid
+func pagination(w http.ResponseWriter, r *http.Request) (page, perPage int, ok bool) {
+	page, perPage = 1, defaultPerPage
+	query := r.URL.Query()
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPage {
+			writeError(r.Context(), w, http.StatusBadRequest, "page must be between 1 and "+strconv.Itoa(maxPage))
+			return 0, 0, false
+		}
+		page = n
+	}
+	if v := query.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			writeError(r.Context(), w, http.StatusBadRequest, "per_page must be between 1 and "+strconv.Itoa(maxPerPage))
+			return 0, 0, false
+		}
+		perPage = n
+	}
+	return page, perPage, true
+}
+
+func validCommentStatus(status string) bool {
+	for _, s := range models.CommentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+)
+
+func TestRequestUser(t *testing.T) {
+	key := &auth.Principal{KeyID: 12, Name: "mobile-app", Scopes: []string{auth.ScopeCommentsWrite}}
+	adminToken := &auth.Principal{Name: "admin", Scopes: auth.Scopes}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		userID     string
+		trustProxy bool
+		want       string
+	}{
+		{name: "anonymous", want: ""},
+		{name: "header from a direct client is ignored", userID: "alice", want: ""},
+		{name: "header from a direct client does not override the key", principal: key, userID: "alice", want: "key:12"},
+		{name: "api key", principal: key, want: "key:12"},
+		{name: "admin token", principal: adminToken, want: "key:admin"},
+		{name: "trusted proxy", userID: " alice ", trustProxy: true, want: "user:alice"},
+		{name: "trusted proxy acting through a key", principal: key, userID: "alice", trustProxy: true, want: "user:alice"},
+		{name: "trusted proxy without a user", principal: key, trustProxy: true, want: "key:12"},
+		{name: "proxy user named like a key", principal: key, userID: "key:12", trustProxy: true, want: "user:key:12"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/posts/1/comments", nil)
+		if tt.userID != "" {
+			r.Header.Set(UserIDHeader, tt.userID)
+		}
+		if tt.principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), tt.principal))
+		}
+		if got := requestUser(r, tt.trustProxy); got != tt.want {
+			t.Errorf("%s: requestUser() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantPage    int
+		wantPerPage int
+		wantOK      bool
+	}{
+		{query: "", wantPage: 1, wantPerPage: 20, wantOK: true},
+		{query: "page=3&per_page=50", wantPage: 3, wantPerPage: 50, wantOK: true},
+		{query: "page=10000&per_page=100", wantPage: 10000, wantPerPage: 100, wantOK: true},
+		{query: "page=0"},
+		{query: "page=10001"},
+		{query: "page=9223372036854775807"},
+		{query: "page=abc"},
+		{query: "per_page=0"},
+		{query: "per_page=101"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/comments?"+tt.query, nil)
+		w := httptest.NewRecorder()
+		page, perPage, ok := pagination(w, r)
+		if page != tt.wantPage || perPage != tt.wantPerPage || ok != tt.wantOK {
+			t.Errorf("pagination(%q) = %d, %d, %v; want %d, %d, %v", tt.query, page, perPage, ok, tt.wantPage, tt.wantPerPage, tt.wantOK)
+		}
+		if !ok && w.Code != http.StatusBadRequest {
+			t.Errorf("pagination(%q) answered %d, want %d", tt.query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
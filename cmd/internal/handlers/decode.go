@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+// maxBodyBytes caps JSON request bodies; larger ones are rejected with 413
+const maxBodyBytes = 1 << 20
+
+// decodeJSON reads a single JSON object into dst and checks its validate
+// tags. Unknown fields, trailing data and oversized bodies are rejected. On
+// failure it writes the error response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		if _, trailing := dec.Token(); trailing != io.EOF {
+			err = errors.New("trailing data after JSON object")
+		}
+	}
+	if err != nil {
+		writeDecodeError(r.Context(), w, err)
+		return false
+	}
+
+	if errs := validation.Struct(dst); len(errs) > 0 {
+		writeValidationError(r.Context(), w, errs)
+		return false
+	}
+	return true
+}
+
+// writeDecodeError answers 413 for oversized bodies, 422 for fields that are
+// unknown or of the wrong type and 400 for anything that is not JSON
+func writeDecodeError(ctx context.Context, w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeError(ctx, w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeValidationError(ctx, w, validation.Errors{{
+			Field:   typeErr.Field,
+			Code:    validation.CodeInvalidType,
+			Message: "must be " + jsonTypeName(typeErr.Type.String()),
+		}})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeValidationError(ctx, w, validation.Errors{{
+			Field:   field,
+			Code:    validation.CodeUnknownField,
+			Message: "is not a known field",
+		}})
+	case errors.Is(err, io.EOF):
+		writeError(ctx, w, http.StatusBadRequest, "Request body is required")
+	default:
+		writeError(ctx, w, http.StatusBadRequest, "Invalid request body")
+	}
+}
+
+// writeValidationError answers 422 listing every invalid field
+func writeValidationError(ctx context.Context, w http.ResponseWriter, errs validation.Errors) {
+	writeErrorResponse(ctx, w, http.StatusUnprocessableEntity, ErrorResponse{
+		Error:  "Validation failed",
+		Fields: errs,
+	})
+}
+
+func jsonTypeName(goType string) string {
+	switch {
+	case goType == "string":
+		return "a string"
+	case goType == "bool":
+		return "a boolean"
+	case strings.HasPrefix(goType, "[]"):
+		return "an array"
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "uint"), strings.HasPrefix(goType, "float"):
+		return "a number"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+// ErrorResponse is the body of every error response. RequestID matches the
+// X-Request-ID header and the request_id field in the logs.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+	// Fields lists every invalid field of a 422 response, or every field the
+	// caller may not set in a 403
+	Fields validation.Errors `json:"fields,omitempty"`
+	// Position is the 1-based character of a search query that could not be parsed
+	Position int `json:"position,omitempty"`
+}
+
+// writeError writes a JSON error response carrying the request ID from ctx
+func writeError(ctx context.Context, w http.ResponseWriter, status int, message string) {
+	writeErrorResponse(ctx, w, status, ErrorResponse{Error: message})
+}
+
+func writeErrorResponse(ctx context.Context, w http.ResponseWriter, status int, resp ErrorResponse) {
+	resp.RequestID = logging.RequestID(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/events"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// replayBatch is the number of missed events read from Redis at a time
+const replayBatch = 100
+
+type EventHandler struct {
+	broker    *events.Broker // nil when the event stream is disabled
+	heartbeat time.Duration
+	timeouts  Timeouts
+	logger    *slog.Logger
+}
+
+func NewEventHandler(broker *events.Broker, heartbeat time.Duration, timeouts Timeouts, logger *slog.Logger) *EventHandler {
+	return &EventHandler{
+		broker:    broker,
+		heartbeat: heartbeat,
+		timeouts:  timeouts,
+		logger:    logger,
+	}
+}
+
+// StreamEvents handles GET /events?tag=golang&author=alice as Server-Sent
+// Events. A client reconnecting with Last-Event-ID first gets the events it
+// missed that are still in the Redis stream.
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if h.broker == nil {
+		writeError(r.Context(), w, http.StatusServiceUnavailable, "Event stream is disabled")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := events.Filter{
+		Tag:    strings.TrimSpace(query.Get("tag")),
+		Author: strings.TrimSpace(query.Get("author")),
+	}
+	lastID := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if lastID != "" && !events.ValidID(lastID) {
+		writeError(r.Context(), w, http.StatusBadRequest, "Last-Event-ID must be the id of an event from this stream")
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is missed;
+	// live events already replayed are skipped by ID
+	sub := h.broker.Subscribe(filter)
+	defer sub.Close()
+
+	var missed []models.PostEvent
+	if lastID != "" {
+		cacheCtx, cancel := withTimeout(r.Context(), h.timeouts.Cache)
+		defer cancel()
+		var err error
+		if missed, err = h.broker.Since(cacheCtx, lastID, replayBatch); err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to read missed post events", slog.Any("error", err))
+			writeOperationError(cacheCtx, w, err, http.StatusInternalServerError, "Failed to resume event stream")
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server write timeout. Where the deadline cannot
+	// be lifted the connection is cut and the client resumes.
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	last := lastID
+	for len(missed) > 0 {
+		for _, event := range missed {
+			last = event.ID
+			if !filter.Match(event) {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		if len(missed) < replayBatch {
+			break
+		}
+
+		cacheCtx, cancel := withTimeout(r.Context(), h.timeouts.Cache)
+		var err error
+		missed, err = h.broker.Since(cacheCtx, last, replayBatch)
+		cancel()
+		if err != nil {
+			// The client resumes from the last event sent when it reconnects
+			h.logger.WarnContext(r.Context(), "failed to read missed post events", slog.Any("error", err))
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					h.logger.InfoContext(r.Context(), "event stream client fell behind and was disconnected")
+				}
+				return
+			}
+			if last != "" && !events.After(event.ID, last) {
+				continue
+			}
+			last = event.ID
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one SSE message named after the event type
+func writeEvent(w io.Writer, event models.PostEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
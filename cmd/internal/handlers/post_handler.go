@@ -1,61 +1,82 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/events"
+	"github.com/hungpv1995/golang_training_2025/internal/indexing"
+	"github.com/hungpv1995/golang_training_2025/internal/language"
 	"github.com/hungpv1995/golang_training_2025/internal/models"
 	"github.com/hungpv1995/golang_training_2025/internal/repository"
 	"github.com/hungpv1995/golang_training_2025/internal/search"
+	"github.com/hungpv1995/golang_training_2025/internal/stats"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PostHandler struct {
-	repo   *repository.PostRepository
-	cache  *cache.RedisCache
-	search *search.ElasticSearch
+	repo       *repository.PostRepository
+	cache      cache.Cache
+	search     *search.ElasticSearch
+	indexer    *indexing.Queue // nil when change data capture reindexes posts
+	stats      *stats.Tracker  // nil when view counting is disabled
+	events     *events.Broker  // nil when the event stream is disabled
+	trustProxy bool            // take activity log client IPs from X-Forwarded-For
+	timeouts   Timeouts
+	logger     *slog.Logger
 }
 
-func NewPostHandler(repo *repository.PostRepository, cache *cache.RedisCache, search *search.ElasticSearch) *PostHandler {
+func NewPostHandler(repo *repository.PostRepository, cache cache.Cache, search *search.ElasticSearch, indexer *indexing.Queue, stats *stats.Tracker, events *events.Broker, trustProxy bool, timeouts Timeouts, logger *slog.Logger) *PostHandler {
 	return &PostHandler{
-		repo:   repo,
-		cache:  cache,
-		search: search,
+		repo:       repo,
+		cache:      cache,
+		search:     search,
+		indexer:    indexer,
+		stats:      stats,
+		events:     events,
+		trustProxy: trustProxy,
+		timeouts:   timeouts,
+		logger:     logger,
 	}
 }
 
 // CreatePost handles POST /posts
 func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatePostRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
-
-	// Validate input
-	if req.Title == "" || req.Content == "" {
-		http.Error(w, "Title and content are required", http.StatusBadRequest)
+	var ok bool
+	if req.Language, ok = postLanguage(w, r, req.Language, req.Title, req.Content); !ok {
 		return
 	}
 
 	// Create post with transaction
-	post, err := h.repo.CreatePostWithTransaction(&req)
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	actor := requestActor(r, h.trustProxy)
+	post, err := h.repo.CreatePostWithTransaction(dbCtx, &req, actor)
 	if err != nil {
-		log.Printf("Failed to create post: %v", err)
-		http.Error(w, "Failed to create post", http.StatusInternalServerError)
+		h.logger.ErrorContext(dbCtx, "failed to create post", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to create post")
 		return
 	}
 
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int("post.id", post.ID))
+
 	// Index in Elasticsearch asynchronously
-	go func() {
-		if err := h.search.IndexPost(post); err != nil {
-			log.Printf("Failed to index post in Elasticsearch: %v", err)
-		}
-	}()
+	h.reindex(r.Context(), post.ID)
+	h.publishEvent(r, models.EventPostCreated, post.ID, actor, &models.PostSnapshot{Title: post.Title, Content: post.Content, Tags: post.Tags, Language: post.Language})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -67,49 +88,146 @@ func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int("post.id", id))
+
 	// Try to get from cache first
-	cachedPost, err := h.cache.GetPost(id)
+	cacheCtx, cancel := withTimeout(ctx, h.timeouts.Cache)
+	cachedPost, err := h.cache.GetPost(cacheCtx, id)
+	cancel()
 	if err != nil {
-		log.Printf("Cache error: %v", err)
+		h.logger.WarnContext(ctx, "cache read failed", slog.Int("post_id", id), slog.Any("error", err))
 	}
 
 	if cachedPost != nil {
 		// Cache hit
-		log.Printf("Cache hit for post %d", id)
+		span.SetAttributes(attribute.Bool("cache.hit", true))
 		// Add related posts
-		cachedPost.RelatedPosts = h.search.GetRelatedPosts(cachedPost.ID, cachedPost.Tags)
+		cachedPost.RelatedPosts = h.relatedPosts(r, cachedPost)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(cachedPost)
+		h.recordView(r, id)
 		return
 	}
 
 	// Cache miss - get from database
-	log.Printf("Cache miss for post %d", id)
-	post, err := h.repo.GetPostByID(id)
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	dbCtx, cancel := withTimeout(ctx, h.timeouts.Database)
+	defer cancel()
+	post, err := h.repo.GetPostByID(dbCtx, id)
 	if err != nil {
-		if err.Error() == "post not found" {
-			http.Error(w, "Post not found", http.StatusNotFound)
+		if errors.Is(err, repository.ErrPostNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Post not found")
 		} else {
-			log.Printf("Failed to get post: %v", err)
-			http.Error(w, "Failed to get post", http.StatusInternalServerError)
+			h.logger.ErrorContext(ctx, "failed to get post", slog.Int("post_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to get post")
 		}
 		return
 	}
 
-	// Cache the result with 5 minute TTL
-	if err := h.cache.SetPost(post, 5*time.Minute); err != nil {
-		log.Printf("Failed to cache post: %v", err)
+	// Cache the result with the configured post TTL
+	cacheCtx, cancel = withTimeout(ctx, h.timeouts.Cache)
+	if err := h.cache.SetPost(cacheCtx, post); err != nil {
+		h.logger.WarnContext(ctx, "failed to cache post", slog.Int("post_id", id), slog.Any("error", err))
 	}
+	cancel()
 
 	// Get related posts (bonus feature)
-	post.RelatedPosts = h.search.GetRelatedPosts(post.ID, post.Tags)
+	post.RelatedPosts = h.relatedPosts(r, post)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(post)
+	h.recordView(r, id)
+}
+
+// recordView counts a view once the response has been written, so a slow or
+// unavailable Redis never delays reading a post
+func (h *PostHandler) recordView(r *http.Request, id int) {
+	if h.stats == nil {
+		return
+	}
+	ctx, cancel := withTimeout(context.WithoutCancel(r.Context()), h.timeouts.Cache)
+	defer cancel()
+	if err := h.stats.RecordView(ctx, r, id); err != nil {
+		h.logger.WarnContext(ctx, "failed to record post view", slog.Int("post_id", id), slog.Any("error", err))
+	}
+}
+
+// Trending limits for GET /posts/trending
+const (
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+// TrendingPosts handles GET /posts/trending?window=24h&limit=10
+func (h *PostHandler) TrendingPosts(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		writeError(r.Context(), w, http.StatusServiceUnavailable, "View statistics are disabled")
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = stats.Window24h
+	}
+	if _, err := stats.ParseWindow(window); err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := defaultTrendingLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTrendingLimit {
+			writeError(r.Context(), w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxTrendingLimit))
+			return
+		}
+		limit = n
+	}
+
+	cacheCtx, cancel := withTimeout(r.Context(), h.timeouts.Cache)
+	defer cancel()
+	scores, err := h.stats.Trending(cacheCtx, window, limit)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to get trending posts", slog.String("window", window), slog.Any("error", err))
+		writeOperationError(cacheCtx, w, err, http.StatusInternalServerError, "Failed to get trending posts")
+		return
+	}
+
+	resp := models.TrendingResponse{Window: window, Posts: []models.TrendingPost{}}
+	if len(scores) > 0 {
+		ids := make([]int, len(scores))
+		for i, s := range scores {
+			ids[i] = s.PostID
+		}
+
+		dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+		defer cancel()
+		posts, err := h.repo.GetPostsByIDs(dbCtx, ids)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "failed to load trending posts", slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to get trending posts")
+			return
+		}
+
+		byID := make(map[int]*models.Post, len(posts))
+		for _, p := range posts {
+			byID[p.ID] = p
+		}
+		// Keep the score order; deleted posts are skipped
+		for _, s := range scores {
+			if p, ok := byID[s.PostID]; ok {
+				resp.Posts = append(resp.Posts, models.TrendingPost{ID: p.ID, Title: p.Title, Tags: p.Tags, Score: s.Score})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
 // UpdatePost handles PUT /posts/:id
@@ -117,70 +235,109 @@ func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
 	var req models.UpdatePostRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
-
-	// Validate input
-	if req.Title == "" || req.Content == "" {
-		http.Error(w, "Title and content are required", http.StatusBadRequest)
+	var ok bool
+	if req.Language, ok = postLanguage(w, r, req.Language, req.Title, req.Content); !ok {
 		return
 	}
 
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int("post.id", id))
+
 	// Update in database
-	if err := h.repo.UpdatePost(id, &req); err != nil {
-		if err.Error() == "post not found" {
-			http.Error(w, "Post not found", http.StatusNotFound)
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	actor := requestActor(r, h.trustProxy)
+	updated, err := h.repo.UpdatePost(dbCtx, id, &req, actor)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Post not found")
 		} else {
-			log.Printf("Failed to update post: %v", err)
-			http.Error(w, "Failed to update post", http.StatusInternalServerError)
+			h.logger.ErrorContext(r.Context(), "failed to update post", slog.Int("post_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to update post")
 		}
 		return
 	}
 
-	// Invalidate cache
-	if err := h.cache.InvalidatePost(id); err != nil {
-		log.Printf("Failed to invalidate cache: %v", err)
+	// Invalidate cache. The update is committed, so finish this even if the
+	// client has gone away; otherwise readers would see the stale post.
+	cacheCtx, cancelCache := withTimeout(context.WithoutCancel(r.Context()), h.timeouts.Cache)
+	defer cancelCache()
+	if err := h.cache.InvalidatePost(cacheCtx, id); err != nil {
+		h.logger.WarnContext(cacheCtx, "failed to invalidate cached post", slog.Int("post_id", id), slog.Any("error", err))
+	} else {
+		h.logger.DebugContext(cacheCtx, "cached post invalidated", slog.Int("post_id", id))
 	}
-	log.Printf("Cache invalidated for post %d", id)
 
 	// Update in Elasticsearch asynchronously
-	go func() {
-		// Get the updated post to index it
-		post, err := h.repo.GetPostByID(id)
-		if err != nil {
-			log.Printf("Failed to get post for indexing: %v", err)
-			return
-		}
-		if err := h.search.IndexPost(post); err != nil {
-			log.Printf("Failed to update post in Elasticsearch: %v", err)
-		}
-	}()
+	h.reindex(r.Context(), id)
+	h.publishEvent(r, models.EventPostUpdated, id, actor, updated)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Post updated successfully",
+	json.NewEncoder(w).Encode(models.MessageResponse{
+		Message: "Post updated successfully",
 	})
 }
 
+// DeletePost handles DELETE /posts/:id. Comments, reactions and stats go
+// with the post; its activity log stays.
+func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int("post.id", id))
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	actor := requestActor(r, h.trustProxy)
+	deleted, err := h.repo.DeletePost(dbCtx, id, actor)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Post not found")
+		} else {
+			h.logger.ErrorContext(r.Context(), "failed to delete post", slog.Int("post_id", id), slog.Any("error", err))
+			writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to delete post")
+		}
+		return
+	}
+
+	// The deletion is committed, so finish this even if the client has gone away
+	cacheCtx, cancelCache := withTimeout(context.WithoutCancel(r.Context()), h.timeouts.Cache)
+	defer cancelCache()
+	if err := h.cache.InvalidatePost(cacheCtx, id); err != nil {
+		h.logger.WarnContext(cacheCtx, "failed to invalidate cached post", slog.Int("post_id", id), slog.Any("error", err))
+	}
+
+	// The indexer removes posts that no longer exist from Elasticsearch
+	h.reindex(r.Context(), id)
+	h.publishEvent(r, models.EventPostDeleted, id, actor, deleted)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // SearchByTag handles GET /posts/search-by-tag?tag=<tag_name>
 func (h *PostHandler) SearchByTag(w http.ResponseWriter, r *http.Request) {
 	tag := r.URL.Query().Get("tag")
 	if tag == "" {
-		http.Error(w, "Tag parameter is required", http.StatusBadRequest)
+		writeError(r.Context(), w, http.StatusBadRequest, "Tag parameter is required")
 		return
 	}
 
-	posts, err := h.repo.SearchPostsByTag(tag)
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	posts, err := h.repo.SearchPostsByTag(dbCtx, tag)
 	if err != nil {
-		log.Printf("Failed to search posts by tag: %v", err)
-		http.Error(w, "Failed to search posts", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to search posts by tag", slog.String("tag", tag), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to search posts")
 		return
 	}
 
@@ -196,18 +353,45 @@ func (h *PostHandler) SearchByTag(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// SearchPosts handles GET /posts/search?q=<query>
+// SearchPosts handles GET /posts/search?q=<query>&lang=<en|vi>&language=<en|vi>.
+// q uses the syntax of search.Query, lang is the language of the query and
+// language filters the posts.
 func (h *PostHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "Query parameter is required", http.StatusBadRequest)
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(r.Context(), w, http.StatusBadRequest, "Query parameter is required")
+		return
+	}
+	query, err := search.ParseQuery(q)
+	if err != nil {
+		var syntaxErr *search.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid search query")
+			return
+		}
+		writeErrorResponse(r.Context(), w, http.StatusBadRequest, ErrorResponse{
+			Error:    "Invalid search query: " + syntaxErr.Message,
+			Position: syntaxErr.Position,
+		})
 		return
 	}
+	for _, param := range []string{"lang", "language"} {
+		if value := r.URL.Query().Get(param); value != "" && !language.Valid(value) {
+			writeError(r.Context(), w, http.StatusBadRequest, param+" must be one of "+strings.Join(language.Supported, ", "))
+			return
+		}
+	}
+	opts := search.SearchOptions{
+		Lang:     r.URL.Query().Get("lang"),
+		Language: r.URL.Query().Get("language"),
+	}
 
-	posts, err := h.search.SearchPosts(query)
+	searchCtx, cancel := withTimeout(r.Context(), h.timeouts.Search)
+	defer cancel()
+	posts, err := h.search.SearchPosts(searchCtx, query, opts)
 	if err != nil {
-		log.Printf("Failed to search posts: %v", err)
-		http.Error(w, "Failed to search posts", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "failed to search posts", slog.Any("error", err))
+		writeOperationError(searchCtx, w, err, http.StatusInternalServerError, "Failed to search posts")
 		return
 	}
 
@@ -222,3 +406,57 @@ func (h *PostHandler) SearchPosts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// reindex queues a changed post for indexing, unless the change listener
+// does it: it is notified of every change, from any replica
+func (h *PostHandler) reindex(ctx context.Context, postID int) {
+	if h.indexer == nil {
+		return
+	}
+	h.indexer.Enqueue(ctx, postID)
+}
+
+// publishEvent streams a committed change to /events clients. The change has
+// succeeded either way, so a failure is only logged.
+func (h *PostHandler) publishEvent(r *http.Request, eventType string, postID int, actor models.Actor, post *models.PostSnapshot) {
+	if h.events == nil {
+		return
+	}
+	ctx, cancel := withTimeout(context.WithoutCancel(r.Context()), h.timeouts.Cache)
+	defer cancel()
+	_, err := h.events.Publish(ctx, models.PostEvent{
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		PostID:     postID,
+		Actor:      actor.Name,
+		Post:       post,
+	})
+	if err != nil {
+		h.logger.WarnContext(ctx, "failed to publish post event", slog.Int("post_id", postID), slog.Any("error", err))
+	}
+}
+
+// postLanguage returns the language a post is stored with: the one requested
+// or, when none is, the one detected from its text. An unsupported language
+// is answered with 422.
+func postLanguage(w http.ResponseWriter, r *http.Request, requested, title, content string) (string, bool) {
+	if requested == "" {
+		return language.Detect(title + " " + content), true
+	}
+	if !language.Valid(requested) {
+		writeValidationError(r.Context(), w, validation.Errors{{
+			Field:   "language",
+			Code:    validation.CodeInvalidValue,
+			Message: "must be one of " + strings.Join(language.Supported, ", "),
+		}})
+		return "", false
+	}
+	return requested, true
+}
+
+// relatedPosts looks up related posts within the search timeout
+func (h *PostHandler) relatedPosts(r *http.Request, post *models.Post) []models.Related {
+	ctx, cancel := withTimeout(r.Context(), h.timeouts.Search)
+	defer cancel()
+	return h.search.GetRelatedPosts(ctx, post.ID, post.Tags)
+}
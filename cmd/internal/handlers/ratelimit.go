@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+)
+
+// WithRateLimit applies the token bucket configured for each route, keyed by
+// client (API key, user or IP). Allowed responses carry RateLimit-* headers;
+// rejected ones get 429 with Retry-After. If the limiter fails the request is
+// let through.
+func WithRateLimit(limiter ratelimit.Limiter, cfg ratelimit.Config, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			limit, ok := cfg.LimitFor(r.Method, route)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route = ratelimit.NormalizeRoute(route)
+			client := ratelimit.ClientKey(r, cfg.TrustProxy)
+			if principal := auth.PrincipalFrom(r.Context()); principal != nil {
+				client = "key:" + strconv.Itoa(principal.KeyID)
+			}
+			key := r.Method + " " + route + ":" + client
+			result, err := limiter.Allow(r.Context(), key, limit)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "rate limit check failed", slog.Any("error", err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result.SetHeaders(w.Header())
+			if !result.Allowed {
+				metrics.RateLimited.WithLabelValues(r.Method, route).Inc()
+				writeError(r.Context(), w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeTemplate returns the mux path template of the matched route, "" when
+// none matched
+func routeTemplate(r *http.Request) string {
+	route := ""
+	if current := mux.CurrentRoute(r); current != nil {
+		route, _ = current.GetPathTemplate()
+	}
+	return route
+}
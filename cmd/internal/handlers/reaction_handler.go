@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/indexing"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+type ReactionHandler struct {
+	reactions  *repository.ReactionRepository
+	cache      cache.Cache
+	indexer    *indexing.Queue
+	trustProxy bool // take reacting users from the X-User-ID of the proxy
+	timeouts   Timeouts
+	logger     *slog.Logger
+}
+
+func NewReactionHandler(reactions *repository.ReactionRepository, cache cache.Cache, indexer *indexing.Queue, trustProxy bool, timeouts Timeouts, logger *slog.Logger) *ReactionHandler {
+	return &ReactionHandler{
+		reactions:  reactions,
+		cache:      cache,
+		indexer:    indexer,
+		trustProxy: trustProxy,
+		timeouts:   timeouts,
+		logger:     logger,
+	}
+}
+
+// AddReaction handles POST /posts/:id/reactions with {"type": "like"}.
+// Reacting twice with the same type is a no-op.
+func (h *ReactionHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	userID, ok := requireUser(w, r, h.trustProxy)
+	if !ok {
+		return
+	}
+
+	var req models.ReactionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !validReaction(req.Type) {
+		writeValidationError(r.Context(), w, validation.Errors{{
+			Field:   "type",
+			Code:    validation.CodeInvalidValue,
+			Message: "must be one of " + strings.Join(models.ReactionTypes, ", "),
+		}})
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	added, err := h.reactions.AddReaction(dbCtx, postID, userID, req.Type)
+	if err != nil {
+		if errors.Is(err, repository.ErrPostNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Post not found")
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to add reaction", slog.Int("post_id", postID), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to add reaction")
+		return
+	}
+	if added {
+		h.reactionChanged(r.Context(), postID, req.Type, 1)
+	}
+
+	counts, err := h.reactions.CountReactions(dbCtx, postID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to count reactions", slog.Int("post_id", postID), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to count reactions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.PostReactions{PostID: postID, Reactions: counts})
+}
+
+// RemoveReaction handles DELETE /posts/:id/reactions?type=like. Removing a
+// reaction the user never left is a no-op.
+func (h *ReactionHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	userID, ok := requireUser(w, r, h.trustProxy)
+	if !ok {
+		return
+	}
+	reaction := r.URL.Query().Get("type")
+	if !validReaction(reaction) {
+		writeError(r.Context(), w, http.StatusBadRequest, "type must be one of "+strings.Join(models.ReactionTypes, ", "))
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	removed, err := h.reactions.RemoveReaction(dbCtx, postID, userID, reaction)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to remove reaction", slog.Int("post_id", postID), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to remove reaction")
+		return
+	}
+	if removed {
+		h.reactionChanged(r.Context(), postID, reaction, -1)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reactionChanged updates the cached counter and reindexes the post so search
+// sees the new total. The change is committed, so this runs even if the
+// client has gone away.
+func (h *ReactionHandler) reactionChanged(ctx context.Context, postID int, reaction string, delta int) {
+	cacheCtx, cancel := withTimeout(context.WithoutCancel(ctx), h.timeouts.Cache)
+	defer cancel()
+	if err := h.cache.IncrReaction(cacheCtx, postID, reaction, delta); err != nil {
+		// Drop the post rather than serve a wrong count until it expires
+		h.logger.WarnContext(cacheCtx, "failed to update cached reaction count", slog.Int("post_id", postID), slog.Any("error", err))
+		if err := h.cache.InvalidatePost(cacheCtx, postID); err != nil {
+			h.logger.WarnContext(cacheCtx, "failed to invalidate cached post", slog.Int("post_id", postID), slog.Any("error", err))
+		}
+	}
+
+	h.indexer.Enqueue(ctx, postID)
+}
+
+func validReaction(reaction string) bool {
+	for _, t := range models.ReactionTypes {
+		if t == reaction {
+			return true
+		}
+	}
+	return false
+}
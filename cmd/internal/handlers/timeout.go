@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// StatusClientClosedRequest is the non-standard status (popularised by nginx)
+// recorded when the client goes away before the response is ready
+const StatusClientClosedRequest = 499
+
+// Timeouts bounds each kind of downstream call made while serving a request
+type Timeouts struct {
+	Database time.Duration
+	Cache    time.Duration
+	Search   time.Duration
+}
+
+// WithRequestTimeout gives every request a deadline. Handlers see it through
+// r.Context() and answer 504 when it expires. Streaming routes, given as
+// "GET /events", run until the client goes away instead.
+func WithRequestTimeout(timeout time.Duration, streaming ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(streaming) > 0 {
+				if current := mux.CurrentRoute(r); current != nil {
+					route, _ := current.GetPathTemplate()
+					if slices.Contains(streaming, r.Method+" "+route) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withTimeout derives a context for one downstream operation
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextErrorStatus maps a failed operation to 504 or 499 when it failed
+// because its deadline passed or the client disconnected
+func contextErrorStatus(ctx context.Context, err error) (int, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, true
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return StatusClientClosedRequest, true
+	}
+	return 0, false
+}
+
+// writeOperationError answers 504 or 499 when err comes from a deadline or
+// a cancellation and with the given status and message otherwise
+func writeOperationError(ctx context.Context, w http.ResponseWriter, err error, status int, message string) {
+	if ctxStatus, ok := contextErrorStatus(ctx, err); ok {
+		status = ctxStatus
+		message = "Request timed out"
+		if ctxStatus == StatusClientClosedRequest {
+			message = "Client closed request"
+		}
+	}
+	writeError(ctx, w, status, message)
+}
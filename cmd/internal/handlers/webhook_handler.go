@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+type WebhookHandler struct {
+	repo     *repository.WebhookRepository
+	timeouts Timeouts
+	logger   *slog.Logger
+}
+
+func NewWebhookHandler(repo *repository.WebhookRepository, timeouts Timeouts, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		repo:     repo,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// CreateWebhook handles POST /webhooks with the URL, events and signing secret
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	var errs validation.Errors
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, validation.FieldError{Field: "url", Code: validation.CodeInvalidValue, Message: "must be an absolute http or https URL"})
+	}
+	for i, event := range req.Events {
+		if !validWebhookEvent(event) {
+			errs = append(errs, validation.FieldError{
+				Field:   fmt.Sprintf("events[%d]", i),
+				Code:    validation.CodeInvalidValue,
+				Message: "must be one of " + strings.Join(models.WebhookEventTypes, ", "),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		writeValidationError(r.Context(), w, errs)
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	webhook, err := h.repo.CreateWebhook(dbCtx, &req)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to create webhook", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "webhook created",
+		slog.Int("webhook_id", webhook.ID),
+		slog.Any("events", webhook.Events),
+		slog.String("created_by", principalName(r)),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	webhooks, err := h.repo.ListWebhooks(dbCtx)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to list webhooks", slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id. Pending deliveries are dropped.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	if err := h.repo.DeleteWebhook(dbCtx, id); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to delete webhook", slog.Int("webhook_id", id), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "webhook deleted", slog.Int("webhook_id", id), slog.String("deleted_by", principalName(r)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/:id/deliveries?status=failed&page=1
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !validDeliveryStatus(status) {
+		writeError(r.Context(), w, http.StatusBadRequest, "status must be one of "+strings.Join(models.DeliveryStatuses, ", "))
+		return
+	}
+	page, perPage, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	deliveries, total, err := h.repo.ListDeliveries(dbCtx, id, status, page, perPage)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to list webhook deliveries", slog.Int("webhook_id", id), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to list deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WebhookDeliveryPage{
+		Deliveries: deliveries,
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+	})
+}
+
+// Redeliver handles POST /webhook-deliveries/:id/redeliver. The event is
+// queued again as a new delivery, sent on the next poll.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(r.Context(), w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	dbCtx, cancel := withTimeout(r.Context(), h.timeouts.Database)
+	defer cancel()
+	delivery, err := h.repo.Redeliver(dbCtx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrDeliveryNotFound) {
+			writeError(r.Context(), w, http.StatusNotFound, "Delivery not found")
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to redeliver webhook", slog.Int("delivery_id", id), slog.Any("error", err))
+		writeOperationError(dbCtx, w, err, http.StatusInternalServerError, "Failed to redeliver")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range models.WebhookEventTypes {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func validDeliveryStatus(status string) bool {
+	for _, s := range models.DeliveryStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/search"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/indexing")
+
+// Config controls the background indexing workers
+type Config struct {
+	Workers   int
+	QueueSize int
+	// RetryInterval is how often jobs persisted in pending_index_jobs are replayed
+	RetryInterval time.Duration
+	// RetryBatchSize is the number of persisted jobs replayed at a time
+	RetryBatchSize int
+	// JobTimeout bounds loading and indexing a single post
+	JobTimeout time.Duration
+}
+
+// DefaultConfig returns the indexing settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Workers:        4,
+		QueueSize:      1000,
+		RetryInterval:  time.Minute,
+		RetryBatchSize: 100,
+		JobTimeout:     10 * time.Second,
+	}
+}
+
+// job is one post to index, linked to the span of the request that queued it
+type job struct {
+	postID int
+	origin trace.SpanContext
+}
+
+// Queue indexes posts in Elasticsearch in the background. Jobs that cannot be
+// indexed, either because Elasticsearch failed, the queue was full or the
+// service shut down first, are persisted in Postgres and replayed later.
+type Queue struct {
+	repo   *repository.PostRepository
+	search *search.ElasticSearch
+	cfg    Config
+	logger *slog.Logger
+
+	jobs chan job
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	// queued holds the posts waiting for a worker; queueing one again is a
+	// no-op, since the worker loads the post as it is when it gets to it
+	queued   map[int]bool
+	inFlight map[int]int
+}
+
+func NewQueue(repo *repository.PostRepository, search *search.ElasticSearch, cfg Config, logger *slog.Logger) *Queue {
+	return &Queue{
+		repo:     repo,
+		search:   search,
+		cfg:      cfg,
+		logger:   logger,
+		jobs:     make(chan job, cfg.QueueSize),
+		stop:     make(chan struct{}),
+		queued:   make(map[int]bool),
+		inFlight: make(map[int]int),
+	}
+}
+
+// Start launches the workers and the replay loop for persisted jobs
+func (q *Queue) Start() {
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	q.wg.Add(1)
+	go q.replayLoop()
+}
+
+// Len returns the number of jobs waiting for a worker
+func (q *Queue) Len() int {
+	return len(q.jobs)
+}
+
+// Enqueue schedules a post for indexing without blocking the caller, unless
+// it is already waiting for a worker. The indexing span is linked to the span
+// in ctx rather than parented by it, since it usually outlives the request.
+func (q *Queue) Enqueue(ctx context.Context, postID int) {
+	j := job{postID: postID, origin: trace.SpanContextFromContext(ctx)}
+
+	q.mu.Lock()
+	if !q.closed {
+		if q.queued[postID] {
+			q.mu.Unlock()
+			metrics.IndexJobs.WithLabelValues("deduplicated").Inc()
+			return
+		}
+		select {
+		case q.jobs <- j:
+			q.queued[postID] = true
+			q.mu.Unlock()
+			return
+		default:
+		}
+	}
+	q.mu.Unlock()
+
+	q.logger.WarnContext(ctx, "indexing queue unavailable, persisting job", slog.Int("post_id", postID))
+	q.persist(context.WithoutCancel(ctx), []int{postID})
+}
+
+// Shutdown stops accepting jobs and waits for queued ones to be indexed.
+// Whatever is still queued or in flight when ctx expires is persisted.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+		close(q.stop)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	var pending []int
+	for j := range q.jobs {
+		pending = append(pending, j.postID)
+	}
+	q.mu.Lock()
+	for postID := range q.inFlight {
+		pending = append(pending, postID)
+	}
+	q.mu.Unlock()
+
+	q.logger.Warn("indexing did not drain before shutdown, persisting jobs", slog.Int("jobs", len(pending)))
+	// ctx has expired, so persist with a fresh deadline
+	persistCtx, cancel := context.WithTimeout(context.Background(), q.cfg.JobTimeout)
+	defer cancel()
+	if err := q.repo.SavePendingIndexJobs(persistCtx, pending); err != nil {
+		return err
+	}
+	metrics.IndexJobs.WithLabelValues("persisted").Add(float64(len(pending)))
+	return ctx.Err()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	for j := range q.jobs {
+		q.track(j.postID, 1)
+		if err := q.index(j); err != nil {
+			q.logger.Error("failed to index post", slog.Int("post_id", j.postID), slog.Any("error", err))
+			metrics.IndexJobs.WithLabelValues("failed").Inc()
+			q.persist(context.Background(), []int{j.postID})
+		} else {
+			metrics.IndexJobs.WithLabelValues("indexed").Inc()
+		}
+		q.track(j.postID, -1)
+	}
+}
+
+func (q *Queue) index(j job) (err error) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(attribute.Int("post.id", j.postID))}
+	if j.origin.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: j.origin}))
+	}
+	ctx, span := tracer.Start(context.Background(), "indexing.IndexPost", opts...)
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, q.cfg.JobTimeout)
+	defer cancel()
+
+	post, err := q.repo.GetPostByID(ctx, j.postID)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		// Deleted since it was queued
+		return q.search.DeletePost(ctx, j.postID)
+	}
+	if err != nil {
+		return err
+	}
+	return q.search.IndexPost(ctx, post)
+}
+
+// replayLoop periodically moves persisted jobs back onto the queue
+func (q *Queue) replayLoop() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		q.replay()
+
+		select {
+		case <-ticker.C:
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+func (q *Queue) replay() {
+	ctx, span := tracer.Start(context.Background(), "indexing.Replay")
+	var err error
+	defer tracing.End(span, &err)
+
+	ctx, cancel := context.WithTimeout(ctx, q.cfg.JobTimeout)
+	defer cancel()
+
+	postIDs, err := q.repo.TakePendingIndexJobs(ctx, q.cfg.RetryBatchSize)
+	if err != nil {
+		q.logger.ErrorContext(ctx, "failed to load pending index jobs", slog.Any("error", err))
+		return
+	}
+	if len(postIDs) > 0 {
+		q.logger.InfoContext(ctx, "replaying pending index jobs", slog.Int("jobs", len(postIDs)))
+	}
+	for _, postID := range postIDs {
+		q.Enqueue(ctx, postID)
+	}
+}
+
+func (q *Queue) track(postID, delta int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if delta > 0 {
+		// A change made from now on needs another job
+		delete(q.queued, postID)
+	}
+	q.inFlight[postID] += delta
+	if q.inFlight[postID] <= 0 {
+		delete(q.inFlight, postID)
+	}
+}
+
+func (q *Queue) persist(ctx context.Context, postIDs []int) {
+	ctx, cancel := context.WithTimeout(ctx, q.cfg.JobTimeout)
+	defer cancel()
+
+	if err := q.repo.SavePendingIndexJobs(ctx, postIDs); err != nil {
+		q.logger.ErrorContext(ctx, "failed to persist index jobs", slog.Any("post_ids", postIDs), slog.Any("error", err))
+		return
+	}
+	metrics.IndexJobs.WithLabelValues("persisted").Add(float64(len(postIDs)))
+}
@@ -0,0 +1,30 @@
+package indexing
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestEnqueueDeduplicatesWaitingPosts(t *testing.T) {
+	ctx := context.Background()
+	// Without Start no worker runs, so the test takes jobs itself
+	q := NewQueue(nil, nil, DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	q.Enqueue(ctx, 1)
+	q.Enqueue(ctx, 2)
+	q.Enqueue(ctx, 1)
+	if q.Len() != 2 {
+		t.Fatalf("Len = %d after queueing post 1 twice, want 2", q.Len())
+	}
+
+	// Once a worker has taken the job, a new change needs a new one
+	j := <-q.jobs
+	q.track(j.postID, 1)
+	q.Enqueue(ctx, j.postID)
+	q.track(j.postID, -1)
+	if q.Len() != 2 {
+		t.Errorf("Len = %d after requeueing a post being indexed, want 2", q.Len())
+	}
+}
@@ -0,0 +1,112 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/search"
+)
+
+// rebuildBatchSize is the number of posts read and bulk indexed at a time
+const rebuildBatchSize = 500
+
+// rebuildOverlap rescans changes made this long before the rebuild started,
+// for transactions that committed after it began
+const rebuildOverlap = time.Minute
+
+// RebuildResult describes a finished rebuild
+type RebuildResult struct {
+	Index string
+	// Previous are the indices the alias pointed at before
+	Previous []string
+	Posts    int
+	// CaughtUp is the number of posts changed during the build and written again
+	CaughtUp int
+}
+
+// Rebuild migrates the search index to the current mapping without
+// downtime: every post is copied from Postgres into a new index, the alias
+// is swapped to it in one step, and posts changed meanwhile, which the API
+// wrote to the old index, are written again. The old index is kept.
+func Rebuild(ctx context.Context, repo *repository.PostRepository, changes *repository.ChangeRepository, es *search.ElasticSearch, logger *slog.Logger) (*RebuildResult, error) {
+	started, err := changes.Now(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := es.NewIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &RebuildResult{Index: index}
+
+	afterID := 0
+	for {
+		posts, err := repo.ListPostsAfter(ctx, afterID, rebuildBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := es.BulkIndex(ctx, index, posts); err != nil {
+			return nil, fmt.Errorf("failed to fill %s: %w", index, err)
+		}
+		result.Posts += len(posts)
+		if len(posts) < rebuildBatchSize {
+			break
+		}
+		afterID = posts[len(posts)-1].ID
+		logger.InfoContext(ctx, "rebuilding search index", slog.String("index", index), slog.Int("posts", result.Posts))
+	}
+
+	if result.Previous, err = es.SwapAlias(ctx, index); err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "search alias swapped", slog.String("index", index), slog.Any("previous", result.Previous))
+
+	// Writes now reach the new index; replay those that went to the old one
+	cursor := repository.PostChange{At: started.Add(-rebuildOverlap)}
+	for {
+		batch, err := changes.ChangesAfter(ctx, cursor, rebuildBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		ids := make([]int, len(batch))
+		for i, change := range batch {
+			ids[i] = change.PostID
+		}
+		posts, err := repo.GetPostsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		if err := es.BulkIndex(ctx, search.Alias, posts); err != nil {
+			return nil, err
+		}
+		found := make(map[int]bool, len(posts))
+		for _, post := range posts {
+			found[post.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				if err := es.DeletePost(ctx, id); err != nil {
+					return nil, err
+				}
+			}
+		}
+		result.CaughtUp += len(batch)
+		cursor = batch[len(batch)-1]
+		if len(batch) < rebuildBatchSize {
+			break
+		}
+	}
+
+	logger.InfoContext(ctx, "search index rebuilt",
+		slog.String("index", index),
+		slog.Int("posts", result.Posts),
+		slog.Int("caught_up", result.CaughtUp),
+	)
+	return result, nil
+}
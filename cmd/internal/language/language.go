@@ -0,0 +1,59 @@
+// Package language names the languages posts are written in and guesses the
+// language of a text
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages with their own analyzers in the search index
+const (
+	English    = "en"
+	Vietnamese = "vi"
+)
+
+// Default is assumed for texts without any sign of another language
+const Default = English
+
+// Supported lists every language a post may be tagged with
+var Supported = []string{English, Vietnamese}
+
+// vietnameseThreshold is the share of letters that must be Vietnamese-only
+// for a text to count as Vietnamese. Vietnamese prose marks most syllables,
+// while an English post quoting a place name stays well below it.
+const vietnameseThreshold = 0.02
+
+// vietnameseLetters are letters used by Vietnamese and not by the other Latin
+// alphabets our authors write in. Shared letters such as é or à are left out.
+const vietnameseLetters = "ăắằẳẵặâấầẩẫậđêếềểễệôốồổỗộơớờởỡợưứừửữự" +
+	"ạảẹẻẽịỉĩọỏụủũỳỵỷỹ"
+
+// Valid reports whether lang is one of Supported
+func Valid(lang string) bool {
+	for _, supported := range Supported {
+		if lang == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect guesses the language of text: Vietnamese when enough of its letters
+// are Vietnamese-only, Default otherwise
+func Detect(text string) string {
+	letters, marked := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if strings.ContainsRune(vietnameseLetters, unicode.ToLower(r)) {
+			marked++
+		}
+	}
+	if letters > 0 && float64(marked)/float64(letters) >= vietnameseThreshold {
+		return Vietnamese
+	}
+	return Default
+}
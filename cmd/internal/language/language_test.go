@@ -0,0 +1,34 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: Default},
+		{text: "1234 !?", want: Default},
+		{text: "Getting started with Go modules", want: English},
+		{text: "Xin chào, hôm nay trời đẹp quá", want: Vietnamese},
+		{text: "HÀ NỘI MÙA THU", want: Vietnamese},
+		{text: "Một ngày ở Sài Gòn", want: Vietnamese},
+		// Letters shared with French do not count
+		{text: "Café crème à la carte", want: English},
+		// One place name in a long English text stays below the threshold
+		{text: "We spent a week travelling through the north of the country, hiking every morning, eating street food every evening and finally flying home from Đà Nẵng after many long and happy days", want: English},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for lang, want := range map[string]bool{"en": true, "vi": true, "": false, "EN": false, "fr": false} {
+		if got := Valid(lang); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
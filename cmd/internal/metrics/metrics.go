@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "blog_api"
+
+// Registry holds every collector exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts requests by method, mux route template and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by method, mux route template and status
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// CacheOperations counts cache calls by backend, operation and result
+	// (hit, miss or error for reads; ok or error for writes)
+	CacheOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_operations_total",
+		Help:      "Cache operations by backend, operation and result.",
+	}, []string{"backend", "operation", "result"})
+
+	// SearchDuration observes Elasticsearch request latency by operation
+	SearchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "elasticsearch_request_duration_seconds",
+		Help:      "Elasticsearch request latency by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// SearchFailures counts failed Elasticsearch requests by operation
+	SearchFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "elasticsearch_request_failures_total",
+		Help:      "Failed Elasticsearch requests by operation.",
+	}, []string{"operation"})
+
+	// RateLimited counts requests rejected by the rate limiter
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by method and route template.",
+	}, []string{"method", "route"})
+
+	// IndexJobs counts background indexing jobs by result
+	// (indexed, failed, persisted or deduplicated)
+	IndexJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "indexing_jobs_total",
+		Help:      "Background indexing jobs by result.",
+	}, []string{"result"})
+
+	// PostViews counts post views by result (counted or bot)
+	PostViews = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "post_views_total",
+		Help:      "Post views by result; bot views are not counted in post stats.",
+	}, []string{"result"})
+
+	// ActivityPruned counts activity log entries past retention by result
+	// (deleted or archived)
+	ActivityPruned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "activity_logs_pruned_total",
+		Help:      "Activity log entries removed by the retention job, by result.",
+	}, []string{"result"})
+
+	// WebhookDeliveries counts webhook delivery attempts by result
+	// (succeeded, retried or failed for good)
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result.",
+	}, []string{"result"})
+
+	// PostChanges counts post changes picked up from the database by source
+	// (notify or catch_up)
+	PostChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "post_changes_total",
+		Help:      "Post changes captured from PostgreSQL by source.",
+	}, []string{"source"})
+
+	// EventStreamDropped counts /events clients disconnected for falling behind
+	EventStreamDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_stream_dropped_total",
+		Help:      "Event stream clients disconnected because their buffer was full.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		CacheOperations,
+		SearchDuration,
+		SearchFailures,
+		IndexJobs,
+		PostViews,
+		ActivityPruned,
+		WebhookDeliveries,
+		PostChanges,
+		EventStreamDropped,
+		RateLimited,
+	)
+}
+
+// RegisterDB exports the database/sql connection pool stats
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterIndexQueue exports the number of jobs waiting in the indexing queue
+func RegisterIndexQueue(depth func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "indexing_queue_depth",
+		Help:      "Jobs waiting in the background indexing queue.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterEventStream exports the number of clients connected to /events
+func RegisterEventStream(clients func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "event_stream_clients",
+		Help:      "Clients connected to the post event stream on this replica.",
+	}, func() float64 {
+		return float64(clients())
+	}))
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
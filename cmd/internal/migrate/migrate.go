@@ -0,0 +1,396 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// lockID is the advisory lock held while migrating, so replicas starting
+// together apply each migration once. The value is arbitrary.
+const lockID = 4_731_020_250
+
+// ErrChecksumMismatch is returned when an applied migration's file was changed
+var ErrChecksumMismatch = errors.New("migration changed after it was applied")
+
+// States reported by Status
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	// StateModified is an applied migration whose file no longer matches
+	StateModified = "modified"
+	// StateUnknown is an applied migration this binary has no file for, e.g.
+	// one added by a newer release
+	StateUnknown = "unknown"
+)
+
+var fileName = regexp.MustCompile(`^([0-9]+)_([A-Za-z0-9_]+?)(\.down)?\.sql$`)
+
+// Migration is one NNN_name.sql file and its optional NNN_name.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of the up file, recorded when it is applied
+	Checksum string
+}
+
+// Status describes one migration, applied or not
+type Status struct {
+	Version   int
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations, recording them in
+// schema_migrations. Each migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New reads the migrations in fsys, in version order
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, m.Name, match[2])
+		}
+		if match[3] != "" {
+			m.Down = string(data)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		m.Up = string(data)
+		m.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d (%s) has a down file but no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// Latest returns the highest version known to this binary, 0 when there is none
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every known and applied migration in version order
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := ensureTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+	done, err := appliedMigrations(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.statuses(done), nil
+}
+
+// statuses compares the known migrations with the applied ones in done,
+// which it consumes
+func (m *Migrator) statuses(done map[int]applied) []Status {
+	var statuses []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name, State: StatePending}
+		if a, ok := done[mig.Version]; ok {
+			s.State = StateApplied
+			if a.checksum != mig.Checksum {
+				s.State = StateModified
+			}
+			s.AppliedAt = &a.appliedAt
+			delete(done, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range done {
+		statuses = append(statuses, Status{Version: version, Name: a.name, State: StateUnknown, AppliedAt: &a.appliedAt})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int { return a.Version - b.Version })
+	return statuses
+}
+
+// Up applies every pending migration. Applied migrations this binary does
+// not know are left alone, so an older release can start during a rollout.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn, done map[int]applied) error {
+		return m.applyUpTo(ctx, conn, done, m.Latest())
+	})
+}
+
+// Down rolls back the latest applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn, done map[int]applied) error {
+		latest := 0
+		for version := range done {
+			latest = max(latest, version)
+		}
+		if latest == 0 {
+			m.logger.InfoContext(ctx, "no migrations to roll back")
+			return nil
+		}
+		return m.rollBack(ctx, conn, latest)
+	})
+}
+
+// To applies or rolls back migrations until version is the latest applied;
+// 0 rolls back every migration
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.locked(ctx, func(conn *sql.Conn, done map[int]applied) error {
+		if err := m.applyUpTo(ctx, conn, done, version); err != nil {
+			return err
+		}
+
+		var above []int
+		for v := range done {
+			if v > version {
+				above = append(above, v)
+			}
+		}
+		slices.Sort(above)
+		for i := len(above) - 1; i >= 0; i-- {
+			if err := m.rollBack(ctx, conn, above[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// locked runs fn on one connection holding the migration lock, with the
+// applied migrations after their checksums were verified
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, done map[int]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	if !acquired {
+		m.logger.InfoContext(ctx, "waiting for another process to finish migrating")
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			m.logger.WarnContext(ctx, "failed to release the migration lock", slog.Any("error", err))
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(done); err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// verify checks that no applied migration's file was changed since
+func (m *Migrator) verify(done map[int]applied) error {
+	for _, mig := range m.migrations {
+		if a, ok := done[mig.Version]; ok && a.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %03d_%s.sql has checksum %s, %s was applied", ErrChecksumMismatch, mig.Version, mig.Name, mig.Checksum, a.checksum)
+		}
+	}
+	return nil
+}
+
+// applyUpTo applies the pending migrations up to version, in order
+func (m *Migrator) applyUpTo(ctx context.Context, conn *sql.Conn, done map[int]applied, version int) error {
+	pending := 0
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, ok := done[mig.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, conn, mig); err != nil {
+			return err
+		}
+		pending++
+	}
+	if pending == 0 {
+		m.logger.InfoContext(ctx, "database schema is up to date")
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	start := time.Now()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		mig.Version, mig.Name, mig.Checksum,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	m.logger.InfoContext(ctx, "migration applied",
+		slog.Int("version", mig.Version),
+		slog.String("name", mig.Name),
+		slog.Duration("elapsed", time.Since(start)),
+	)
+	return nil
+}
+
+func (m *Migrator) rollBack(ctx context.Context, conn *sql.Conn, version int) error {
+	mig := m.find(version)
+	if mig == nil {
+		return fmt.Errorf("cannot roll back migration %d: this binary has no file for it", version)
+	}
+	if mig.Down == "" {
+		return fmt.Errorf("cannot roll back migration %03d_%s: it has no down file", mig.Version, mig.Name)
+	}
+
+	start := time.Now()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+		return fmt.Errorf("failed to roll back migration %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+		return fmt.Errorf("failed to record rollback of %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	m.logger.InfoContext(ctx, "migration rolled back",
+		slog.Int("version", mig.Version),
+		slog.String("name", mig.Name),
+		slog.Duration("elapsed", time.Since(start)),
+	)
+	return nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// execer is satisfied by *sql.DB and *sql.Conn
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func ensureTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, db execer) (map[int]applied, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int]applied)
+	for rows.Next() {
+		var version int
+		var a applied
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
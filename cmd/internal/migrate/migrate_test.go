@@ -0,0 +1,136 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.sql":         {Data: []byte("CREATE INDEX i ON t(c);")},
+		"002_create_table.sql":      {Data: []byte("CREATE TABLE t (c INT);")},
+		"002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"001_init.sql":              {Data: []byte("abc")},
+		"README.md":                 {Data: []byte("not a migration")},
+		"003_notes.txt":             {Data: []byte("not a migration either")},
+	}
+	got, err := load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		version int
+		name    string
+		down    string
+	}{
+		{version: 1, name: "init"},
+		{version: 2, name: "create_table", down: "DROP TABLE t;"},
+		{version: 10, name: "add_index"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d migrations, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Version != w.version || got[i].Name != w.name || got[i].Down != w.down {
+			t.Errorf("migration %d = %d %s (down %q), want %d %s (down %q)", i, got[i].Version, got[i].Name, got[i].Down, w.version, w.name, w.down)
+		}
+	}
+	// SHA-256 of "abc"
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got[0].Checksum != want {
+		t.Errorf("checksum = %s, want %s", got[0].Checksum, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "names differ",
+			fsys: fstest.MapFS{
+				"001_init.sql":         {Data: []byte("up")},
+				"001_initial.down.sql": {Data: []byte("down")},
+			},
+			want: "has files named",
+		},
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{"001_init.down.sql": {Data: []byte("down")}},
+			want: "no up file",
+		},
+	}
+	for _, tt := range tests {
+		_, err := load(tt.fsys)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: load() error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range loaded {
+		if m.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %03d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+func TestStatusesAndVerify(t *testing.T) {
+	m := &Migrator{migrations: []Migration{
+		{Version: 1, Name: "init", Checksum: "a"},
+		{Version: 2, Name: "users", Checksum: "b"},
+		{Version: 3, Name: "posts", Checksum: "c"},
+	}}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	done := map[int]applied{
+		1: {name: "init", checksum: "a", appliedAt: at},
+		2: {name: "users", checksum: "edited", appliedAt: at},
+		7: {name: "from_a_newer_release", checksum: "x", appliedAt: at},
+	}
+
+	if err := m.verify(done); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("verify() = %v, want ErrChecksumMismatch", err)
+	}
+
+	want := []struct {
+		version int
+		state   string
+		applied bool
+	}{
+		{version: 1, state: StateApplied, applied: true},
+		{version: 2, state: StateModified, applied: true},
+		{version: 3, state: StatePending},
+		{version: 7, state: StateUnknown, applied: true},
+	}
+	got := m.statuses(done)
+	if len(got) != len(want) {
+		t.Fatalf("statuses() returned %d entries, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Version != w.version || got[i].State != w.state || (got[i].AppliedAt != nil) != w.applied {
+			t.Errorf("status %d = %d %s (applied %v), want %d %s (applied %v)", i, got[i].Version, got[i].State, got[i].AppliedAt != nil, w.version, w.state, w.applied)
+		}
+	}
+
+	if err := m.verify(map[int]applied{1: {checksum: "a"}, 7: {checksum: "x"}}); err != nil {
+		t.Errorf("verify() = %v with matching checksums", err)
+	}
+}
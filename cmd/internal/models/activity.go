@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// Actions recorded in the activity log. Posts are live as soon as they are
+// created and have no draft or published state, so there is no publish action.
+const (
+	ActionNewPost    = "new_post"
+	ActionUpdatePost = "update_post"
+	ActionChangeTags = "change_tags"
+	ActionDeletePost = "delete_post"
+)
+
+// ActivityActions lists every action in the activity log
+var ActivityActions = []string{ActionNewPost, ActionUpdatePost, ActionChangeTags, ActionDeletePost}
+
+// Actor identifies who made a change: the X-User-ID user of a trusted proxy,
+// else the API key name, and the client IP address
+type Actor struct {
+	Name string
+	IP   string
+}
+
+// PostSnapshot is the state of a post before or after a change
+type PostSnapshot struct {
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Tags     []string `json:"tags"`
+	Language string   `json:"language,omitempty"`
+	// ReactionCount is the total indexed to boost search results. It is only
+	// set when the copies of a post are compared.
+	ReactionCount int `json:"reaction_count,omitempty"`
+}
+
+// ActivityLog is one entry of the audit trail. Before is empty for new posts
+// and After for deleted ones.
+type ActivityLog struct {
+	ID       int           `json:"id"`
+	Action   string        `json:"action"`
+	PostID   int           `json:"post_id"`
+	Actor    string        `json:"actor,omitempty"`
+	ClientIP string        `json:"client_ip,omitempty"`
+	Before   *PostSnapshot `json:"before,omitempty"`
+	After    *PostSnapshot `json:"after,omitempty"`
+	LoggedAt time.Time     `json:"logged_at"`
+}
+
+// ActivityFilter selects activity log entries; zero fields match everything
+type ActivityFilter struct {
+	Action string
+	Actor  string
+	PostID int
+	Since  time.Time
+	Until  time.Time
+}
+
+// ActivityPage is one page of activity log entries, newest first
+type ActivityPage struct {
+	Entries []ActivityLog `json:"entries"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey represents a credential for a machine client. The secret itself is
+// never stored or returned after creation.
+type APIKey struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedFrom *int       `json:"rotated_from,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,unique"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// RotateAPIKeyRequest represents the optional request body for rotating an API key
+type RotateAPIKeyRequest struct {
+	// GracePeriod keeps the old key valid this long, e.g. "1h"; empty revokes it immediately
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// CreatedAPIKey is returned once when a key is created or rotated; Key is
+// the only time the secret is shown
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
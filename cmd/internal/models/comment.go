@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Comment moderation statuses
+const (
+	CommentPending  = "pending"
+	CommentApproved = "approved"
+	CommentRejected = "rejected"
+	CommentSpam     = "spam"
+)
+
+// CommentStatuses lists every moderation status
+var CommentStatuses = []string{CommentPending, CommentApproved, CommentRejected, CommentSpam}
+
+// Comment represents a comment on a post. Replies holds approved replies
+// when comments are listed as threads.
+type Comment struct {
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	ParentID  *int       `json:"parent_id,omitempty"`
+	AuthorID  string     `json:"author_id,omitempty"`
+	Body      string     `json:"body"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// Deleted comments are kept as placeholders without body or author
+	Deleted bool      `json:"deleted,omitempty"`
+	Replies []Comment `json:"replies,omitempty"`
+}
+
+// CreateCommentRequest represents the request body for commenting on a post
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=5000"`
+	// ParentID makes the comment a reply
+	ParentID *int `json:"parent_id,omitempty"`
+}
+
+// UpdateCommentRequest represents the request body for editing a comment
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=5000"`
+}
+
+// ModerateCommentRequest represents the request body for changing a comment's status
+type ModerateCommentRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// CommentPage is one page of comments
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Page     int       `json:"page"`
+	PerPage  int       `json:"per_page"`
+	// Total counts top-level comments for threads, all matches for the moderation queue
+	Total int `json:"total"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Kinds of drift between a post in Postgres and its copies
+const (
+	// DriftMissing is a post that is not indexed
+	DriftMissing = "missing"
+	// DriftStale is a copy whose title, content or tags differ from Postgres
+	DriftStale = "stale"
+	// DriftOrphan is a copy of a post that no longer exists
+	DriftOrphan = "orphan"
+)
+
+// PostDrift is one post whose search document or cached entry does not match
+// Postgres. Hashes are SHA-256 of the title, content and tags.
+type PostDrift struct {
+	PostID int    `json:"post_id"`
+	Hash   string `json:"hash,omitempty"`
+	// Search is missing, stale or orphan; empty when the document matches
+	Search     string `json:"search,omitempty"`
+	SearchHash string `json:"search_hash,omitempty"`
+	// Cache is stale or orphan; empty when the entry matches or nothing is cached
+	Cache     string `json:"cache,omitempty"`
+	CacheHash string `json:"cache_hash,omitempty"`
+	Repaired  bool   `json:"repaired"`
+	// Error is why the repair failed
+	Error string `json:"error,omitempty"`
+}
+
+// ConsistencyReport is the outcome of comparing posts in Postgres with
+// Elasticsearch and the cache
+type ConsistencyReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Repair     bool      `json:"repair"`
+	// CacheChecked is false when the cache is local to each replica and was skipped
+	CacheChecked bool `json:"cache_checked"`
+	// Checked is the number of posts read from Postgres
+	Checked     int `json:"checked"`
+	SearchDrift int `json:"search_drift"`
+	CacheDrift  int `json:"cache_drift"`
+	Repaired    int `json:"repaired"`
+	// NextAfterID continues a check that stopped at its limit; 0 once every post was checked
+	NextAfterID int         `json:"next_after_id,omitempty"`
+	Drift       []PostDrift `json:"drift"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// PostEvent is a post change streamed from GET /events. ID is the Redis
+// stream entry ID, sent as the SSE id for Last-Event-ID resume. Post is the
+// post after the change, or as it was before for post.deleted.
+type PostEvent struct {
+	ID         string        `json:"id,omitempty"`
+	Type       string        `json:"type"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	PostID     int           `json:"post_id"`
+	Actor      string        `json:"actor"`
+	Post       *PostSnapshot `json:"post,omitempty"`
+}
@@ -6,12 +6,15 @@ import (
 
 // Post represents a blog post
 type Post struct {
-	ID           int       `json:"id"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	Tags         []string  `json:"tags"`
-	CreatedAt    time.Time `json:"created_at"`
-	RelatedPosts []Related `json:"related_posts,omitempty"`
+	ID           int            `json:"id"`
+	Title        string         `json:"title"`
+	Content      string         `json:"content"`
+	Tags         []string       `json:"tags"`
+	Language     string         `json:"language"`
+	CreatedAt    time.Time      `json:"created_at"`
+	CommentCount int            `json:"comment_count"`
+	Reactions    map[string]int `json:"reactions"`
+	RelatedPosts []Related      `json:"related_posts,omitempty"`
 }
 
 // Related represents a related post
@@ -23,16 +26,20 @@ type Related struct {
 
 // CreatePostRequest represents the request body for creating a post
 type CreatePostRequest struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	Title   string   `json:"title" validate:"required,max=255"`
+	Content string   `json:"content" validate:"required,max=100000"`
+	Tags    []string `json:"tags" validate:"max=10,unique,itemmin=1,itemmax=50,pattern=tag"`
+	// Language is "en" or "vi"; when omitted it is detected from the text
+	Language string `json:"language,omitempty"`
 }
 
 // UpdatePostRequest represents the request body for updating a post
 type UpdatePostRequest struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	Title   string   `json:"title" validate:"required,max=255"`
+	Content string   `json:"content" validate:"required,max=100000"`
+	Tags    []string `json:"tags" validate:"max=10,unique,itemmin=1,itemmax=50,pattern=tag"`
+	// Language is "en" or "vi"; when omitted it is detected from the text
+	Language string `json:"language,omitempty"`
 }
 
 // SearchResponse represents search results
@@ -40,3 +47,43 @@ type SearchResponse struct {
 	Posts []interface{} `json:"posts"`
 	Total int           `json:"total"`
 }
+
+// SearchHit describes one entry of SearchResponse.Posts. Tag searches return
+// id, title and tags; full-text searches also return content, language,
+// created_at, the reaction count and the relevance score.
+type SearchHit struct {
+	ID            int        `json:"id"`
+	Title         string     `json:"title"`
+	Tags          []string   `json:"tags"`
+	Content       string     `json:"content,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	Score         float64    `json:"score,omitempty"`
+	ReactionCount int        `json:"reaction_count,omitempty"`
+}
+
+// MessageResponse is returned by operations that have nothing else to report
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// PostStats holds the views of a post; Views is a delta when flushing counters
+type PostStats struct {
+	PostID         int   `json:"post_id"`
+	Views          int64 `json:"views"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// TrendingPost is a post ranked by its recent views, newer views weighing more
+type TrendingPost struct {
+	ID    int      `json:"id"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+// TrendingResponse lists the trending posts of a window, highest score first
+type TrendingResponse struct {
+	Window string         `json:"window"`
+	Posts  []TrendingPost `json:"posts"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// Reaction types readers can leave on a post
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+// ReactionTypes lists every reaction type
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad, ReactionAngry}
+
+// ReactionRequest represents the request body for reacting to a post
+type ReactionRequest struct {
+	Type string `json:"type" validate:"required"`
+}
+
+// PostReactions holds the reaction counts of a post by type
+type PostReactions struct {
+	PostID    int            `json:"post_id"`
+	Reactions map[string]int `json:"reactions"`
+}
+
+// TotalReactions adds up reaction counts of every type
+func TotalReactions(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
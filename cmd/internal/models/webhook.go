@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Post lifecycle events delivered to webhooks and streamed from /events
+const (
+	EventPostCreated = "post.created"
+	EventPostUpdated = "post.updated"
+	EventPostDeleted = "post.deleted"
+)
+
+// WebhookEventTypes lists every event a webhook can subscribe to
+var WebhookEventTypes = []string{EventPostCreated, EventPostUpdated, EventPostDeleted}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// DeliveryStatuses lists every delivery status
+var DeliveryStatuses = []string{DeliveryPending, DeliverySucceeded, DeliveryFailed}
+
+// Webhook is a subscription to post events. The secret is never returned.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookRequest represents the request body for subscribing to events
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,max=2000"`
+	Events []string `json:"events" validate:"required,unique"`
+	// Secret signs every delivery with HMAC-SHA256
+	Secret string `json:"secret" validate:"required,min=16,max=200"`
+}
+
+// WebhookEvent is the body of a webhook delivery. Post is the post after the
+// change, or as it was before for post.deleted.
+type WebhookEvent struct {
+	Type       string        `json:"type"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	PostID     int           `json:"post_id"`
+	Post       *PostSnapshot `json:"post,omitempty"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Attempts
+// counts requests made so far; ResponseStatus and Error describe the last one.
+type WebhookDelivery struct {
+	ID             int          `json:"id"`
+	WebhookID      int          `json:"webhook_id"`
+	Event          string       `json:"event"`
+	Payload        WebhookEvent `json:"payload"`
+	Status         string       `json:"status"`
+	Attempts       int          `json:"attempts"`
+	NextAttemptAt  *time.Time   `json:"next_attempt_at,omitempty"`
+	ResponseStatus int          `json:"response_status,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	DeliveredAt    *time.Time   `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryPage is one page of a webhook's deliveries, newest first
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page       int               `json:"page"`
+	PerPage    int               `json:"per_page"`
+	Total      int               `json:"total"`
+}
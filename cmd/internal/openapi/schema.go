@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/validation"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas derives JSON Schemas from Go types using their json and validate
+// tags. Named structs become components and are referenced with $ref.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// alias publishes a struct under a name other than its Go type name
+func (s *schemas) alias(v any, name string) {
+	s.names[reflect.TypeOf(v)] = name
+}
+
+// ref returns the schema for the type of v
+func (s *schemas) ref(v any) *Schema {
+	return s.of(reflect.TypeOf(v))
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.of(t.Elem()))
+	case reflect.Struct:
+		name := s.nameOf(t)
+		if _, ok := s.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Interface:
+		// Any JSON value
+		return &Schema{}
+	default:
+		panic(fmt.Sprintf("openapi: cannot describe %s", t))
+	}
+}
+
+func (s *schemas) nameOf(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	return t.Name()
+}
+
+// object describes a struct. A field is required when its validate tag says
+// so, or, for fields without one, when it is always present in the JSON.
+func (s *schemas) object(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs without a JSON name are flattened, as encoding/json does
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			embedded := s.object(sf.Type)
+			for prop, schema := range embedded.Properties {
+				obj.Properties[prop] = schema
+			}
+			obj.Required = append(obj.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		prop := s.of(sf.Type)
+		required := !strings.Contains(opts, "omitempty") && sf.Type.Kind() != reflect.Pointer
+		if tag, ok := sf.Tag.Lookup("validate"); ok {
+			rules, err := validation.ParseTag(tag)
+			if err != nil {
+				panic(fmt.Sprintf("openapi: %s.%s: %v", t.Name(), sf.Name, err))
+			}
+			prop = constrain(prop, rules)
+			required = rules.Required
+		}
+
+		obj.Properties[name] = prop
+		if required {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+	return obj
+}
+
+// constrain adds validation rules to a string or array schema
+func constrain(schema *Schema, rules validation.Rules) *Schema {
+	limit := func(n int) *int {
+		if n < 0 {
+			return nil
+		}
+		return &n
+	}
+
+	out := *schema
+	switch out.Type {
+	case "string":
+		out.MinLength, out.MaxLength = limit(rules.Min), limit(rules.Max)
+		if rules.Required && out.MinLength == nil {
+			out.MinLength = limit(1)
+		}
+		out.Pattern = validation.PatternExpr(rules.Pattern)
+	case "array":
+		out.MinItems, out.MaxItems = limit(rules.Min), limit(rules.Max)
+		if rules.Required && out.MinItems == nil {
+			out.MinItems = limit(1)
+		}
+		out.UniqueItems = rules.Unique
+		items := *out.Items
+		items.MinLength, items.MaxLength = limit(rules.ItemMin), limit(rules.ItemMax)
+		items.Pattern = validation.PatternExpr(rules.Pattern)
+		out.Items = &items
+	}
+	return &out
+}
+
+// nullable allows null in addition to the values schema accepts
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	}
+	out := *schema
+	if typ, ok := out.Type.(string); ok {
+		out.Type = []string{typ, "null"}
+	}
+	return &out
+}
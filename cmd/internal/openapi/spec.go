@@ -0,0 +1,523 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/handlers"
+	"github.com/hungpv1995/golang_training_2025/internal/health"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// APIVersion is the version of the blog API described by the document
+const APIVersion = "1.0.0"
+
+// securityScheme is the name of the API key scheme in the document
+const securityScheme = "ApiKeyAuth"
+
+// Content types used by the routes
+const (
+	contentJSON = "application/json"
+	contentText = "text/plain"
+	contentHTML = "text/html"
+	contentSSE  = "text/event-stream"
+)
+
+// Route describes one operation. Path params such as {id} are integers.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Tag         string
+	// Scope is the API key scope checked by the route. KeyRequired marks
+	// routes that need a key even when auth.enabled is false.
+	Scope       string
+	KeyRequired bool
+	// Query and Headers are parameters besides the {id} path params
+	Query   []Param
+	Headers []Param
+	// Body is a value of the request body type; BodyOptional allows an empty body
+	Body         any
+	BodyOptional bool
+	Status       int
+	// Response is a value of the response body type, nil for an empty body
+	Response    any
+	ContentType string
+	// Errors lists statuses beyond those implied by Body and Scope
+	Errors []int
+}
+
+// Param is a query or header parameter, a required string unless set otherwise
+type Param struct {
+	Name        string
+	Description string
+	Optional    bool
+	Integer     bool
+}
+
+// Parameters shared by the comment and activity routes
+var (
+	userIDHeader = Param{
+		Name:        handlers.UserIDHeader,
+		Description: "User on whose behalf the request is made. Only honoured from a trusted proxy (rate_limit.trust_proxy); otherwise the calling API key is the user.",
+		Optional:    true,
+	}
+	pageParams = []Param{
+		{Name: "page", Description: "Page number, from 1 to 10000", Optional: true, Integer: true},
+		{Name: "per_page", Description: "Items per page, at most 100 (default 20)", Optional: true, Integer: true},
+	}
+	activityParams = append([]Param{
+		{Name: "action", Description: "new_post, update_post, change_tags or delete_post", Optional: true},
+		{Name: "actor", Description: "X-User-ID of a trusted proxy, else API key name, of whoever made the change", Optional: true},
+		{Name: "since", Description: "Earliest time logged, RFC 3339", Optional: true},
+		{Name: "until", Description: "Time logged before, RFC 3339", Optional: true},
+	}, pageParams...)
+)
+
+// liveness is the body of /livez and /health
+type liveness struct {
+	Status string `json:"status"`
+}
+
+// routes must list every route registered in cmd/server; Verify checks it
+var routes = []Route{
+	{
+		Method: http.MethodPost, Path: "/posts", OperationID: "createPost", Tag: "posts",
+		Summary: "Create a post",
+		Scope:   auth.ScopePostsWrite,
+		Body:    models.CreatePostRequest{},
+		Status:  http.StatusCreated, Response: models.Post{},
+		Errors: []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/posts/{id}", OperationID: "getPost", Tag: "posts",
+		Summary: "Get a post with related posts, served from the cache when possible",
+		Scope:   auth.ScopePostsRead,
+		Status:  http.StatusOK, Response: models.Post{},
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodPut, Path: "/posts/{id}", OperationID: "updatePost", Tag: "posts",
+		Summary: "Update a post and invalidate its cache entry",
+		Scope:   auth.ScopePostsWrite,
+		Body:    models.UpdatePostRequest{},
+		Status:  http.StatusOK, Response: models.MessageResponse{},
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodDelete, Path: "/posts/{id}", OperationID: "deletePost", Tag: "posts",
+		Summary: "Delete a post with its comments and reactions; its activity log is kept",
+		Scope:   auth.ScopePostsWrite,
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/posts/search-by-tag", OperationID: "searchPostsByTag", Tag: "posts",
+		Summary: "List posts with a tag",
+		Scope:   auth.ScopePostsRead,
+		Query:   []Param{{Name: "tag", Description: "Exact tag to match"}},
+		Status:  http.StatusOK, Response: models.SearchResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/posts/search", OperationID: "searchPosts", Tag: "posts",
+		Summary: "Full-text search over titles and content",
+		Scope:   auth.ScopeSearchRead,
+		Query: []Param{
+			{Name: "q", Description: `Search query: words, "phrases", -excluded, a OR b, (groups), title:, content:, tag:, after:YYYY-MM-DD, before:YYYY-MM-DD and prefix*`},
+			{Name: "lang", Description: "Language of the query, en or vi; detected when omitted", Optional: true},
+			{Name: "language", Description: "Only posts written in this language, en or vi", Optional: true},
+		},
+		Status: http.StatusOK, Response: models.SearchResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/posts/trending", OperationID: "trendingPosts", Tag: "posts",
+		Summary: "Posts with the most recent views; newer views weigh more",
+		Scope:   auth.ScopePostsRead,
+		Query: []Param{
+			{Name: "window", Description: "24h (default) or 7d", Optional: true},
+			{Name: "limit", Description: "Number of posts, at most 50 (default 10)", Optional: true, Integer: true},
+		},
+		Status: http.StatusOK, Response: models.TrendingResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/posts/{id}/comments", OperationID: "listComments", Tag: "comments",
+		Summary: "List a post's approved top-level comments with their replies nested",
+		Scope:   auth.ScopePostsRead,
+		Query:   pageParams,
+		Status:  http.StatusOK, Response: models.CommentPage{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodPost, Path: "/posts/{id}/comments", OperationID: "createComment", Tag: "comments",
+		Summary: "Comment on a post or reply to a comment; the comment waits for moderation",
+		Scope:   auth.ScopeCommentsWrite,
+		Headers: []Param{userIDHeader},
+		Body:    models.CreateCommentRequest{},
+		Status:  http.StatusCreated, Response: models.Comment{},
+		Errors: []int{http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodPut, Path: "/comments/{id}", OperationID: "updateComment", Tag: "comments",
+		Summary: "Edit your own comment; it goes back to moderation",
+		Scope:   auth.ScopeCommentsWrite,
+		Headers: []Param{userIDHeader},
+		Body:    models.UpdateCommentRequest{},
+		Status:  http.StatusOK, Response: models.Comment{},
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodDelete, Path: "/comments/{id}", OperationID: "deleteComment", Tag: "comments",
+		Summary: "Delete your own comment, keeping a placeholder for its replies",
+		Scope:   auth.ScopeCommentsWrite,
+		Headers: []Param{userIDHeader},
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/comments/moderation", OperationID: "listModerationQueue", Tag: "comments",
+		Summary: "List comments with a moderation status, oldest first",
+		Scope:   auth.ScopeCommentsModerate, KeyRequired: true,
+		Query: append([]Param{
+			{Name: "status", Description: "pending (default), approved, rejected or spam", Optional: true},
+		}, pageParams...),
+		Status: http.StatusOK, Response: models.CommentPage{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodPut, Path: "/comments/{id}/status", OperationID: "moderateComment", Tag: "comments",
+		Summary: "Approve, reject or mark a comment as spam",
+		Scope:   auth.ScopeCommentsModerate, KeyRequired: true,
+		Body:   models.ModerateCommentRequest{},
+		Status: http.StatusOK, Response: models.Comment{},
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodPost, Path: "/posts/{id}/reactions", OperationID: "addReaction", Tag: "reactions",
+		Summary: "React to a post; reacting twice with the same type has no effect",
+		Scope:   auth.ScopeReactionsWrite,
+		Headers: []Param{userIDHeader},
+		Body:    models.ReactionRequest{},
+		Status:  http.StatusOK, Response: models.PostReactions{},
+		Errors: []int{http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodDelete, Path: "/posts/{id}/reactions", OperationID: "removeReaction", Tag: "reactions",
+		Summary: "Take back your reaction to a post",
+		Scope:   auth.ScopeReactionsWrite,
+		Headers: []Param{userIDHeader},
+		Query: []Param{
+			{Name: "type", Description: "like, love, laugh, wow, sad or angry"},
+		},
+		Status: http.StatusNoContent,
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/activity", OperationID: "listActivity", Tag: "activity",
+		Summary: "List the audit trail of changes to posts, newest first",
+		Scope:   auth.ScopeActivityRead, KeyRequired: true,
+		Query:  activityParams,
+		Status: http.StatusOK, Response: models.ActivityPage{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/posts/{id}/activity", OperationID: "listPostActivity", Tag: "activity",
+		Summary: "List the audit trail of one post, deleted posts included",
+		Scope:   auth.ScopeActivityRead, KeyRequired: true,
+		Query:  activityParams,
+		Status: http.StatusOK, Response: models.ActivityPage{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/events", OperationID: "streamEvents", Tag: "events",
+		Summary: "Stream post.created, post.updated and post.deleted as Server-Sent Events; each data line is a PostEvent",
+		Scope:   auth.ScopePostsRead,
+		Query: []Param{
+			{Name: "tag", Description: "Only posts with this tag (before the change for post.deleted)", Optional: true},
+			{Name: "author", Description: "Only changes made by this API key name or X-User-ID", Optional: true},
+		},
+		Headers: []Param{
+			{Name: "Last-Event-ID", Description: "Resume after this event, replaying the missed events still kept", Optional: true},
+		},
+		Status: http.StatusOK, ContentType: contentSSE,
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodPost, Path: "/webhooks", OperationID: "createWebhook", Tag: "webhooks",
+		Summary: "Subscribe a URL to post events; deliveries are signed with the secret",
+		Scope:   auth.ScopeWebhooksManage, KeyRequired: true,
+		Body:   models.CreateWebhookRequest{},
+		Status: http.StatusCreated, Response: models.Webhook{},
+		Errors: []int{http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks", OperationID: "listWebhooks", Tag: "webhooks",
+		Summary: "List webhooks without their secrets",
+		Scope:   auth.ScopeWebhooksManage, KeyRequired: true,
+		Status: http.StatusOK, Response: []models.Webhook{},
+		Errors: []int{http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodDelete, Path: "/webhooks/{id}", OperationID: "deleteWebhook", Tag: "webhooks",
+		Summary: "Delete a webhook with its delivery history",
+		Scope:   auth.ScopeWebhooksManage, KeyRequired: true,
+		Status: http.StatusNoContent,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/{id}/deliveries", OperationID: "listWebhookDeliveries", Tag: "webhooks",
+		Summary: "List a webhook's deliveries, newest first",
+		Scope:   auth.ScopeWebhooksManage, KeyRequired: true,
+		Query: append([]Param{
+			{Name: "status", Description: "pending, succeeded or failed", Optional: true},
+		}, pageParams...),
+		Status: http.StatusOK, Response: models.WebhookDeliveryPage{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhook-deliveries/{id}/redeliver", OperationID: "redeliverWebhook", Tag: "webhooks",
+		Summary: "Queue a delivery's event again as a new delivery",
+		Scope:   auth.ScopeWebhooksManage, KeyRequired: true,
+		Status: http.StatusAccepted, Response: models.WebhookDelivery{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodPost, Path: "/api-keys", OperationID: "createAPIKey", Tag: "api-keys",
+		Summary: "Create an API key; the key is only returned here",
+		Scope:   auth.ScopeKeysManage, KeyRequired: true,
+		Body:   models.CreateAPIKeyRequest{},
+		Status: http.StatusCreated, Response: models.CreatedAPIKey{},
+		Errors: []int{http.StatusInternalServerError},
+	},
+	{
+		Method: http.MethodGet, Path: "/api-keys", OperationID: "listAPIKeys", Tag: "api-keys",
+		Summary: "List API keys without their secrets",
+		Scope:   auth.ScopeKeysManage, KeyRequired: true,
+		Status: http.StatusOK, Response: []models.APIKey{},
+		Errors: []int{http.StatusInternalServerError},
+	},
+	{
+		Method: http.MethodPost, Path: "/api-keys/{id}/rotate", OperationID: "rotateAPIKey", Tag: "api-keys",
+		Summary: "Replace an API key, optionally keeping the old one valid for a grace period",
+		Scope:   auth.ScopeKeysManage, KeyRequired: true,
+		Body: models.RotateAPIKeyRequest{}, BodyOptional: true,
+		Status: http.StatusCreated, Response: models.CreatedAPIKey{},
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		Method: http.MethodDelete, Path: "/api-keys/{id}", OperationID: "revokeAPIKey", Tag: "api-keys",
+		Summary: "Revoke an API key immediately",
+		Scope:   auth.ScopeKeysManage, KeyRequired: true,
+		Status: http.StatusNoContent,
+		Errors: []int{http.StatusNotFound, http.StatusInternalServerError},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/admin/log-level", OperationID: "getLogLevel", Tag: "admin",
+		Summary: "Get the current log level",
+		Scope:   auth.ScopeAdmin, KeyRequired: true,
+		Status: http.StatusOK, Response: handlers.LogLevel{},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/log-level", OperationID: "setLogLevel", Tag: "admin",
+		Summary: "Change the log level without a restart",
+		Scope:   auth.ScopeAdmin, KeyRequired: true,
+		Body:   handlers.LogLevel{},
+		Status: http.StatusOK, Response: handlers.LogLevel{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/consistency", OperationID: "checkConsistency", Tag: "admin",
+		Summary: "Compare posts with their search documents and cached entries, optionally repairing drift",
+		Scope:   auth.ScopeAdmin, KeyRequired: true,
+		Query: []Param{
+			{Name: "after_id", Description: "Start after this post ID; pass next_after_id to continue", Optional: true, Integer: true},
+			{Name: "limit", Description: "Posts to check, at most 10000 (default 1000)", Optional: true, Integer: true},
+			{Name: "repair", Description: "true to reindex or remove drifted documents and evict drifted cache entries", Optional: true},
+		},
+		Status: http.StatusOK, Response: models.ConsistencyReport{},
+		Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusGatewayTimeout},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/livez", OperationID: "getLiveness", Tag: "operations",
+		Summary: "Liveness probe; checks no dependencies",
+		Status:  http.StatusOK, Response: liveness{},
+	},
+	{
+		Method: http.MethodGet, Path: "/health", OperationID: "getHealth", Tag: "operations",
+		Summary: "Alias of /livez kept for existing probes",
+		Status:  http.StatusOK, Response: liveness{},
+	},
+	{
+		Method: http.MethodGet, Path: "/readyz", OperationID: "getReadiness", Tag: "operations",
+		Summary: "Readiness probe; every dependency is up and the cache is warm",
+		Status:  http.StatusOK, Response: health.Report{},
+	},
+	{
+		Method: http.MethodGet, Path: "/metrics", OperationID: "getMetrics", Tag: "operations",
+		Summary: "Prometheus metrics",
+		Status:  http.StatusOK, ContentType: contentText,
+	},
+	{
+		Method: http.MethodGet, Path: "/openapi.json", OperationID: "getOpenAPI", Tag: "operations",
+		Summary: "This document",
+		Status:  http.StatusOK,
+	},
+	{
+		Method: http.MethodGet, Path: "/docs", OperationID: "getDocs", Tag: "operations",
+		Summary: "Swagger UI for this document",
+		Status:  http.StatusOK, ContentType: contentHTML,
+	},
+}
+
+// Spec builds the OpenAPI document for every route
+func Spec() *Document {
+	s := newSchemas()
+	s.alias(health.Report{}, "HealthReport")
+	s.alias(health.ComponentStatus{}, "ComponentHealth")
+	s.alias(liveness{}, "Liveness")
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       "Blog API",
+			Version:     APIVersion,
+			Description: "Posts, threaded comments and reactions with caching, full-text search and scoped API keys.",
+		},
+		Tags: []Tag{
+			{Name: "posts", Description: "Create, read, update and search posts"},
+			{Name: "comments", Description: "Threaded comments and their moderation"},
+			{Name: "reactions", Description: "Reactions to posts, one per user and type"},
+			{Name: "activity", Description: "Audit trail of changes to posts"},
+			{Name: "events", Description: "Live stream of post changes"},
+			{Name: "webhooks", Description: "Signed notifications of post changes"},
+			{Name: "api-keys", Description: "Credentials for machine clients"},
+			{Name: "admin", Description: "Runtime administration"},
+			{Name: "operations", Description: "Probes, metrics and documentation"},
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				securityScheme: {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "Authorization",
+					Description: `"ApiKey <key>". Scopes are listed per operation.`,
+				},
+			},
+		},
+	}
+
+	errorSchema := s.ref(handlers.ErrorResponse{})
+	for _, route := range routes {
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = PathItem{}
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = route.operation(s, errorSchema)
+	}
+
+	// Posts in search results are untyped maps; describe what they contain
+	s.components["SearchResponse"].Properties["posts"].Items = s.ref(models.SearchHit{})
+	// Event stream messages carry PostEvent JSON as their data
+	s.ref(models.PostEvent{})
+	// Readiness failures carry the report rather than an error
+	doc.Paths["/readyz"]["get"].Responses[strconv.Itoa(http.StatusServiceUnavailable)] = Response{
+		Description: http.StatusText(http.StatusServiceUnavailable),
+		Content:     map[string]MediaType{contentJSON: {Schema: s.ref(health.Report{})}},
+	}
+
+	doc.Components.Schemas = s.components
+	return doc
+}
+
+func (p Param) parameter(in string) Parameter {
+	typ := "string"
+	if p.Integer {
+		typ = "integer"
+	}
+	return Parameter{
+		Name:        p.Name,
+		In:          in,
+		Description: p.Description,
+		Required:    !p.Optional,
+		Schema:      &Schema{Type: typ},
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (r Route) operation(s *schemas, errorSchema *Schema) *Operation {
+	op := &Operation{
+		OperationID: r.OperationID,
+		Summary:     r.Summary,
+		Tags:        []string{r.Tag},
+		Responses:   make(map[string]Response),
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer"},
+		})
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, q.parameter("query"))
+	}
+	for _, h := range r.Headers {
+		op.Parameters = append(op.Parameters, h.parameter("header"))
+	}
+
+	errs := append([]int(nil), r.Errors...)
+	if r.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: !r.BodyOptional,
+			Content:  map[string]MediaType{contentJSON: {Schema: s.ref(r.Body)}},
+		}
+		errs = append(errs, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity)
+	}
+	if r.Scope != "" {
+		requirement := map[string][]string{securityScheme: {r.Scope}}
+		if r.KeyRequired {
+			op.Security = []map[string][]string{requirement}
+		} else {
+			// Anonymous access is allowed unless auth.enabled is set
+			op.Security = []map[string][]string{{}, requirement}
+		}
+		errs = append(errs, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	success := Response{Description: http.StatusText(r.Status)}
+	switch {
+	case r.Response != nil:
+		success.Content = map[string]MediaType{contentJSON: {Schema: s.ref(r.Response)}}
+	case r.ContentType != "":
+		success.Content = map[string]MediaType{r.ContentType: {Schema: &Schema{Type: "string"}}}
+	case r.Status != http.StatusNoContent:
+		success.Content = map[string]MediaType{contentJSON: {Schema: &Schema{Type: "object"}}}
+	}
+	op.Responses[strconv.Itoa(r.Status)] = success
+
+	sort.Ints(errs)
+	for _, status := range errs {
+		op.Responses[strconv.Itoa(status)] = Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{contentJSON: {Schema: errorSchema}},
+		}
+	}
+	return op
+}
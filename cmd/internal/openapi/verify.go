@@ -0,0 +1,66 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+var routePattern = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// Verify reports every route registered on router that the document does not
+// describe, and every documented operation that is not registered
+func Verify(router *mux.Router, doc *Document) error {
+	registered := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			// Routes matched by something other than a path, e.g. subrouter hosts
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{"ANY"}
+		}
+		for _, method := range methods {
+			registered[method+" "+PathTemplate(template)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("openapi: failed to walk routes: %w", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var problems []string
+	for route := range registered {
+		if !documented[route] {
+			problems = append(problems, "registered but not documented: "+route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			problems = append(problems, "documented but not registered: "+route)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi: document and routes diverge:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// PathTemplate strips variable patterns from a mux path template, turning
+// "/posts/{id:[0-9]+}" into the OpenAPI path "/posts/{id}"
+func PathTemplate(template string) string {
+	return routePattern.ReplaceAllString(template, "{$1}")
+}
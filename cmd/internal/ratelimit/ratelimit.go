@@ -0,0 +1,232 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported limiter backends
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// Limit is a token bucket: Rate tokens are added per second up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit reads a limit written as "<count>/<unit>[:<burst>]" where unit
+// is s, m or h, e.g. "10/m" or "5/s:20". Burst defaults to count.
+func ParseLimit(spec string) (Limit, error) {
+	spec = strings.TrimSpace(spec)
+	rateSpec, burstSpec, hasBurst := strings.Cut(spec, ":")
+	countSpec, unit, ok := strings.Cut(rateSpec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <count>/<s|m|h>[:<burst>]", spec)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(countSpec))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: count must be a positive integer", spec)
+	}
+
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid limit %q: unit must be s, m or h", spec)
+	}
+
+	burst := count
+	if hasBurst {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstSpec))
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q: burst must be a positive integer", spec)
+		}
+	}
+
+	return Limit{Rate: float64(count) / per.Seconds(), Burst: burst}, nil
+}
+
+// String formats the limit as requests per second with its burst
+func (l Limit) String() string {
+	return fmt.Sprintf("%g/s:%d", l.Rate, l.Burst)
+}
+
+// window is the time an empty bucket takes to refill completely
+func (l Limit) window() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result is the outcome of taking one token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// SetHeaders writes the RateLimit-* headers, plus Retry-After when the
+// request was rejected
+func (r Result) SetHeaders(h http.Header) {
+	h.Set("RateLimit-Limit", strconv.Itoa(r.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(r.Reset)))
+	if !r.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(r.RetryAfter), 1)))
+	}
+}
+
+// Limiter takes one token from the bucket identified by key
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	// Check reports whether Allow would succeed, without taking a token
+	Check(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule limits one route, identified by method and mux path template
+type Rule struct {
+	Method string
+	Route  string
+	Limit  Limit
+}
+
+// ParseRule reads a rule written as "<METHOD> <route>=<limit>", e.g.
+// "POST /posts=10/m:5". The route is the mux path template as registered.
+func ParseRule(spec string) (Rule, error) {
+	target, limitSpec, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rule %q, expected \"<METHOD> <route>=<limit>\"", spec)
+	}
+	method, route, ok := strings.Cut(strings.TrimSpace(target), " ")
+	route = strings.TrimSpace(route)
+	if !ok || method == "" || !strings.HasPrefix(route, "/") {
+		return Rule{}, fmt.Errorf("invalid rule %q, expected \"<METHOD> <route>=<limit>\"", spec)
+	}
+
+	limit, err := ParseLimit(limitSpec)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: %w", spec, err)
+	}
+
+	return Rule{
+		Method: strings.ToUpper(method),
+		Route:  NormalizeRoute(route),
+		Limit:  limit,
+	}, nil
+}
+
+// Config selects the backend and the limits applied to each route
+type Config struct {
+	Enabled bool
+	Backend string
+	// Default applies to every route without its own rule; nil leaves them unlimited
+	Default *Limit
+	Rules   []Rule
+	// Exempt routes are never limited, e.g. probes and the metrics endpoint
+	Exempt []string
+	// AuthFailures limits failed API key attempts per client IP, so keys
+	// cannot be guessed at the rate of the route limits; nil disables it
+	AuthFailures *Limit
+	// TrustProxy takes the client IP from X-Forwarded-For instead of the
+	// connection, and the user from X-User-ID
+	TrustProxy bool
+}
+
+// DefaultConfig returns the rate limits used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Enabled: true,
+		Backend: BackendRedis,
+		Exempt:  []string{"/livez", "/readyz", "/health", "/metrics"},
+	}
+}
+
+// LimitFor returns the limit for a request to route, and false when the
+// route is not limited
+func (c Config) LimitFor(method, route string) (Limit, bool) {
+	route = NormalizeRoute(route)
+	for _, exempt := range c.Exempt {
+		if route == exempt {
+			return Limit{}, false
+		}
+	}
+	for _, rule := range c.Rules {
+		if rule.Method == method && rule.Route == route {
+			return rule.Limit, true
+		}
+	}
+	if c.Default != nil {
+		return *c.Default, true
+	}
+	return Limit{}, false
+}
+
+var routePattern = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// NormalizeRoute strips variable patterns from a mux template so that
+// "/posts/{id:[0-9]+}" and "/posts/{id}" name the same route
+func NormalizeRoute(route string) string {
+	return routePattern.ReplaceAllString(route, "{$1}")
+}
+
+// ClientKey identifies an anonymous caller by IP. Behind a trusted proxy,
+// which sets X-User-ID for signed-in users and strips it from client
+// requests, the user ID is preferred. Authenticated callers are keyed by
+// their API key ID instead.
+func ClientKey(r *http.Request, trustProxy bool) string {
+	if user := TrustedUserID(r, trustProxy); user != "" {
+		return "user:" + user
+	}
+	return "ip:" + ClientIP(r, trustProxy)
+}
+
+// TrustedUserID returns the X-User-ID set by a trusted proxy, and "" when
+// there is none or the proxy is not trusted: a client talking to the API
+// directly could send any value
+func TrustedUserID(r *http.Request, trustProxy bool) string {
+	if !trustProxy {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-User-ID"))
+}
+
+// ClientIP returns the address of the caller, honouring X-Forwarded-For only
+// when the service runs behind a trusted proxy
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds rounds d up to whole seconds for the RateLimit headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Limit
+		wantErr bool
+	}{
+		{spec: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{spec: "60/m:5", want: Limit{Rate: 1, Burst: 5}},
+		{spec: " 3600/h ", want: Limit{Rate: 1, Burst: 3600}},
+		{spec: "10", wantErr: true},
+		{spec: "0/s", wantErr: true},
+		{spec: "10/d", wantErr: true},
+		{spec: "10/s:0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		forwarded  string
+		trustProxy bool
+		want       string
+	}{
+		{name: "direct", want: "ip:192.0.2.1"},
+		{name: "user header from a direct client is ignored", userID: "alice", want: "ip:192.0.2.1"},
+		{name: "forwarded for from a direct client is ignored", forwarded: "198.51.100.7", want: "ip:192.0.2.1"},
+		{name: "proxy without user", forwarded: "198.51.100.7, 10.0.0.1", trustProxy: true, want: "ip:198.51.100.7"},
+		{name: "proxy with user", userID: " alice ", forwarded: "198.51.100.7", trustProxy: true, want: "user:alice"},
+		{name: "proxy without forwarded for", trustProxy: true, want: "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/posts/1", nil)
+			r.RemoteAddr = "192.0.2.1:4321"
+			if tt.userID != "" {
+				r.Header.Set("X-User-ID", tt.userID)
+			}
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := ClientKey(r, tt.trustProxy); got != tt.want {
+				t.Errorf("ClientKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterCheckTakesNoToken(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLimiter()
+	limit := Limit{Rate: 1.0 / 3600, Burst: 2}
+
+	for i := 0; i < 5; i++ {
+		if result, _ := l.Check(ctx, "k", limit); !result.Allowed {
+			t.Fatalf("Check %d not allowed on a full bucket", i)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if result, _ := l.Allow(ctx, "k", limit); !result.Allowed {
+			t.Fatalf("Allow %d rejected within the burst", i)
+		}
+	}
+	result, _ := l.Check(ctx, "k", limit)
+	if result.Allowed {
+		t.Fatal("Check allowed on an empty bucket")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Hour {
+		t.Errorf("RetryAfter = %v, want within an hour", result.RetryAfter)
+	}
+}
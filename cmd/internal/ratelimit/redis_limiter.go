@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes from a bucket atomically. It uses the
+// Redis clock so replicas with skewed clocks share one consistent bucket.
+//
+// KEYS[1] bucket key
+// ARGV[1] rate in tokens per second, ARGV[2] burst, ARGV[3] tokens to take;
+// 0 only reports whether one is available
+// Returns {allowed, remaining, retry_after_ms, reset_ms}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil then
+  tokens = burst
+  updated = now
+end
+
+tokens = math.min(burst, tokens + (math.max(0, now - updated) / 1000) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+  tokens = tokens - cost
+  allowed = 1
+else
+  retry_after = math.ceil((1 - tokens) / rate * 1000)
+end
+
+local reset = math.ceil((burst - tokens) / rate * 1000)
+redis.call("HSET", KEYS[1], "tokens", tokens, "updated", now)
+redis.call("PEXPIRE", KEYS[1], reset + 1000)
+
+return {allowed, math.floor(tokens), retry_after, reset}
+`)
+
+// RedisLimiter shares buckets between replicas through Redis
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisLimiter(client *redis.Client, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow takes one token from the bucket for key
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.take(ctx, key, limit, 1)
+}
+
+// Check reports whether the bucket for key has a token, without taking it
+func (l *RedisLimiter) Check(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.take(ctx, key, limit, 0)
+}
+
+func (l *RedisLimiter) take(ctx context.Context, key string, limit Limit, cost int) (Result, error) {
+	values, err := tokenBucketScript.Run(ctx, l.client, []string{l.prefix + key}, limit.Rate, limit.Burst, cost).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+// FallbackLimiter uses primary and switches to fallback for any request
+// primary cannot serve, so a Redis outage degrades to per-replica limits
+// instead of rejecting or admitting everything
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	logger   *slog.Logger
+}
+
+func NewFallbackLimiter(primary, fallback Limiter, logger *slog.Logger) *FallbackLimiter {
+	return &FallbackLimiter{
+		primary:  primary,
+		fallback: fallback,
+		logger:   logger,
+	}
+}
+
+// Allow takes one token from primary, or from fallback if primary fails
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, limit)
+	if err == nil {
+		return result, nil
+	}
+
+	l.logger.WarnContext(ctx, "rate limiter unavailable, using in-memory fallback", slog.Any("error", err))
+	return l.fallback.Allow(ctx, key, limit)
+}
+
+// Check asks primary, or fallback if primary fails
+func (l *FallbackLimiter) Check(ctx context.Context, key string, limit Limit) (Result, error) {
+	result, err := l.primary.Check(ctx, key, limit)
+	if err == nil {
+		return result, nil
+	}
+
+	l.logger.WarnContext(ctx, "rate limiter unavailable, using in-memory fallback", slog.Any("error", err))
+	return l.fallback.Check(ctx, key, limit)
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ActivityRepository struct {
+	db *sql.DB
+}
+
+func NewActivityRepository(db *sql.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// logActivity records a change to a post in the transaction making it
+func logActivity(ctx context.Context, tx *sql.Tx, action string, postID int, actor models.Actor, before, after *models.PostSnapshot) error {
+	beforeData, err := encodeSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := encodeSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO activity_logs (action, post_id, actor, client_ip, before_data, after_data)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		action, postID, actor.Name, actor.IP, beforeData, afterData,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert activity log: %w", err)
+	}
+	return nil
+}
+
+// encodeSnapshot returns the JSONB value of a snapshot, NULL when there is none
+func encodeSnapshot(s *models.PostSnapshot) (any, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode post snapshot: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeSnapshot(data []byte) (*models.PostSnapshot, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var s models.PostSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode post snapshot: %w", err)
+	}
+	return &s, nil
+}
+
+// ListActivity returns one page of matching entries, newest first, and the
+// number of matches
+func (r *ActivityRepository) ListActivity(ctx context.Context, filter models.ActivityFilter, page, perPage int) (_ []models.ActivityLog, total int, err error) {
+	ctx, span := startSpan(ctx, "ActivityRepository", "ListActivity", attribute.String("activity.action", filter.Action), attribute.Int("page", page))
+	defer tracing.End(span, &err)
+
+	where, args := activityConditions(filter)
+
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM activity_logs`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count activity logs: %w", err)
+	}
+
+	n := len(args)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, action, COALESCE(post_id, 0), actor, client_ip, before_data, after_data, logged_at
+		 FROM activity_logs`+where+`
+		 ORDER BY logged_at DESC, id DESC
+		 LIMIT $`+strconv.Itoa(n+1)+` OFFSET $`+strconv.Itoa(n+2),
+		append(args, perPage, (page-1)*perPage)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list activity logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.ActivityLog{}
+	for rows.Next() {
+		var entry models.ActivityLog
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.PostID, &entry.Actor, &entry.ClientIP, &before, &after, &entry.LoggedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan activity log: %w", err)
+		}
+		if entry.Before, err = decodeSnapshot(before); err != nil {
+			return nil, 0, err
+		}
+		if entry.After, err = decodeSnapshot(after); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// activityConditions builds the WHERE clause of a filter
+func activityConditions(filter models.ActivityFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.PostID != 0 {
+		add("post_id = $%d", filter.PostID)
+	}
+	if !filter.Since.IsZero() {
+		add("logged_at >= $%d", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		add("logged_at < $%d", filter.Until.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// PruneActivity removes up to limit entries logged before cutoff, moving them
+// to activity_logs_archive when archive is set. Rows locked by another
+// replica's prune are skipped.
+func (r *ActivityRepository) PruneActivity(ctx context.Context, cutoff time.Time, limit int, archive bool) (_ int, err error) {
+	ctx, span := startSpan(ctx, "ActivityRepository", "PruneActivity", attribute.Bool("activity.archive", archive))
+	defer tracing.End(span, &err)
+
+	query := `DELETE FROM activity_logs WHERE id IN (
+		 SELECT id FROM activity_logs WHERE logged_at < $1
+		 ORDER BY logged_at LIMIT $2 FOR UPDATE SKIP LOCKED
+	 )`
+	if archive {
+		query = `WITH pruned AS (` + query + ` RETURNING *)
+		 INSERT INTO activity_logs_archive SELECT * FROM pruned`
+	}
+
+	res, err := r.db.ExecContext(ctx, query, cutoff.UTC(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune activity logs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune activity logs: %w", err)
+	}
+	span.SetAttributes(attribute.Int64("activity.pruned", n))
+	return int(n), nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const apiKeyColumns = `id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at, rotated_from`
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner, extra ...interface{}) (*models.APIKey, error) {
+	var key models.APIKey
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	var rotatedFrom sql.NullInt64
+
+	dest := []interface{}{&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt, &rotatedFrom}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	if rotatedFrom.Valid {
+		id := int(rotatedFrom.Int64)
+		key.RotatedFrom = &id
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new key by its prefix and hash
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest, prefix, hash string) (_ *models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "CreateAPIKey")
+	defer tracing.End(span, &err)
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+apiKeyColumns,
+		req.Name, prefix, hash, pq.Array(req.Scopes), req.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns every key, newest first, without secrets
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) (_ []*models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "ListAPIKeys")
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByPrefix returns a key and its stored hash for authentication
+func (r *APIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (_ *models.APIKey, _ string, err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "GetAPIKeyByPrefix", attribute.String("api_key.prefix", prefix))
+	defer tracing.End(span, &err)
+
+	var hash string
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+`, key_hash FROM api_keys WHERE prefix = $1`,
+		prefix,
+	), &hash)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, hash, nil
+}
+
+// GetAPIKey returns a key by ID without its secret
+func (r *APIKeyRepository) GetAPIKey(ctx context.Context, id int) (_ *models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "GetAPIKey", attribute.Int("api_key.id", id))
+	defer tracing.End(span, &err)
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// RotateAPIKey creates a replacement for an active key with the same name,
+// scopes and expiry, and retires the old key after grace (immediately if zero)
+func (r *APIKeyRepository) RotateAPIKey(ctx context.Context, id int, prefix, hash string, grace time.Duration) (_ *models.APIKey, err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "RotateAPIKey", attribute.Int("api_key.id", id))
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Retiring the old key first takes its row lock, so a concurrent rotation
+	// waits here and then finds revoked_at already set. revoked_at in the
+	// future keeps the old key usable until then.
+	result, err := tx.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = NOW() + make_interval(secs => $2)
+		 WHERE id = $1 AND revoked_at IS NULL`,
+		id, grace.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retire rotated api key: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	key, err := scanAPIKey(tx.QueryRowContext(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at, rotated_from)
+		 SELECT name, $2, $3, scopes, expires_at, id
+		 FROM api_keys
+		 WHERE id = $1
+		 RETURNING `+apiKeyColumns,
+		id, prefix, hash,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rotated api key: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return key, nil
+}
+
+// RevokeAPIKey revokes a key immediately
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "RevokeAPIKey", attribute.Int("api_key.id", id))
+	defer tracing.End(span, &err)
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = NOW()
+		 WHERE id = $1 AND (revoked_at IS NULL OR revoked_at > NOW())`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records when a key was last used
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) (err error) {
+	ctx, span := startSpan(ctx, "APIKeyRepository", "TouchAPIKey", attribute.Int("api_key.id", id))
+	defer tracing.End(span, &err)
+
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE api_keys SET last_used_at = $2 WHERE id = $1`,
+		id, usedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PostChange is a post created, updated or deleted at At, by database time
+type PostChange struct {
+	PostID int
+	At     time.Time
+}
+
+// ChangeRepository reads the change history kept by the posts triggers
+// (updated_at and post_deletions) to catch up on missed notifications
+type ChangeRepository struct {
+	db *sql.DB
+}
+
+func NewChangeRepository(db *sql.DB) *ChangeRepository {
+	return &ChangeRepository{db: db}
+}
+
+// Now returns the database clock, in the same form as updated_at
+func (r *ChangeRepository) Now(ctx context.Context) (now time.Time, err error) {
+	ctx, span := startSpan(ctx, "ChangeRepository", "Now")
+	defer tracing.End(span, &err)
+
+	if err := r.db.QueryRowContext(ctx, `SELECT NOW()::timestamp`).Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read database time: %w", err)
+	}
+	return now, nil
+}
+
+// ChangesAfter returns up to limit posts changed or deleted after the change
+// (at, postID), oldest first. Pass the last change returned to get the next page.
+func (r *ChangeRepository) ChangesAfter(ctx context.Context, after PostChange, limit int) (_ []PostChange, err error) {
+	ctx, span := startSpan(ctx, "ChangeRepository", "ChangesAfter", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, updated_at FROM posts WHERE (updated_at, id) > ($1, $2)
+		 UNION ALL
+		 SELECT post_id, deleted_at FROM post_deletions WHERE (deleted_at, post_id) > ($1, $2)
+		 ORDER BY 2, 1
+		 LIMIT $3`,
+		after.At, after.PostID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list post changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []PostChange
+	for rows.Next() {
+		var c PostChange
+		if err := rows.Scan(&c.PostID, &c.At); err != nil {
+			return nil, fmt.Errorf("failed to scan post change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// PruneDeletions removes tombstones of posts deleted before cutoff
+func (r *ChangeRepository) PruneDeletions(ctx context.Context, cutoff time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "ChangeRepository", "PruneDeletions")
+	defer tracing.End(span, &err)
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM post_deletions WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune post deletions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// indexerLockID is the advisory lock held by the one replica that reindexes
+// captured changes. The value is arbitrary but differs from the migration lock.
+const indexerLockID = 4_731_020_251
+
+// IndexerLock is the session advisory lock of the replica that reindexes
+// captured changes. It is held for as long as its connection stays open.
+type IndexerLock struct {
+	conn *sql.Conn
+}
+
+// TryIndexerLock takes the indexer lock without waiting. It returns nil when
+// another replica holds it.
+func (r *ChangeRepository) TryIndexerLock(ctx context.Context) (_ *IndexerLock, err error) {
+	ctx, span := startSpan(ctx, "ChangeRepository", "TryIndexerLock")
+	defer tracing.End(span, &err)
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, indexerLockID).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take the indexer lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return &IndexerLock{conn: conn}, nil
+}
+
+// Held reports whether the lock's connection, and so the lock, is still alive
+func (l *IndexerLock) Held(ctx context.Context) bool {
+	return l.conn.PingContext(ctx) == nil
+}
+
+// Release gives up the lock, so another replica can take over
+func (l *IndexerLock) Release(ctx context.Context) error {
+	defer l.conn.Close()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, indexerLockID); err != nil {
+		return fmt.Errorf("failed to release the indexer lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrCommentNotFound is returned when no comment has the requested ID, or it was deleted
+var ErrCommentNotFound = errors.New("comment not found")
+
+// ErrInvalidParent is returned when a reply's parent is missing, deleted or on another post
+var ErrInvalidParent = errors.New("parent comment not found on this post")
+
+const commentColumns = `id, post_id, parent_id, author_id, body, status, created_at, updated_at, deleted_at`
+
+type CommentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+func scanComment(row interface{ Scan(...any) error }) (*models.Comment, error) {
+	var c models.Comment
+	var parentID sql.NullInt64
+	var updatedAt, deletedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.PostID, &parentID, &c.AuthorID, &c.Body, &c.Status, &c.CreatedAt, &updatedAt, &deletedAt); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	if updatedAt.Valid {
+		c.UpdatedAt = &updatedAt.Time
+	}
+	if deletedAt.Valid {
+		c.Deleted = true
+		c.AuthorID = ""
+		c.Body = ""
+	}
+	return &c, nil
+}
+
+func scanComments(rows *sql.Rows) ([]*models.Comment, error) {
+	defer rows.Close()
+	var comments []*models.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// CreateComment adds a pending comment to a post, as a reply when ParentID is set
+func (r *CommentRepository) CreateComment(ctx context.Context, postID int, authorID string, req *models.CreateCommentRequest) (_ *models.Comment, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "CreateComment", attribute.Int("post.id", postID))
+	defer tracing.End(span, &err)
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)`, postID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check post: %w", err)
+	}
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	if req.ParentID != nil {
+		err := r.db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND post_id = $2 AND deleted_at IS NULL)`,
+			*req.ParentID, postID,
+		).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check parent comment: %w", err)
+		}
+		if !exists {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	comment, err := scanComment(r.db.QueryRowContext(ctx,
+		`INSERT INTO comments (post_id, parent_id, author_id, body)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+commentColumns,
+		postID, req.ParentID, authorID, req.Body,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("comment.id", comment.ID))
+	return comment, nil
+}
+
+// GetComment retrieves a comment that has not been deleted
+func (r *CommentRepository) GetComment(ctx context.Context, id int) (_ *models.Comment, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "GetComment", attribute.Int("comment.id", id))
+	defer tracing.End(span, &err)
+
+	comment, err := scanComment(r.db.QueryRowContext(ctx,
+		`SELECT `+commentColumns+` FROM comments WHERE id = $1 AND deleted_at IS NULL`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return comment, nil
+}
+
+// ListThreads returns one page of a post's approved top-level comments with
+// all their approved replies nested, and the number of top-level comments
+func (r *CommentRepository) ListThreads(ctx context.Context, postID, page, perPage int) (_ []models.Comment, total int, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "ListThreads", attribute.Int("post.id", postID), attribute.Int("page", page))
+	defer tracing.End(span, &err)
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM comments WHERE post_id = $1 AND parent_id IS NULL AND status = $2`,
+		postID, models.CommentApproved,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+commentColumns+` FROM comments
+		 WHERE post_id = $1 AND parent_id IS NULL AND status = $2
+		 ORDER BY created_at, id
+		 LIMIT $3 OFFSET $4`,
+		postID, models.CommentApproved, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+	roots, err := scanComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(roots) == 0 {
+		return []models.Comment{}, total, nil
+	}
+
+	rootIDs := make([]int, len(roots))
+	for i, c := range roots {
+		rootIDs[i] = c.ID
+	}
+
+	// Replies to hidden comments stay hidden, so the walk stops at them
+	rows, err = r.db.QueryContext(ctx,
+		`WITH RECURSIVE thread AS (
+		     SELECT `+commentColumns+` FROM comments
+		     WHERE parent_id = ANY($1) AND status = $2
+		   UNION ALL
+		     SELECT c.id, c.post_id, c.parent_id, c.author_id, c.body, c.status, c.created_at, c.updated_at, c.deleted_at
+		     FROM comments c JOIN thread t ON c.parent_id = t.id
+		     WHERE c.status = $2
+		 )
+		 SELECT `+commentColumns+` FROM thread ORDER BY created_at, id`,
+		pq.Array(rootIDs), models.CommentApproved,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list replies: %w", err)
+	}
+	replies, err := scanComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buildThreads(roots, replies), total, nil
+}
+
+// buildThreads nests replies under their parents, keeping creation order
+func buildThreads(roots, replies []*models.Comment) []models.Comment {
+	children := make(map[int][]*models.Comment)
+	for _, c := range replies {
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+
+	var nest func(c *models.Comment) models.Comment
+	nest = func(c *models.Comment) models.Comment {
+		out := *c
+		for _, child := range children[c.ID] {
+			out.Replies = append(out.Replies, nest(child))
+		}
+		return out
+	}
+
+	threads := make([]models.Comment, len(roots))
+	for i, root := range roots {
+		threads[i] = nest(root)
+	}
+	return threads
+}
+
+// ListByStatus returns one page of undeleted comments with a moderation
+// status, oldest first, and how many there are in total
+func (r *CommentRepository) ListByStatus(ctx context.Context, status string, page, perPage int) (_ []models.Comment, total int, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "ListByStatus", attribute.String("comment.status", status), attribute.Int("page", page))
+	defer tracing.End(span, &err)
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM comments WHERE status = $1 AND deleted_at IS NULL`,
+		status,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+commentColumns+` FROM comments
+		 WHERE status = $1 AND deleted_at IS NULL
+		 ORDER BY created_at, id
+		 LIMIT $2 OFFSET $3`,
+		status, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+	found, err := scanComments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	comments := make([]models.Comment, len(found))
+	for i, c := range found {
+		comments[i] = *c
+	}
+	return comments, total, nil
+}
+
+// UpdateComment replaces a comment's body and sends it back to moderation.
+// countChanged reports whether the post's comment count changed as a result.
+func (r *CommentRepository) UpdateComment(ctx context.Context, id int, body string) (_ *models.Comment, countChanged bool, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "UpdateComment", attribute.Int("comment.id", id))
+	defer tracing.End(span, &err)
+
+	return r.updateAndCount(ctx, id,
+		`UPDATE comments SET body = $2, status = $3, updated_at = NOW()
+		 WHERE id = $1 AND deleted_at IS NULL
+		 RETURNING `+commentColumns,
+		body, models.CommentPending,
+	)
+}
+
+// SetStatus moves a comment to a moderation status.
+// countChanged reports whether the post's comment count changed as a result.
+func (r *CommentRepository) SetStatus(ctx context.Context, id int, status string) (_ *models.Comment, countChanged bool, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "SetStatus", attribute.Int("comment.id", id), attribute.String("comment.status", status))
+	defer tracing.End(span, &err)
+
+	return r.updateAndCount(ctx, id,
+		`UPDATE comments SET status = $2
+		 WHERE id = $1 AND deleted_at IS NULL
+		 RETURNING `+commentColumns,
+		status,
+	)
+}
+
+// DeleteComment blanks a comment, keeping it as a placeholder for its replies.
+// countChanged reports whether the post's comment count changed as a result.
+func (r *CommentRepository) DeleteComment(ctx context.Context, id int) (_ *models.Comment, countChanged bool, err error) {
+	ctx, span := startSpan(ctx, "CommentRepository", "DeleteComment", attribute.Int("comment.id", id))
+	defer tracing.End(span, &err)
+
+	return r.updateAndCount(ctx, id,
+		`UPDATE comments SET body = '', deleted_at = NOW()
+		 WHERE id = $1 AND deleted_at IS NULL
+		 RETURNING `+commentColumns,
+	)
+}
+
+// updateAndCount runs an UPDATE ... RETURNING on comment id and recounts the
+// post's visible comments in the same transaction
+func (r *CommentRepository) updateAndCount(ctx context.Context, id int, query string, args ...any) (*models.Comment, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	comment, err := scanComment(tx.QueryRowContext(ctx, query, append([]any{id}, args...)...))
+	if err == sql.ErrNoRows {
+		return nil, false, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	var before, after int
+	err = tx.QueryRowContext(ctx,
+		`WITH previous AS (
+		     SELECT comment_count FROM posts WHERE id = $1 FOR UPDATE
+		 )
+		 UPDATE posts SET comment_count = (
+		     SELECT COUNT(*) FROM comments
+		     WHERE post_id = $1 AND status = $2 AND deleted_at IS NULL
+		 )
+		 FROM previous
+		 WHERE posts.id = $1
+		 RETURNING previous.comment_count, posts.comment_count`,
+		comment.PostID, models.CommentApproved,
+	).Scan(&before, &after)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update comment count: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return comment, before != after, nil
+}
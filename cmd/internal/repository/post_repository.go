@@ -1,25 +1,79 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/hungpv1995/golang_training_2025/internal/models"
-	_ "github.com/lib/pq"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/repository")
+
+// ErrPostNotFound is returned when no post has the requested ID
+var ErrPostNotFound = errors.New("post not found")
+
 type PostRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPostRepository(db *sql.DB, logger *slog.Logger) *PostRepository {
+	return &PostRepository{db: db, logger: logger}
+}
+
+// Ping checks if the database is reachable
+func (r *PostRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// reactionCountsColumn selects a post's reaction counts as a JSON object
+const reactionCountsColumn = `COALESCE((
+		SELECT jsonb_object_agg(reaction, n) FROM (
+			SELECT reaction, COUNT(*) AS n FROM post_reactions
+			WHERE post_id = posts.id GROUP BY reaction
+		) counts
+	), '{}')`
+
+// decodeReactions parses the reactionCountsColumn value
+func decodeReactions(data []byte) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(data) == 0 {
+		return counts, nil
+	}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("failed to decode reaction counts: %w", err)
+	}
+	return counts, nil
 }
 
-func NewPostRepository(db *sql.DB) *PostRepository {
-	return &PostRepository{db: db}
+// startSpan starts a client span for one repository operation
+func startSpan(ctx context.Context, repository, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemPostgreSQL, semconv.DBOperation(operation))
+	return tracer.Start(ctx, repository+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
 }
 
 // CreatePostWithTransaction creates a new post and logs the activity in a transaction
-func (r *PostRepository) CreatePostWithTransaction(post *models.CreatePostRequest) (*models.Post, error) {
-	tx, err := r.db.Begin()
+func (r *PostRepository) CreatePostWithTransaction(ctx context.Context, post *models.CreatePostRequest, actor models.Actor) (_ *models.Post, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "CreatePostWithTransaction")
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -27,24 +81,24 @@ func (r *PostRepository) CreatePostWithTransaction(post *models.CreatePostReques
 
 	// Insert post
 	var newPost models.Post
-	err = tx.QueryRow(
-		`INSERT INTO posts (title, content, tags)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, title, content, tags, created_at`,
-		post.Title, post.Content, post.Tags,
-	).Scan(&newPost.ID, &newPost.Title, &newPost.Content, &newPost.Tags, &newPost.CreatedAt)
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO posts (title, content, tags, language)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, title, content, tags, language, created_at, comment_count`,
+		post.Title, post.Content, pq.Array(post.Tags), post.Language,
+	).Scan(&newPost.ID, &newPost.Title, &newPost.Content, pq.Array(&newPost.Tags), &newPost.Language, &newPost.CreatedAt, &newPost.CommentCount)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert post: %w", err)
 	}
 
 	// Insert activity log
-	_, err = tx.Exec(
-		`INSERT INTO activity_logs (action, post_id) VALUES ($1, $2)`,
-		"new_post", newPost.ID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert activity log: %w", err)
+	after := &models.PostSnapshot{Title: newPost.Title, Content: newPost.Content, Tags: newPost.Tags, Language: newPost.Language}
+	if err := logActivity(ctx, tx, models.ActionNewPost, newPost.ID, actor, nil, after); err != nil {
+		return nil, err
+	}
+	if err := enqueueWebhookEvent(ctx, tx, models.WebhookEvent{Type: models.EventPostCreated, OccurredAt: newPost.CreatedAt, PostID: newPost.ID, Post: after}); err != nil {
+		return nil, err
 	}
 
 	// Commit transaction
@@ -52,22 +106,28 @@ func (r *PostRepository) CreatePostWithTransaction(post *models.CreatePostReques
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	newPost.Reactions = map[string]int{}
+	span.SetAttributes(attribute.Int("post.id", newPost.ID))
 	return &newPost, nil
 }
 
 // GetPostByID retrieves a post by its ID
-func (r *PostRepository) GetPostByID(id int) (*models.Post, error) {
+func (r *PostRepository) GetPostByID(ctx context.Context, id int) (_ *models.Post, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "GetPostByID", attribute.Int("post.id", id))
+	defer tracing.End(span, &err)
+
 	var post models.Post
 	var tagsArray sql.NullString
+	var reactions []byte
 
-	err := r.db.QueryRow(
-		`SELECT id, title, content, array_to_string(tags, ','), created_at
+	err = r.db.QueryRowContext(ctx,
+		`SELECT id, title, content, array_to_string(tags, ','), language, created_at, comment_count, `+reactionCountsColumn+`
 		 FROM posts WHERE id = $1`,
 		id,
-	).Scan(&post.ID, &post.Title, &post.Content, &tagsArray, &post.CreatedAt)
+	).Scan(&post.ID, &post.Title, &post.Content, &tagsArray, &post.Language, &post.CreatedAt, &post.CommentCount, &reactions)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrPostNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
@@ -80,34 +140,222 @@ func (r *PostRepository) GetPostByID(id int) (*models.Post, error) {
 		post.Tags = []string{}
 	}
 
+	if post.Reactions, err = decodeReactions(reactions); err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
-// UpdatePost updates an existing post
-func (r *PostRepository) UpdatePost(id int, post *models.UpdatePostRequest) error {
-	result, err := r.db.Exec(
-		`UPDATE posts SET title = $1, content = $2, tags = $3 WHERE id = $4`,
-		post.Title, post.Content, post.Tags, id,
+// ListRecentPostIDs returns the IDs of the most recently created posts
+func (r *PostRepository) ListRecentPostIDs(ctx context.Context, limit int) (_ []int, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "ListRecentPostIDs", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id FROM posts ORDER BY created_at DESC, id DESC LIMIT $1`,
+		limit,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update post: %w", err)
+		return nil, fmt.Errorf("failed to list recent posts: %w", err)
 	}
+	return scanPostIDs(rows)
+}
+
+// ListMostViewedPostIDs returns the IDs of the posts with the most views
+// flushed to post_stats
+func (r *PostRepository) ListMostViewedPostIDs(ctx context.Context, limit int) (_ []int, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "ListMostViewedPostIDs", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
 
-	rowsAffected, err := result.RowsAffected()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT post_id FROM post_stats ORDER BY views DESC, post_id DESC LIMIT $1`,
+		limit,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to list most viewed posts: %w", err)
 	}
+	return scanPostIDs(rows)
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("post not found")
+func scanPostIDs(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan post id: %w", err)
+		}
+		ids = append(ids, id)
 	}
 
-	return nil
+	return ids, rows.Err()
+}
+
+// GetPostsByIDs retrieves several posts in one query; missing IDs are skipped
+func (r *PostRepository) GetPostsByIDs(ctx context.Context, ids []int) (_ []*models.Post, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "GetPostsByIDs", attribute.Int("post.count", len(ids)))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, title, content, tags, language, created_at, comment_count, `+reactionCountsColumn+`
+		 FROM posts WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+	return scanPosts(rows)
+}
+
+// ListPostsAfter returns up to limit posts with an ID above afterID, in ID
+// order. Pass the last ID returned to get the next page.
+func (r *PostRepository) ListPostsAfter(ctx context.Context, afterID, limit int) (_ []*models.Post, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "ListPostsAfter", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, title, content, tags, language, created_at, comment_count, `+reactionCountsColumn+`
+		 FROM posts WHERE id > $1 ORDER BY id LIMIT $2`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+	return scanPosts(rows)
+}
+
+func scanPosts(rows *sql.Rows) ([]*models.Post, error) {
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		var post models.Post
+		var tags pq.StringArray
+		var reactions []byte
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &tags, &post.Language, &post.CreatedAt, &post.CommentCount, &reactions); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		var err error
+		if post.Reactions, err = decodeReactions(reactions); err != nil {
+			return nil, err
+		}
+		post.Tags = []string(tags)
+		if post.Tags == nil {
+			post.Tags = []string{}
+		}
+		posts = append(posts, &post)
+	}
+
+	return posts, rows.Err()
+}
+
+// UpdatePost updates an existing post and logs the change with the post as
+// it was before. Changes to the tags alone are logged as change_tags. The
+// post as updated is returned.
+func (r *PostRepository) UpdatePost(ctx context.Context, id int, post *models.UpdatePostRequest, actor models.Actor) (_ *models.PostSnapshot, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "UpdatePost", attribute.Int("post.id", id))
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockPost(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE posts SET title = $1, content = $2, tags = $3, language = $4 WHERE id = $5`,
+		post.Title, post.Content, pq.Array(post.Tags), post.Language, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	after := &models.PostSnapshot{Title: post.Title, Content: post.Content, Tags: post.Tags, Language: post.Language}
+	if after.Tags == nil {
+		after.Tags = []string{}
+	}
+	action := models.ActionUpdatePost
+	if before.Title == after.Title && before.Content == after.Content && !slices.Equal(before.Tags, after.Tags) {
+		action = models.ActionChangeTags
+	}
+	if err := logActivity(ctx, tx, action, id, actor, before, after); err != nil {
+		return nil, err
+	}
+	if err := enqueueWebhookEvent(ctx, tx, models.WebhookEvent{Type: models.EventPostUpdated, OccurredAt: time.Now().UTC(), PostID: id, Post: after}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return after, nil
+}
+
+// DeletePost deletes a post with its comments, reactions and stats, and logs
+// and returns the post as it was
+func (r *PostRepository) DeletePost(ctx context.Context, id int, actor models.Actor) (_ *models.PostSnapshot, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "DeletePost", attribute.Int("post.id", id))
+	defer tracing.End(span, &err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockPost(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM posts WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete post: %w", err)
+	}
+	if err := logActivity(ctx, tx, models.ActionDeletePost, id, actor, before, nil); err != nil {
+		return nil, err
+	}
+	if err := enqueueWebhookEvent(ctx, tx, models.WebhookEvent{Type: models.EventPostDeleted, OccurredAt: time.Now().UTC(), PostID: id, Post: before}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return before, nil
+}
+
+// lockPost reads a post for a change in tx, locking it until tx ends
+func lockPost(ctx context.Context, tx *sql.Tx, id int) (*models.PostSnapshot, error) {
+	var snapshot models.PostSnapshot
+	err := tx.QueryRowContext(ctx,
+		`SELECT title, content, tags, language FROM posts WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&snapshot.Title, &snapshot.Content, pq.Array(&snapshot.Tags), &snapshot.Language)
+	if err == sql.ErrNoRows {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if snapshot.Tags == nil {
+		snapshot.Tags = []string{}
+	}
+	return &snapshot, nil
 }
 
 // SearchPostsByTag searches posts by a specific tag using GIN index
-func (r *PostRepository) SearchPostsByTag(tag string) ([]map[string]interface{}, error) {
-	rows, err := r.db.Query(
+func (r *PostRepository) SearchPostsByTag(ctx context.Context, tag string) (_ []map[string]interface{}, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "SearchPostsByTag", attribute.String("post.tag", tag))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
 		`SELECT id, title, tags FROM posts WHERE $1 = ANY(tags)`,
 		tag,
 	)
@@ -123,6 +371,7 @@ func (r *PostRepository) SearchPostsByTag(tag string) ([]map[string]interface{},
 		var tagsArray sql.NullString
 
 		if err := rows.Scan(&id, &title, &tagsArray); err != nil {
+			r.logger.WarnContext(ctx, "skipping unreadable post in tag search", slog.String("tag", tag), slog.Any("error", err))
 			continue
 		}
 
@@ -151,3 +400,59 @@ func (r *PostRepository) SearchPostsByTag(tag string) ([]map[string]interface{},
 
 	return posts, nil
 }
+
+// SavePendingIndexJobs records posts that still need to be indexed in
+// Elasticsearch, or removed from it once deleted
+func (r *PostRepository) SavePendingIndexJobs(ctx context.Context, postIDs []int) (err error) {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	ctx, span := startSpan(ctx, "PostRepository", "SavePendingIndexJobs", attribute.Int("post.count", len(postIDs)))
+	defer tracing.End(span, &err)
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO pending_index_jobs (post_id)
+		 SELECT unnest($1::int[])
+		 ON CONFLICT (post_id) DO NOTHING`,
+		pq.Array(postIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pending index jobs: %w", err)
+	}
+
+	return nil
+}
+
+// TakePendingIndexJobs removes and returns up to limit pending index jobs, oldest first
+func (r *PostRepository) TakePendingIndexJobs(ctx context.Context, limit int) (_ []int, err error) {
+	ctx, span := startSpan(ctx, "PostRepository", "TakePendingIndexJobs", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM pending_index_jobs
+		 WHERE post_id IN (
+		     SELECT post_id FROM pending_index_jobs
+		     ORDER BY queued_at
+		     LIMIT $1
+		     FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING post_id`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take pending index jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var postIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan pending index job: %w", err)
+		}
+		postIDs = append(postIDs, id)
+	}
+
+	return postIDs, rows.Err()
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// foreignKeyViolation is the Postgres error code for a missing referenced row
+const foreignKeyViolation = "23503"
+
+type ReactionRepository struct {
+	db *sql.DB
+}
+
+func NewReactionRepository(db *sql.DB) *ReactionRepository {
+	return &ReactionRepository{db: db}
+}
+
+// AddReaction records a user's reaction to a post. added is false when the
+// user had already left that reaction, which the unique constraint enforces.
+func (r *ReactionRepository) AddReaction(ctx context.Context, postID int, userID, reaction string) (added bool, err error) {
+	ctx, span := startSpan(ctx, "ReactionRepository", "AddReaction", attribute.Int("post.id", postID), attribute.String("reaction", reaction))
+	defer tracing.End(span, &err)
+
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO post_reactions (post_id, user_id, reaction)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT ON CONSTRAINT post_reactions_unique DO NOTHING`,
+		postID, userID, reaction,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return false, ErrPostNotFound
+		}
+		return false, fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RemoveReaction deletes a user's reaction to a post. removed is false when
+// there was nothing to remove.
+func (r *ReactionRepository) RemoveReaction(ctx context.Context, postID int, userID, reaction string) (removed bool, err error) {
+	ctx, span := startSpan(ctx, "ReactionRepository", "RemoveReaction", attribute.Int("post.id", postID), attribute.String("reaction", reaction))
+	defer tracing.End(span, &err)
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2 AND reaction = $3`,
+		postID, userID, reaction,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CountReactions returns the reactions of a post by type
+func (r *ReactionRepository) CountReactions(ctx context.Context, postID int) (_ map[string]int, err error) {
+	ctx, span := startSpan(ctx, "ReactionRepository", "CountReactions", attribute.Int("post.id", postID))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT reaction, COUNT(*) FROM post_reactions WHERE post_id = $1 GROUP BY reaction`,
+		postID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reactions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reaction string
+		var n int
+		if err := rows.Scan(&reaction, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction count: %w", err)
+		}
+		counts[reaction] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepository(db *sql.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// AddPostStats adds view deltas to post_stats in one statement. Unique
+// visitor counts are estimates of the total, so the larger value is kept.
+// Stats for posts that no longer exist are dropped.
+func (r *StatsRepository) AddPostStats(ctx context.Context, stats []models.PostStats) (err error) {
+	ctx, span := startSpan(ctx, "StatsRepository", "AddPostStats", attribute.Int("post.count", len(stats)))
+	defer tracing.End(span, &err)
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(stats))
+	views := make([]int64, len(stats))
+	visitors := make([]int64, len(stats))
+	for i, s := range stats {
+		ids[i], views[i], visitors[i] = int64(s.PostID), s.Views, s.UniqueVisitors
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO post_stats (post_id, views, unique_visitors, updated_at)
+		 SELECT s.post_id, s.views, s.unique_visitors, NOW()
+		 FROM unnest($1::int[], $2::bigint[], $3::bigint[]) AS s(post_id, views, unique_visitors)
+		 WHERE EXISTS (SELECT 1 FROM posts WHERE id = s.post_id)
+		 ON CONFLICT (post_id) DO UPDATE SET
+		     views = post_stats.views + EXCLUDED.views,
+		     unique_visitors = GREATEST(post_stats.unique_visitors, EXCLUDED.unique_visitors),
+		     updated_at = NOW()`,
+		pq.Array(ids), pq.Array(views), pq.Array(visitors),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save post stats: %w", err)
+	}
+	return nil
+}
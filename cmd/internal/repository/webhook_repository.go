@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrWebhookNotFound is returned when no webhook has the given ID
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// ErrDeliveryNotFound is returned when no webhook delivery has the given ID
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts,
+	CASE WHEN status = 'pending' THEN next_attempt_at END,
+	COALESCE(response_status, 0), COALESCE(error, ''), created_at, delivered_at`
+
+// PendingDelivery is a delivery claimed for sending, with what is needed to send it
+type PendingDelivery struct {
+	ID       int
+	URL      string
+	Secret   string
+	Event    string
+	Payload  []byte
+	Attempts int
+}
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// enqueueWebhookEvent queues a delivery of event to every webhook subscribed
+// to it, in the transaction making the change, so events are neither lost
+// nor sent for changes that roll back
+func enqueueWebhookEvent(ctx context.Context, tx *sql.Tx, event models.WebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		 SELECT id, $1, $2 FROM webhooks WHERE $1 = ANY(events)`,
+		event.Type, string(payload),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// CreateWebhook subscribes a URL to events
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, req *models.CreateWebhookRequest) (_ *models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "CreateWebhook")
+	defer tracing.End(span, &err)
+
+	var webhook models.Webhook
+	err = r.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (url, events, secret) VALUES ($1, $2, $3)
+		 RETURNING id, url, events, created_at`,
+		req.URL, pq.Array(req.Events), req.Secret,
+	).Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.Events), &webhook.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns every webhook, oldest first
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "ListWebhooks")
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, url, events, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.Events), &webhook.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes a webhook with its delivery history
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "DeleteWebhook", attribute.Int("webhook.id", id))
+	defer tracing.End(span, &err)
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns one page of a webhook's deliveries, newest first,
+// optionally only those with a status
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int, status string, page, perPage int) (_ []models.WebhookDelivery, total int, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "ListDeliveries", attribute.Int("webhook.id", webhookID), attribute.Int("page", page))
+	defer tracing.End(span, &err)
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1)`, webhookID).Scan(&exists); err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !exists {
+		return nil, 0, ErrWebhookNotFound
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1 AND ($2 = '' OR status = $2)`,
+		webhookID, status,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		 WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $3 OFFSET $4`,
+		webhookID, status, perPage, (page-1)*perPage,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// Redeliver queues a new delivery of the same event to the same webhook.
+// The original stays in the history unchanged.
+func (r *WebhookRepository) Redeliver(ctx context.Context, deliveryID int) (_ *models.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "Redeliver", attribute.Int("webhook.delivery.id", deliveryID))
+	defer tracing.End(span, &err)
+
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		 SELECT webhook_id, event, payload FROM webhook_deliveries WHERE id = $1
+		 RETURNING `+deliveryColumns,
+		deliveryID,
+	)
+	delivery, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ClaimDeliveries takes up to limit due deliveries for sending. Each is
+// leased: if it is not completed or failed within lease, it becomes due again
+// and another replica may claim it.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []PendingDelivery, err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "ClaimDeliveries", attribute.Int("limit", limit))
+	defer tracing.End(span, &err)
+
+	rows, err := r.db.QueryContext(ctx,
+		`UPDATE webhook_deliveries d
+		 SET next_attempt_at = NOW() + make_interval(secs => $2)
+		 FROM webhooks w
+		 WHERE w.id = d.webhook_id AND d.id IN (
+		     SELECT id FROM webhook_deliveries
+		     WHERE status = 'pending' AND next_attempt_at <= NOW()
+		     ORDER BY next_attempt_at
+		     LIMIT $1
+		     FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING d.id, w.url, w.secret, d.event, d.payload, d.attempts`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		if err := rows.Scan(&d.ID, &d.URL, &d.Secret, &d.Event, &d.Payload, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		claimed = append(claimed, d)
+	}
+	return claimed, rows.Err()
+}
+
+// CompleteDelivery records a successful attempt
+func (r *WebhookRepository) CompleteDelivery(ctx context.Context, id, responseStatus int) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "CompleteDelivery", attribute.Int("webhook.delivery.id", id))
+	defer tracing.End(span, &err)
+
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = 'succeeded', attempts = attempts + 1, response_status = $2, error = NULL, delivered_at = NOW()
+		 WHERE id = $1`,
+		id, responseStatus,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// FailDelivery records a failed attempt. The delivery is retried after
+// retryIn, or marked failed when retryIn is 0. responseStatus is 0 when no
+// response was received.
+func (r *WebhookRepository) FailDelivery(ctx context.Context, id, responseStatus int, message string, retryIn time.Duration) (err error) {
+	ctx, span := startSpan(ctx, "WebhookRepository", "FailDelivery", attribute.Int("webhook.delivery.id", id))
+	defer tracing.End(span, &err)
+
+	status := models.DeliveryPending
+	if retryIn <= 0 {
+		status = models.DeliveryFailed
+	}
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2, attempts = attempts + 1, response_status = NULLIF($3, 0), error = $4,
+		     next_attempt_at = NOW() + make_interval(secs => $5)
+		 WHERE id = $1`,
+		id, status, responseStatus, message, retryIn.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery failure: %w", err)
+	}
+	return nil
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &d.ResponseStatus, &d.Error, &d.CreatedAt, &deliveredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	if err := json.Unmarshal(payload, &d.Payload); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook payload: %w", err)
+	}
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
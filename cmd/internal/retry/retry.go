@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// Policy describes how often and how long to retry
+type Policy struct {
+	Attempts       int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Do calls fn until it succeeds, the attempts run out or ctx is done.
+// The wait doubles after each failure, capped at MaxBackoff, with up to 50%
+// random jitter so replicas starting together do not retry in lockstep.
+func Do(ctx context.Context, name string, policy Policy, fn func() error) error {
+	backoff := policy.InitialBackoff
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= policy.Attempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", name, attempt, err)
+		}
+
+		wait := backoff
+		if wait > 0 {
+			wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+		}
+		slog.WarnContext(ctx, "dependency not available, retrying",
+			slog.String("dependency", name),
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", policy.Attempts),
+			slog.Duration("retry_in", wait.Round(time.Millisecond)),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w (last error: %v)", name, ctx.Err(), err)
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
@@ -5,105 +5,308 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/hungpv1995/golang_training_2025/internal/language"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
 	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/search")
+
 type ElasticSearch struct {
 	client *elasticsearch.Client
-	ctx    context.Context
+	index  IndexConfig
+	logger *slog.Logger
 }
 
-func NewElasticSearch(client *elasticsearch.Client) *ElasticSearch {
+func NewElasticSearch(client *elasticsearch.Client, index IndexConfig, logger *slog.Logger) *ElasticSearch {
 	return &ElasticSearch{
 		client: client,
-		ctx:    context.Background(),
+		index:  index,
+		logger: logger,
 	}
 }
 
-// CreateIndex creates the posts index with proper mapping
-func (es *ElasticSearch) CreateIndex() error {
-	mapping := `{
-		"mappings": {
-			"properties": {
-				"id": {"type": "integer"},
-				"title": {"type": "text"},
-				"content": {"type": "text"},
-				"tags": {"type": "keyword"},
-				"created_at": {"type": "date"}
-			}
-		}
-	}`
-
-	req := esapi.IndicesCreateRequest{
-		Index: "posts",
-		Body:  strings.NewReader(mapping),
-	}
+// Health checks that the cluster is reachable and not red
+func (es *ElasticSearch) Health(ctx context.Context) (err error) {
+	ctx, done := start(ctx, "health")
+	defer done(&err)
 
-	res, err := req.Do(es.ctx, es.client)
+	res, err := es.client.Cluster.Health(
+		es.client.Cluster.Health.WithContext(ctx),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to create index: %w", err)
+		return fmt.Errorf("failed to get cluster health: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.IsError() && !strings.Contains(res.String(), "resource_already_exists_exception") {
-		return fmt.Errorf("error creating index: %s", res.String())
+	if res.IsError() {
+		return fmt.Errorf("cluster health error: %s", res.String())
+	}
+
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return fmt.Errorf("failed to parse cluster health: %w", err)
+	}
+	if health.Status == "red" {
+		return fmt.Errorf("cluster status is red")
 	}
 
 	return nil
 }
 
 // IndexPost indexes a post in Elasticsearch
-func (es *ElasticSearch) IndexPost(post *models.Post) error {
+func (es *ElasticSearch) IndexPost(ctx context.Context, post *models.Post) (err error) {
+	ctx, done := start(ctx, "index")
+	defer done(&err)
+
 	docID := strconv.Itoa(post.ID)
+	docJSON, err := json.Marshal(document(post))
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	req := esapi.IndexRequest{
+		Index:      Alias,
+		DocumentID: docID,
+		Body:       bytes.NewReader(docJSON),
+		Refresh:    "true",
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to index document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error indexing document: %s", res.String())
+	}
+
+	es.logger.DebugContext(ctx, "document indexed", slog.String("index", Alias), slog.String("document_id", docID))
+	return nil
+}
 
-	doc := map[string]interface{}{
+// document is the indexed form of a post, following properties()
+func document(post *models.Post) map[string]interface{} {
+	return map[string]interface{}{
 		"id":         post.ID,
 		"title":      post.Title,
 		"content":    post.Content,
 		"tags":       post.Tags,
+		"language":   documentLanguage(post),
 		"created_at": post.CreatedAt,
+		// Boosts popular posts in SearchPosts
+		"reaction_count": models.TotalReactions(post.Reactions),
 	}
+}
 
-	docJSON, err := json.Marshal(doc)
-	if err != nil {
-		return fmt.Errorf("failed to marshal document: %w", err)
+// Snapshot returns the fields of post that GetDocuments reads back, as
+// IndexPost would index them
+func Snapshot(post *models.Post) models.PostSnapshot {
+	return models.PostSnapshot{
+		Title:         post.Title,
+		Content:       post.Content,
+		Tags:          post.Tags,
+		Language:      documentLanguage(post),
+		ReactionCount: models.TotalReactions(post.Reactions),
 	}
+}
 
-	req := esapi.IndexRequest{
-		Index:      "posts",
+// documentLanguage is the post's language, detected for posts without a valid one
+func documentLanguage(post *models.Post) string {
+	if language.Valid(post.Language) {
+		return post.Language
+	}
+	return language.Detect(post.Title + " " + post.Content)
+}
+
+// DeletePost removes a post from the index; a post that is not indexed is
+// not an error
+func (es *ElasticSearch) DeletePost(ctx context.Context, postID int) (err error) {
+	ctx, done := start(ctx, "delete")
+	defer done(&err)
+
+	docID := strconv.Itoa(postID)
+	req := esapi.DeleteRequest{
+		Index:      Alias,
 		DocumentID: docID,
-		Body:       bytes.NewReader(docJSON),
 		Refresh:    "true",
 	}
 
-	res, err := req.Do(es.ctx, es.client)
+	res, err := req.Do(ctx, es.client)
 	if err != nil {
-		return fmt.Errorf("failed to index document: %w", err)
+		return fmt.Errorf("failed to delete document: %w", err)
 	}
 	defer res.Body.Close()
 
-	if res.IsError() {
-		return fmt.Errorf("error indexing document: %s", res.String())
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("error deleting document: %s", res.String())
 	}
 
-	log.Printf("Document indexed successfully: %s", docID)
+	es.logger.DebugContext(ctx, "document deleted", slog.String("index", Alias), slog.String("document_id", docID))
 	return nil
 }
 
+// GetDocuments returns the title, content, tags, language and reaction count
+// indexed for each of ids, keyed by post ID; posts that are not indexed are
+// left out
+func (es *ElasticSearch) GetDocuments(ctx context.Context, ids []int) (docs map[int]models.PostSnapshot, err error) {
+	ctx, done := start(ctx, "mget")
+	defer done(&err)
+
+	docIDs := make([]string, len(ids))
+	for i, id := range ids {
+		docIDs[i] = strconv.Itoa(id)
+	}
+	body, err := json.Marshal(map[string]interface{}{"ids": docIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document ids: %w", err)
+	}
+
+	res, err := es.client.Mget(bytes.NewReader(body),
+		es.client.Mget.WithContext(ctx),
+		es.client.Mget.WithIndex(Alias),
+		es.client.Mget.WithSourceIncludes("title", "content", "tags", "language", "reaction_count"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting documents: %s", res.String())
+	}
+
+	var result struct {
+		Docs []struct {
+			ID     string              `json:"_id"`
+			Found  bool                `json:"found"`
+			Source models.PostSnapshot `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	docs = make(map[int]models.PostSnapshot, len(result.Docs))
+	for _, doc := range result.Docs {
+		id, err := strconv.Atoi(doc.ID)
+		if err != nil || !doc.Found {
+			continue
+		}
+		docs[id] = doc.Source
+	}
+	return docs, nil
+}
+
+// DocumentIDsAfter returns up to size IDs of indexed posts above afterID and
+// at most upTo, in ID order; upTo 0 means no upper bound
+func (es *ElasticSearch) DocumentIDsAfter(ctx context.Context, afterID, upTo, size int) (ids []int, err error) {
+	ctx, done := start(ctx, "list_ids")
+	defer done(&err)
+
+	bounds := map[string]interface{}{"gt": afterID}
+	if upTo > 0 {
+		bounds["lte"] = upTo
+	}
+	query := map[string]interface{}{
+		"query":   map[string]interface{}{"range": map[string]interface{}{"id": bounds}},
+		"sort":    []interface{}{map[string]interface{}{"id": "asc"}},
+		"size":    size,
+		"_source": false,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	res, err := es.client.Search(
+		es.client.Search.WithContext(ctx),
+		es.client.Search.WithIndex(Alias),
+		es.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document ids: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error listing document ids: %s", res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	for _, hit := range result.Hits.Hits {
+		if id, err := strconv.Atoi(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SearchOptions narrows and tunes SearchPosts
+type SearchOptions struct {
+	// Lang is the language of the query; its subfields stem or keep the
+	// diacritics of the query words. Detected from the query when empty.
+	Lang string
+	// Language keeps only posts written in it; empty searches every post
+	Language string
+}
+
 // SearchPosts performs full-text search on posts
-func (es *ElasticSearch) SearchPosts(query string) ([]map[string]interface{}, error) {
-	// Build the search query
+func (es *ElasticSearch) SearchPosts(ctx context.Context, query *Query, opts SearchOptions) (posts []map[string]interface{}, err error) {
+	ctx, done := start(ctx, "search")
+	defer done(&err)
+
+	lang := opts.Lang
+	if lang == "" {
+		lang = language.Detect(query.String())
+	}
+	filtered := query.source(lang)
+	if opts.Language != "" {
+		filtered = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":   filtered,
+				"filter": map[string]interface{}{"term": map[string]interface{}{"language": opts.Language}},
+			},
+		}
+	}
+
+	// Build the search query. Relevance is multiplied by log10(2 + reactions),
+	// so popular posts rank higher without drowning out better matches.
 	searchQuery := map[string]interface{}{
 		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  query,
-				"fields": []string{"title", "content"},
+			"function_score": map[string]interface{}{
+				"query": filtered,
+				"field_value_factor": map[string]interface{}{
+					"field":    "reaction_count",
+					"modifier": "log2p",
+					"missing":  0,
+				},
+				"boost_mode": "multiply",
 			},
 		},
 	}
@@ -115,8 +318,8 @@ func (es *ElasticSearch) SearchPosts(query string) ([]map[string]interface{}, er
 
 	// Perform search
 	res, err := es.client.Search(
-		es.client.Search.WithContext(es.ctx),
-		es.client.Search.WithIndex("posts"),
+		es.client.Search.WithContext(ctx),
+		es.client.Search.WithIndex(Alias),
 		es.client.Search.WithBody(&buf),
 		es.client.Search.WithTrackTotalHits(true),
 	)
@@ -136,7 +339,6 @@ func (es *ElasticSearch) SearchPosts(query string) ([]map[string]interface{}, er
 	}
 
 	// Extract hits
-	var posts []map[string]interface{}
 	if hits, ok := result["hits"].(map[string]interface{}); ok {
 		if hitsArray, ok := hits["hits"].([]interface{}); ok {
 			for _, hit := range hitsArray {
@@ -153,12 +355,25 @@ func (es *ElasticSearch) SearchPosts(query string) ([]map[string]interface{}, er
 	return posts, nil
 }
 
-// GetRelatedPosts finds posts with similar tags
-func (es *ElasticSearch) GetRelatedPosts(currentPostID int, tags []string) []models.Related {
+// GetRelatedPosts finds posts with similar tags. Related posts are optional,
+// so failures are logged and an empty list is returned.
+func (es *ElasticSearch) GetRelatedPosts(ctx context.Context, currentPostID int, tags []string) []models.Related {
 	if len(tags) == 0 {
 		return []models.Related{}
 	}
 
+	relatedPosts, err := es.relatedPosts(ctx, currentPostID, tags)
+	if err != nil {
+		es.logger.WarnContext(ctx, "related posts search failed", slog.Int("post_id", currentPostID), slog.Any("error", err))
+		return []models.Related{}
+	}
+	return relatedPosts
+}
+
+func (es *ElasticSearch) relatedPosts(ctx context.Context, currentPostID int, tags []string) (relatedPosts []models.Related, err error) {
+	ctx, done := start(ctx, "related")
+	defer done(&err)
+
 	// Build Elasticsearch query for related posts
 	shouldClauses := []map[string]interface{}{}
 	for _, tag := range tags {
@@ -186,33 +401,28 @@ func (es *ElasticSearch) GetRelatedPosts(currentPostID int, tags []string) []mod
 
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(searchQuery); err != nil {
-		log.Printf("Failed to encode related posts query: %v", err)
-		return []models.Related{}
+		return nil, fmt.Errorf("failed to encode related posts query: %w", err)
 	}
 
 	res, err := es.client.Search(
-		es.client.Search.WithContext(es.ctx),
-		es.client.Search.WithIndex("posts"),
+		es.client.Search.WithContext(ctx),
+		es.client.Search.WithIndex(Alias),
 		es.client.Search.WithBody(&buf),
 	)
 	if err != nil {
-		log.Printf("Error searching related posts: %v", err)
-		return []models.Related{}
+		return nil, fmt.Errorf("failed to search related posts: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		log.Printf("Error searching related posts: %s", res.String())
-		return []models.Related{}
+		return nil, fmt.Errorf("related posts search error: %s", res.String())
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		log.Printf("Failed to parse related posts response: %v", err)
-		return []models.Related{}
+		return nil, fmt.Errorf("failed to parse related posts response: %w", err)
 	}
 
-	var relatedPosts []models.Related
 	if hits, ok := result["hits"].(map[string]interface{}); ok {
 		if hitsArray, ok := hits["hits"].([]interface{}); ok {
 			for _, hit := range hitsArray {
@@ -238,5 +448,23 @@ func (es *ElasticSearch) GetRelatedPosts(currentPostID int, tags []string) []mod
 		}
 	}
 
-	return relatedPosts
+	return relatedPosts, nil
+}
+
+// start begins a span for one Elasticsearch operation. The returned function
+// ends the span and records the operation's latency and failure metrics.
+func start(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	began := time.Now()
+	ctx, span := tracer.Start(ctx, "elasticsearch."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemElasticsearch, semconv.DBOperation(operation)),
+	)
+
+	return ctx, func(err *error) {
+		metrics.SearchDuration.WithLabelValues(operation).Observe(time.Since(began).Seconds())
+		if *err != nil {
+			metrics.SearchFailures.WithLabelValues(operation).Inc()
+		}
+		tracing.End(span, err)
+	}
 }
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/hungpv1995/golang_training_2025/internal/language"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+func TestSnapshotMatchesDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		post models.Post
+		lang string
+	}{
+		{name: "tagged", post: models.Post{ID: 1, Title: "Hello", Content: "World", Tags: []string{"go"}, Language: language.Vietnamese}, lang: language.Vietnamese},
+		{name: "detected", post: models.Post{ID: 2, Title: "Phở bò", Content: "Món ăn sáng của người Hà Nội"}, lang: language.Vietnamese},
+		{name: "invalid falls back to detection", post: models.Post{ID: 3, Title: "Hello", Content: "World", Language: "xx"}, lang: language.English},
+	}
+	for _, tt := range tests {
+		tt.post.Reactions = map[string]int{"like": 2, "love": 1}
+		snapshot := Snapshot(&tt.post)
+		doc := document(&tt.post)
+
+		if snapshot.Language != tt.lang || doc["language"] != tt.lang {
+			t.Errorf("%s: language %q in snapshot, %v in document, want %q", tt.name, snapshot.Language, doc["language"], tt.lang)
+		}
+		if snapshot.ReactionCount != 3 || doc["reaction_count"] != 3 {
+			t.Errorf("%s: reaction count %d in snapshot, %v in document, want 3", tt.name, snapshot.ReactionCount, doc["reaction_count"])
+		}
+		if snapshot.Title != doc["title"] || snapshot.Content != doc["content"] {
+			t.Errorf("%s: snapshot %+v does not match document %v", tt.name, snapshot, doc)
+		}
+	}
+}
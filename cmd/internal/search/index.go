@@ -0,0 +1,493 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/hungpv1995/golang_training_2025/internal/language"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+)
+
+// Alias is the name every search and write goes through. It points at one
+// versioned index such as posts-v1-20250315093000.
+const Alias = "posts"
+
+// MappingVersion is the version of the index template below. Bump it with
+// every change to the settings or mappings; indices built from an older
+// version are reported at startup and migrated with Rebuild.
+//
+// Version 2 added the language field and the per-language subfields.
+// Version 3 folds accents in the vi subfields too, keeping the original tokens.
+const MappingVersion = 3
+
+// templateName is the index template applied to every versioned index
+const templateName = "posts"
+
+// IndexConfig holds the settings of new indices
+type IndexConfig struct {
+	Shards   int
+	Replicas int
+}
+
+// DefaultIndexConfig returns the index settings used when nothing is configured
+func DefaultIndexConfig() IndexConfig {
+	return IndexConfig{
+		Shards:   1,
+		Replicas: 1,
+	}
+}
+
+// IndexStatus describes the index behind the alias
+type IndexStatus struct {
+	// Index is the index searched, "" when there is none yet
+	Index string
+	// Legacy is set when Index is a plain index named like the alias, created
+	// before indices were versioned
+	Legacy bool
+	// Version is the mapping version recorded in the index, 0 when unknown
+	Version int
+	// Differences lists fields whose live mapping differs from the template
+	Differences []string
+}
+
+// Current reports whether the index matches the template of this release
+func (s IndexStatus) Current() bool {
+	return s.Index != "" && !s.Legacy && s.Version == MappingVersion && len(s.Differences) == 0
+}
+
+// analysis defines the analyzers used by the text fields
+func analysis() map[string]interface{} {
+	return map[string]interface{}{
+		"analyzer": map[string]interface{}{
+			// Folds accents so "cafe" finds "café"
+			"post_text": map[string]interface{}{
+				"type":      "custom",
+				"tokenizer": "standard",
+				"filter":    []string{"lowercase", "asciifolding"},
+			},
+			// Keeps the diacritics that tell Vietnamese syllables apart next
+			// to the folded tokens, so "bàn" ranks above "bán" for a query of
+			// "bàn" while "ha noi" still finds "Hà Nội"
+			"vietnamese_text": map[string]interface{}{
+				"type":      "custom",
+				"tokenizer": "standard",
+				"filter":    []string{"lowercase", "vietnamese_folding"},
+			},
+		},
+		"filter": map[string]interface{}{
+			"vietnamese_folding": map[string]interface{}{
+				"type":              "asciifolding",
+				"preserve_original": true,
+			},
+		},
+	}
+}
+
+// textField maps a text field searched in every language through the folded
+// field itself and in one language through the subfield named after it
+func textField() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "text",
+		"analyzer": "post_text",
+		"fields": map[string]interface{}{
+			language.English:    map[string]interface{}{"type": "text", "analyzer": "english"},
+			language.Vietnamese: map[string]interface{}{"type": "text", "analyzer": "vietnamese_text"},
+		},
+	}
+}
+
+// properties is the mapping of a post document, written by document
+func properties() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             map[string]interface{}{"type": "integer"},
+		"title":          textField(),
+		"content":        textField(),
+		"tags":           map[string]interface{}{"type": "keyword"},
+		"language":       map[string]interface{}{"type": "keyword"},
+		"created_at":     map[string]interface{}{"type": "date"},
+		"reaction_count": map[string]interface{}{"type": "integer"},
+	}
+}
+
+// template is the body of the index template for versioned indices
+func (es *ElasticSearch) template() map[string]interface{} {
+	return map[string]interface{}{
+		"index_patterns": []string{Alias + "-v*"},
+		"version":        MappingVersion,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"number_of_shards":   es.index.Shards,
+				"number_of_replicas": es.index.Replicas,
+				"analysis":           analysis(),
+			},
+			"mappings": map[string]interface{}{
+				"_meta":      map[string]interface{}{"version": MappingVersion},
+				"properties": properties(),
+			},
+		},
+	}
+}
+
+// EnsureIndex installs the index template and creates the first index behind
+// the alias when there is none. The status of the index in use is returned
+// so callers can report one that needs a Rebuild.
+func (es *ElasticSearch) EnsureIndex(ctx context.Context) (status IndexStatus, err error) {
+	ctx, done := start(ctx, "ensure_index")
+	defer done(&err)
+
+	if err := es.putTemplate(ctx); err != nil {
+		return status, err
+	}
+	if status, err = es.indexStatus(ctx); err != nil || status.Index != "" {
+		return status, err
+	}
+
+	name, err := es.createIndex(ctx, true)
+	if err != nil {
+		return status, err
+	}
+	return IndexStatus{Index: name, Version: MappingVersion}, nil
+}
+
+// Status returns the status of the index behind the alias
+func (es *ElasticSearch) Status(ctx context.Context) (status IndexStatus, err error) {
+	ctx, done := start(ctx, "index_status")
+	defer done(&err)
+
+	return es.indexStatus(ctx)
+}
+
+// NewIndex creates an empty index with the current template, not yet behind
+// the alias, and returns its name
+func (es *ElasticSearch) NewIndex(ctx context.Context) (name string, err error) {
+	ctx, done := start(ctx, "new_index")
+	defer done(&err)
+
+	if err := es.putTemplate(ctx); err != nil {
+		return "", err
+	}
+	return es.createIndex(ctx, false)
+}
+
+// BulkIndex writes posts to index, which may be the alias, in one request
+func (es *ElasticSearch) BulkIndex(ctx context.Context, index string, posts []*models.Post) (err error) {
+	ctx, done := start(ctx, "bulk")
+	defer done(&err)
+
+	if len(posts) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, post := range posts {
+		action := map[string]interface{}{"index": map[string]interface{}{"_id": fmt.Sprint(post.ID)}}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := encoder.Encode(document(post)); err != nil {
+			return fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+
+	req := esapi.BulkRequest{Index: index, Body: &body}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to bulk index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error bulk indexing: %s", res.String())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string          `json:"_id"`
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, r := range item {
+				if len(r.Error) > 0 {
+					return fmt.Errorf("error indexing document %s: %s", r.ID, r.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SwapAlias points the alias at index alone, in one atomic step, and returns
+// the indices it pointed at before. A legacy index named like the alias is
+// deleted, since both cannot exist; versioned indices are kept.
+func (es *ElasticSearch) SwapAlias(ctx context.Context, index string) (previous []string, err error) {
+	ctx, done := start(ctx, "swap_alias")
+	defer done(&err)
+
+	current, err := es.aliasedIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []interface{}{
+		map[string]interface{}{"add": map[string]interface{}{"index": index, "alias": Alias, "is_write_index": true}},
+	}
+	for _, name := range current {
+		if name != index {
+			actions = append(actions, map[string]interface{}{"remove": map[string]interface{}{"index": name, "alias": Alias}})
+			previous = append(previous, name)
+		}
+	}
+	if len(current) == 0 {
+		exists, err := es.indexExists(ctx, Alias)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			actions = append(actions, map[string]interface{}{"remove_index": map[string]interface{}{"index": Alias}})
+			previous = append(previous, Alias)
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode alias actions: %w", err)
+	}
+	req := esapi.IndicesUpdateAliasesRequest{Body: bytes.NewReader(body)}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error updating alias: %s", res.String())
+	}
+	return previous, nil
+}
+
+// putTemplate installs the template unless a newer release already did, so
+// old replicas in a rolling deploy do not downgrade it
+func (es *ElasticSearch) putTemplate(ctx context.Context) error {
+	res, err := es.client.Indices.GetIndexTemplate(
+		es.client.Indices.GetIndexTemplate.WithContext(ctx),
+		es.client.Indices.GetIndexTemplate.WithName(templateName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+	case res.IsError():
+		return fmt.Errorf("error getting index template: %s", res.String())
+	default:
+		var existing struct {
+			IndexTemplates []struct {
+				IndexTemplate struct {
+					Version int `json:"version"`
+				} `json:"index_template"`
+			} `json:"index_templates"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&existing); err != nil {
+			return fmt.Errorf("failed to parse index template: %w", err)
+		}
+		if len(existing.IndexTemplates) > 0 && existing.IndexTemplates[0].IndexTemplate.Version >= MappingVersion {
+			return nil
+		}
+	}
+
+	body, err := json.Marshal(es.template())
+	if err != nil {
+		return fmt.Errorf("failed to encode index template: %w", err)
+	}
+	req := esapi.IndicesPutIndexTemplateRequest{Name: templateName, Body: bytes.NewReader(body)}
+	put, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to put index template: %w", err)
+	}
+	defer put.Body.Close()
+
+	if put.IsError() {
+		return fmt.Errorf("error putting index template: %s", put.String())
+	}
+	return nil
+}
+
+// createIndex creates a versioned index, behind the alias when aliased is set
+func (es *ElasticSearch) createIndex(ctx context.Context, aliased bool) (string, error) {
+	name := fmt.Sprintf("%s-v%d-%s", Alias, MappingVersion, time.Now().UTC().Format("20060102150405"))
+	var body io.Reader
+	if aliased {
+		body = strings.NewReader(fmt.Sprintf(`{"aliases": {%q: {"is_write_index": true}}}`, Alias))
+	}
+
+	req := esapi.IndicesCreateRequest{Index: name, Body: body}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return "", fmt.Errorf("failed to create index: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", fmt.Errorf("error creating index: %s", res.String())
+	}
+
+	es.logger.InfoContext(ctx, "search index created", slog.String("index", name), slog.Int("mapping_version", MappingVersion))
+	return name, nil
+}
+
+func (es *ElasticSearch) indexStatus(ctx context.Context) (IndexStatus, error) {
+	var status IndexStatus
+	indices, err := es.aliasedIndices(ctx)
+	if err != nil {
+		return status, err
+	}
+	switch {
+	case len(indices) > 0:
+		status.Index = indices[0]
+	default:
+		exists, err := es.indexExists(ctx, Alias)
+		if err != nil || !exists {
+			return status, err
+		}
+		status.Index, status.Legacy = Alias, true
+	}
+
+	res, err := es.client.Indices.GetMapping(
+		es.client.Indices.GetMapping.WithContext(ctx),
+		es.client.Indices.GetMapping.WithIndex(status.Index),
+	)
+	if err != nil {
+		return status, fmt.Errorf("failed to get mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return status, fmt.Errorf("error getting mapping: %s", res.String())
+	}
+
+	var result map[string]struct {
+		Mappings struct {
+			Meta struct {
+				Version int `json:"version"`
+			} `json:"_meta"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return status, fmt.Errorf("failed to parse mapping: %w", err)
+	}
+	mapping := result[status.Index].Mappings
+	status.Version = mapping.Meta.Version
+	status.Differences = diffMapping("", properties(), mapping.Properties)
+	return status, nil
+}
+
+// aliasedIndices returns the indices behind the alias, the write index first
+func (es *ElasticSearch) aliasedIndices(ctx context.Context) ([]string, error) {
+	req := esapi.IndicesGetAliasRequest{Name: []string{Alias}}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting alias: %s", res.String())
+	}
+
+	var result map[string]struct {
+		Aliases map[string]struct {
+			IsWriteIndex bool `json:"is_write_index"`
+		} `json:"aliases"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse alias: %w", err)
+	}
+
+	var indices []string
+	for name, index := range result {
+		if index.Aliases[Alias].IsWriteIndex {
+			indices = append([]string{name}, indices...)
+		} else {
+			indices = append(indices, name)
+		}
+	}
+	if len(indices) > 1 {
+		slices.Sort(indices[1:])
+	}
+	return indices, nil
+}
+
+func (es *ElasticSearch) indexExists(ctx context.Context, name string) (bool, error) {
+	req := esapi.IndicesExistsRequest{Index: []string{name}}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return false, fmt.Errorf("failed to check index: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("error checking index: %s", res.String())
+	}
+}
+
+// diffMapping compares the type and analyzers of each expected field, and of
+// its multi-fields, with the live mapping
+func diffMapping(prefix string, expected, live map[string]interface{}) []string {
+	var diffs []string
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		want, _ := expected[name].(map[string]interface{})
+		got, ok := live[name].(map[string]interface{})
+		if !ok {
+			diffs = append(diffs, prefix+name+" is not mapped")
+			continue
+		}
+		for _, key := range []string{"type", "analyzer", "search_analyzer", "normalizer"} {
+			w, ok := want[key]
+			if !ok || fmt.Sprint(got[key]) == fmt.Sprint(w) {
+				continue
+			}
+			have, set := got[key]
+			if !set {
+				have = "default"
+			}
+			diffs = append(diffs, fmt.Sprintf("%s%s has %s %v, expected %v", prefix, name, key, have, w))
+		}
+		if fields, ok := want["fields"].(map[string]interface{}); ok {
+			liveFields, _ := got["fields"].(map[string]interface{})
+			diffs = append(diffs, diffMapping(prefix+name+".", fields, liveFields)...)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDiffMapping(t *testing.T) {
+	// The live mapping as returned by GET /<index>/_mapping, where ES leaves
+	// out the default analyzer
+	current := `{
+		"id": {"type": "integer"},
+		"title": {"type": "text", "analyzer": "post_text", "fields": {"en": {"type": "text", "analyzer": "english"}, "vi": {"type": "text", "analyzer": "vietnamese_text"}}},
+		"content": {"type": "text", "analyzer": "post_text", "fields": {"en": {"type": "text", "analyzer": "english"}, "vi": {"type": "text", "analyzer": "vietnamese_text"}}},
+		"tags": {"type": "keyword"},
+		"language": {"type": "keyword"},
+		"created_at": {"type": "date"},
+		"reaction_count": {"type": "integer"}
+	}`
+	stale := `{
+		"id": {"type": "integer"},
+		"title": {"type": "text", "fields": {"en": {"type": "text", "analyzer": "english"}}},
+		"content": {"type": "text", "analyzer": "post_text", "fields": {"en": {"type": "text", "analyzer": "english"}, "vi": {"type": "text", "analyzer": "vietnamese_text"}}},
+		"tags": {"type": "text"},
+		"created_at": {"type": "date"},
+		"reaction_count": {"type": "long"}
+	}`
+
+	tests := []struct {
+		name string
+		live string
+		want []string
+	}{
+		{name: "current", live: current},
+		{name: "stale", live: stale, want: []string{
+			"language is not mapped",
+			"reaction_count has type long, expected integer",
+			"tags has type text, expected keyword",
+			"title has analyzer default, expected post_text",
+			"title.vi is not mapped",
+		}},
+	}
+	for _, tt := range tests {
+		var live map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.live), &live); err != nil {
+			t.Fatal(err)
+		}
+		got := diffMapping("", properties(), live)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: diffMapping() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits that keep a single search cheap
+const (
+	maxQueryLength = 500
+	maxQueryTerms  = 30
+	maxQueryDepth  = 5
+	// minPrefixLength is the shortest word a trailing * may complete
+	minPrefixLength = 3
+	// maxPrefixExpansions caps the terms a prefix expands to
+	maxPrefixExpansions = 50
+)
+
+// dateLayout is the format of before: and after: dates
+const dateLayout = "2006-01-02"
+
+// queryFields are the field: prefixes a query may use
+var queryFields = []string{"title", "content", "tag", "before", "after"}
+
+// SyntaxError is a query that cannot be parsed. Position is the 1-based
+// character offset of the problem.
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+// Query is a parsed search query. Terms separated by spaces must all match;
+// the syntax is
+//
+//	word          the word, in any field, accents folded
+//	"a phrase"    the words next to each other
+//	prog*         words starting with prog, at least 3 characters before *
+//	-term         posts without term; works on every form below
+//	a OR b        either; OR binds tighter than the spaces around it
+//	(a b) OR c    groups
+//	title:word    title:"a phrase" content:word
+//	tag:golang    the exact tag
+//	after:2024-01-31 before:2024-03-01
+//	              created on or after, and before, a day (UTC)
+//
+// Wildcards elsewhere in a word and regular expressions are rejected, so a
+// query never expands to more than a few terms.
+type Query struct {
+	text string
+	root *queryNode
+}
+
+type nodeKind int
+
+const (
+	nodeAll nodeKind = iota
+	nodeAny
+	nodeNot
+	nodeWord
+	nodePhrase
+	nodePrefix
+	nodeTag
+	nodeBefore
+	nodeAfter
+)
+
+// queryNode is a term or a group of the parsed query. field is "title" or
+// "content" for terms limited to one field.
+type queryNode struct {
+	kind     nodeKind
+	field    string
+	value    string
+	children []*queryNode
+}
+
+// ParseQuery parses q. Errors are *SyntaxError.
+func ParseQuery(q string) (*Query, error) {
+	runes := []rune(q)
+	if len(runes) > maxQueryLength {
+		return nil, &SyntaxError{Position: maxQueryLength + 1, Message: fmt.Sprintf("query is longer than %d characters", maxQueryLength)}
+	}
+	tokens, err := lex(runes)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseAll(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Position: t.pos, Message: `unexpected ")"`}
+	}
+	return &Query{text: q, root: root}, nil
+}
+
+// String returns the query as written
+func (q *Query) String() string {
+	return q.text
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenField
+	tokenNot
+	tokenOr
+	tokenOpen
+	tokenClose
+)
+
+// token is a lexed piece of the query; pos is 1-based. Field tokens carry
+// the field name in field and whether the value was quoted in phrase.
+type token struct {
+	kind   tokenKind
+	pos    int
+	text   string
+	field  string
+	phrase bool
+}
+
+// lex splits a query into tokens
+func lex(runes []rune) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, pos: i + 1})
+			i++
+		case r == '"':
+			text, next, err := lexPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, pos: i + 1, text: text})
+			i = next
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			// A hyphen inside a word, as in e-mail, is part of the word
+			tokens = append(tokens, token{kind: tokenNot, pos: i + 1})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			if !searchable(word) {
+				// No analyzer keeps a word such as "&"; searching for it
+				// would match nothing
+				continue
+			}
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokenOr, pos: start + 1})
+				continue
+			}
+			field, value, ok := strings.Cut(word, ":")
+			if !ok || !isFieldName(field) {
+				tokens = append(tokens, token{kind: tokenWord, pos: start + 1, text: word})
+				continue
+			}
+			t := token{kind: tokenField, pos: start + 1, field: strings.ToLower(field), text: value}
+			if value == "" {
+				if i == len(runes) || runes[i] != '"' {
+					return nil, &SyntaxError{Position: start + 1, Message: fmt.Sprintf("missing value after %s:", field)}
+				}
+				text, next, err := lexPhrase(runes, i)
+				if err != nil {
+					return nil, err
+				}
+				t.text, t.phrase = text, true
+				i = next
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// lexPhrase reads the quoted phrase starting at runes[start] and returns it
+// with the index after its closing quote
+func lexPhrase(runes []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] != '"' {
+			continue
+		}
+		text := strings.TrimSpace(string(runes[start+1 : end]))
+		if text == "" {
+			return "", 0, &SyntaxError{Position: start + 1, Message: "empty phrase"}
+		}
+		return text, end + 1, nil
+	}
+	return "", 0, &SyntaxError{Position: start + 1, Message: "unterminated phrase"}
+}
+
+// searchable reports whether word has a letter or digit to search for, or a
+// * to be rejected if misplaced
+func searchable(word string) bool {
+	return strings.ContainsFunc(word, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '*'
+	})
+}
+
+// isFieldName reports whether s, the text before a colon, names a field.
+// Any run of letters counts, so that a misspelt field is reported rather
+// than searched for as a word.
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryParser builds the query tree from tokens:
+//
+//	all     = any { any }
+//	any     = unary { "OR" unary }
+//	unary   = "-" unary | primary
+//	primary = "(" all ")" | word | phrase | field
+type queryParser struct {
+	tokens []token
+	next   int
+	terms  int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *queryParser) parseAll(depth int) (*queryNode, error) {
+	all := &queryNode{kind: nodeAll}
+	for {
+		if t := p.peek(); t.kind == tokenEOF || t.kind == tokenClose {
+			break
+		}
+		child, err := p.parseAny(depth)
+		if err != nil {
+			return nil, err
+		}
+		all.children = append(all.children, child)
+	}
+	if len(all.children) == 0 {
+		return nil, &SyntaxError{Position: p.peek().pos, Message: "expected a search term"}
+	}
+	return all, nil
+}
+
+func (p *queryParser) parseAny(depth int) (*queryNode, error) {
+	first, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenOr {
+		return first, nil
+	}
+
+	group := &queryNode{kind: nodeAny, children: []*queryNode{first}}
+	for p.peek().kind == tokenOr {
+		or := p.advance()
+		if t := p.peek(); t.kind == tokenEOF || t.kind == tokenClose || t.kind == tokenOr {
+			return nil, &SyntaxError{Position: or.pos, Message: "OR must be followed by a search term"}
+		}
+		child, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		group.children = append(group.children, child)
+	}
+	return group, nil
+}
+
+func (p *queryParser) parseUnary(depth int) (*queryNode, error) {
+	if p.peek().kind != tokenNot {
+		return p.parsePrimary(depth)
+	}
+	p.advance()
+	child, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	return &queryNode{kind: nodeNot, children: []*queryNode{child}}, nil
+}
+
+func (p *queryParser) parsePrimary(depth int) (*queryNode, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenOpen:
+		if depth == maxQueryDepth {
+			return nil, &SyntaxError{Position: t.pos, Message: fmt.Sprintf("groups are nested more than %d deep", maxQueryDepth)}
+		}
+		group, err := p.parseAll(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.advance().kind != tokenClose {
+			return nil, &SyntaxError{Position: t.pos, Message: `missing ")"`}
+		}
+		if len(group.children) == 1 {
+			return group.children[0], nil
+		}
+		return group, nil
+	case tokenClose:
+		return nil, &SyntaxError{Position: t.pos, Message: `unexpected ")"`}
+	case tokenOr:
+		return nil, &SyntaxError{Position: t.pos, Message: "OR must be between two search terms"}
+	case tokenEOF:
+		return nil, &SyntaxError{Position: t.pos, Message: "expected a search term"}
+	}
+
+	p.terms++
+	if p.terms > maxQueryTerms {
+		return nil, &SyntaxError{Position: t.pos, Message: fmt.Sprintf("query has more than %d terms", maxQueryTerms)}
+	}
+	switch t.kind {
+	case tokenPhrase:
+		return &queryNode{kind: nodePhrase, value: t.text}, nil
+	case tokenWord:
+		return textNode("", t.text, t.pos)
+	}
+	return fieldNode(t)
+}
+
+// fieldNode is the term of a field: token
+func fieldNode(t token) (*queryNode, error) {
+	valuePos := t.pos + utf8.RuneCountInString(t.field) + 1
+	switch t.field {
+	case "title", "content":
+		if t.phrase {
+			return &queryNode{kind: nodePhrase, field: t.field, value: t.text}, nil
+		}
+		return textNode(t.field, t.text, valuePos)
+	case "tag":
+		if strings.Contains(t.text, "*") {
+			return nil, &SyntaxError{Position: valuePos, Message: "tags must match exactly; wildcards are not supported"}
+		}
+		return &queryNode{kind: nodeTag, value: t.text}, nil
+	case "before", "after":
+		if _, err := time.Parse(dateLayout, t.text); err != nil {
+			return nil, &SyntaxError{Position: valuePos, Message: fmt.Sprintf("%s: needs a date such as 2024-01-31", t.field)}
+		}
+		kind := nodeBefore
+		if t.field == "after" {
+			kind = nodeAfter
+		}
+		return &queryNode{kind: kind, value: t.text}, nil
+	}
+	return nil, &SyntaxError{Position: t.pos, Message: fmt.Sprintf("unknown field %q; use %s", t.field, strings.Join(queryFields, ", "))}
+}
+
+// textNode is a word, or a prefix when it ends with *. pos is where the word
+// starts.
+func textNode(field, word string, pos int) (*queryNode, error) {
+	if len(word) > 2 && strings.HasPrefix(word, "/") && strings.HasSuffix(word, "/") {
+		return nil, &SyntaxError{Position: pos, Message: "regular expressions are not supported"}
+	}
+	star := strings.IndexRune(word, '*')
+	if star < 0 {
+		return &queryNode{kind: nodeWord, field: field, value: word}, nil
+	}
+	prefix := word[:star]
+	if star != len(word)-1 || utf8.RuneCountInString(prefix) < minPrefixLength {
+		return nil, &SyntaxError{
+			Position: pos + utf8.RuneCountInString(prefix),
+			Message:  fmt.Sprintf("* may only end a word of at least %d characters", minPrefixLength),
+		}
+	}
+	return &queryNode{kind: nodePrefix, field: field, value: prefix}, nil
+}
+
+// source translates the query into Elasticsearch's query DSL. lang picks the
+// language subfields searched next to the folded fields.
+func (q *Query) source(lang string) map[string]interface{} {
+	return q.root.source(lang)
+}
+
+func (n *queryNode) source(lang string) map[string]interface{} {
+	switch n.kind {
+	case nodeAll:
+		var must, filter, mustNot []interface{}
+		for _, child := range n.children {
+			switch child.kind {
+			case nodeNot:
+				mustNot = append(mustNot, child.children[0].source(lang))
+			case nodeTag, nodeBefore, nodeAfter:
+				// Exact conditions do not affect relevance
+				filter = append(filter, child.source(lang))
+			default:
+				must = append(must, child.source(lang))
+			}
+		}
+		clauses := map[string]interface{}{}
+		if len(must) > 0 {
+			clauses["must"] = must
+		}
+		if len(filter) > 0 {
+			clauses["filter"] = filter
+		}
+		if len(mustNot) > 0 {
+			clauses["must_not"] = mustNot
+		}
+		return map[string]interface{}{"bool": clauses}
+	case nodeAny:
+		should := make([]interface{}, len(n.children))
+		for i, child := range n.children {
+			should[i] = child.source(lang)
+		}
+		return map[string]interface{}{
+			"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+		}
+	case nodeNot:
+		return map[string]interface{}{
+			"bool": map[string]interface{}{"must_not": n.children[0].source(lang)},
+		}
+	case nodeTag:
+		return map[string]interface{}{"term": map[string]interface{}{"tags": n.value}}
+	case nodeBefore:
+		return dateRange("lt", n.value)
+	case nodeAfter:
+		return dateRange("gte", n.value)
+	}
+
+	// The folded fields match across languages and accents, so "ha noi"
+	// finds "Hà Nội"; the subfields of the query language add the stemmed
+	// or accented matches on top.
+	fields := []string{"title", "content"}
+	if n.field != "" {
+		fields = []string{n.field}
+	}
+	for _, field := range fields {
+		fields = append(fields, field+"."+lang)
+	}
+	match := map[string]interface{}{
+		"query":  n.value,
+		"fields": fields,
+		// Stopwords have no terms in the en subfields, which then drop out
+		// rather than match every post and turn -the into a query for
+		// nothing; the folded fields keep every word the lexer lets through
+		"zero_terms_query": "none",
+	}
+	switch n.kind {
+	case nodePhrase:
+		match["type"] = "phrase"
+	case nodePrefix:
+		match["type"] = "phrase_prefix"
+		match["max_expansions"] = maxPrefixExpansions
+	default:
+		match["type"] = "most_fields"
+	}
+	return map[string]interface{}{"multi_match": match}
+}
+
+func dateRange(op, day string) map[string]interface{} {
+	return map[string]interface{}{
+		"range": map[string]interface{}{
+			"created_at": map[string]interface{}{op: day, "format": "yyyy-MM-dd"},
+		},
+	}
+}
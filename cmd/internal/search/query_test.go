@@ -0,0 +1,110 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sourceJSON parses q and returns its Elasticsearch query as JSON
+func sourceJSON(t *testing.T, q, lang string) string {
+	t.Helper()
+	query, err := ParseQuery(q)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) = %v", q, err)
+	}
+	data, err := json.Marshal(query.source(lang))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "golang", want: `{"bool":{"must":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"golang","type":"most_fields","zero_terms_query":"none"}}]}}`},
+		{query: `"hello world"`, want: `{"bool":{"must":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"hello world","type":"phrase","zero_terms_query":"none"}}]}}`},
+		{query: "prog*", want: `{"bool":{"must":[{"multi_match":{"fields":["title","content","title.en","content.en"],"max_expansions":50,"query":"prog","type":"phrase_prefix","zero_terms_query":"none"}}]}}`},
+		{query: "title:go", want: `{"bool":{"must":[{"multi_match":{"fields":["title","title.en"],"query":"go","type":"most_fields","zero_terms_query":"none"}}]}}`},
+		{query: `content:"error handling"`, want: `{"bool":{"must":[{"multi_match":{"fields":["content","content.en"],"query":"error handling","type":"phrase","zero_terms_query":"none"}}]}}`},
+		{query: "tag:golang", want: `{"bool":{"filter":[{"term":{"tags":"golang"}}]}}`},
+		{query: "after:2024-01-31 before:2024-03-01", want: `{"bool":{"filter":[{"range":{"created_at":{"format":"yyyy-MM-dd","gte":"2024-01-31"}}},{"range":{"created_at":{"format":"yyyy-MM-dd","lt":"2024-03-01"}}}]}}`},
+		{query: "-tag:draft", want: `{"bool":{"must_not":[{"term":{"tags":"draft"}}]}}`},
+		{query: "go OR rust", want: `{"bool":{"must":[{"bool":{"minimum_should_match":1,"should":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"go","type":"most_fields","zero_terms_query":"none"}},{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"rust","type":"most_fields","zero_terms_query":"none"}}]}}]}}`},
+		{query: "-(tag:a OR tag:b)", want: `{"bool":{"must_not":[{"bool":{"minimum_should_match":1,"should":[{"term":{"tags":"a"}},{"term":{"tags":"b"}}]}}]}}`},
+		{query: "--tag:a", want: `{"bool":{"must_not":[{"bool":{"must_not":{"term":{"tags":"a"}}}}]}}`},
+		{query: "e-mail", want: `{"bool":{"must":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"e-mail","type":"most_fields","zero_terms_query":"none"}}]}}`},
+		// Words no analyzer keeps are left out
+		{query: "go & tag:x", want: `{"bool":{"filter":[{"term":{"tags":"x"}}],"must":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"go","type":"most_fields","zero_terms_query":"none"}}]}}`},
+	}
+	for _, tt := range tests {
+		if got := sourceJSON(t, tt.query, "en"); got != tt.want {
+			t.Errorf("ParseQuery(%q)\n got %s\nwant %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryNegatedStopword(t *testing.T) {
+	// "the" has no terms in the en subfields. With zero_terms_query "all"
+	// those fields would match every post, so -the would match none.
+	want := `{"bool":{"must_not":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"the","type":"most_fields","zero_terms_query":"none"}}]}}`
+	if got := sourceJSON(t, "-the", "en"); got != want {
+		t.Errorf("-the\n got %s\nwant %s", got, want)
+	}
+	if got := sourceJSON(t, "golang -the", "en"); !strings.Contains(got, `"must_not":[{"multi_match":{"fields":["title","content","title.en","content.en"],"query":"the","type":"most_fields","zero_terms_query":"none"}}]`) {
+		t.Errorf("golang -the = %s, want the stopword under must_not with zero_terms_query none", got)
+	}
+	if got := sourceJSON(t, "the", "en"); strings.Contains(got, `"all"`) {
+		t.Errorf("the = %s, a bare stopword must not match every post", got)
+	}
+}
+
+func TestParseQueryLanguage(t *testing.T) {
+	got := sourceJSON(t, "phở", "vi")
+	if !strings.Contains(got, `"fields":["title","content","title.vi","content.vi"]`) {
+		t.Errorf("phở with lang vi = %s, want the vi subfields", got)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query    string
+		position int
+		message  string
+	}{
+		{query: "", position: 1, message: "expected a search term"},
+		{query: "&", position: 2, message: "expected a search term"},
+		{query: `"unterminated`, position: 1, message: "unterminated phrase"},
+		{query: `""`, position: 1, message: "empty phrase"},
+		{query: "(go", position: 1, message: `missing ")"`},
+		{query: "go)", position: 3, message: `unexpected ")"`},
+		{query: "OR go", position: 1, message: "OR must be between two search terms"},
+		{query: "go OR", position: 4, message: "OR must be followed by a search term"},
+		{query: "-", position: 2, message: "expected a search term"},
+		{query: "pr*", position: 3, message: "* may only end a word of at least 3 characters"},
+		{query: "pro*gram", position: 4, message: "* may only end a word of at least 3 characters"},
+		{query: "title:", position: 1, message: "missing value after title:"},
+		{query: "author:bob", position: 1, message: `unknown field "author"`},
+		{query: "tag:go*", position: 5, message: "wildcards are not supported"},
+		{query: "before:yesterday", position: 8, message: "needs a date"},
+		{query: "/go.*/", position: 1, message: "regular expressions are not supported"},
+		{query: "((((((go))))))", position: 6, message: "nested more than 5 deep"},
+		{query: strings.Repeat("go ", 31), position: 91, message: "more than 30 terms"},
+		{query: strings.Repeat("a", 501), position: 501, message: "longer than 500 characters"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseQuery(%q) = %v, want a *SyntaxError", tt.query, err)
+			continue
+		}
+		if syntaxErr.Position != tt.position || !strings.Contains(syntaxErr.Message, tt.message) {
+			t.Errorf("ParseQuery(%q) = %v, want position %d: %s", tt.query, err, tt.position, tt.message)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Windows over which trending posts are ranked
+const (
+	Window24h = "24h"
+	Window7d  = "7d"
+)
+
+// windows maps each trending window to its length
+var windows = map[string]time.Duration{
+	Window24h: 24 * time.Hour,
+	Window7d:  7 * 24 * time.Hour,
+}
+
+// Windows lists the supported trending windows
+var Windows = []string{Window24h, Window7d}
+
+// Config controls view counting and trending scores
+type Config struct {
+	Enabled bool
+	// FlushInterval is how often view counters are written to post_stats
+	FlushInterval time.Duration
+	// FlushBatchSize is the number of posts flushed per statement
+	FlushBatchSize int
+	// BotUserAgents are case-insensitive substrings of user agents whose
+	// views are not counted; requests without a user agent are never counted
+	BotUserAgents []string
+	// TrustProxy takes visitor IPs from X-Forwarded-For
+	TrustProxy bool
+}
+
+// DefaultConfig returns the stats settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Enabled:        true,
+		FlushInterval:  time.Minute,
+		FlushBatchSize: 500,
+		BotUserAgents: []string{
+			"bot", "crawler", "spider", "slurp", "facebookexternalhit",
+			"headlesschrome", "preview", "monitor",
+		},
+	}
+}
+
+// IsBot reports whether a view with this user agent should be ignored
+func (c Config) IsBot(userAgent string) bool {
+	if strings.TrimSpace(userAgent) == "" {
+		return true
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, pattern := range c.BotUserAgents {
+		if pattern != "" && strings.Contains(userAgent, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWindow returns the length of a trending window such as "24h"
+func ParseWindow(window string) (time.Duration, error) {
+	d, ok := windows[window]
+	if !ok {
+		return 0, fmt.Errorf("window must be one of %s", strings.Join(Windows, ", "))
+	}
+	return d, nil
+}
@@ -0,0 +1,282 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/models"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/stats")
+
+// Redis keys. Views are counted per post until the next flush, visitors are
+// kept in a HyperLogLog per post and trending scores in one sorted set per hour.
+const (
+	keyPrefix     = "stats:"
+	dirtyKey      = keyPrefix + "dirty"
+	trendingCache = time.Minute
+)
+
+func viewsKey(postID int) string    { return keyPrefix + "views:" + strconv.Itoa(postID) }
+func visitorsKey(postID int) string { return keyPrefix + "visitors:" + strconv.Itoa(postID) }
+func hourKey(hour int64) string     { return keyPrefix + "hour:" + strconv.FormatInt(hour, 10) }
+func trendingKey(window string) string {
+	return keyPrefix + "trending:" + window
+}
+
+// Score is a post's trending score
+type Score struct {
+	PostID int
+	Score  float64
+}
+
+// Tracker counts post views in Redis and flushes them to Postgres in the
+// background. Every replica flushes; SPOP hands each post to one of them.
+type Tracker struct {
+	client *redis.Client
+	repo   *repository.StatsRepository
+	cfg    Config
+	logger *slog.Logger
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewTracker(client *redis.Client, repo *repository.StatsRepository, cfg Config, logger *slog.Logger) *Tracker {
+	return &Tracker{
+		client: client,
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// RecordView counts a view of a post unless it comes from a bot. Visitors are
+// identified like rate-limited clients: by the X-User-ID of a trusted proxy,
+// then by IP address.
+func (t *Tracker) RecordView(ctx context.Context, r *http.Request, postID int) error {
+	if t.cfg.IsBot(r.UserAgent()) {
+		metrics.PostViews.WithLabelValues("bot").Inc()
+		return nil
+	}
+
+	now := time.Now()
+	hour := now.Unix() / 3600
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, viewsKey(postID))
+		pipe.PFAdd(ctx, visitorsKey(postID), ratelimit.ClientKey(r, t.cfg.TrustProxy))
+		pipe.SAdd(ctx, dirtyKey, postID)
+		pipe.ZIncrBy(ctx, hourKey(hour), 1, strconv.Itoa(postID))
+		// Keep hourly sets for the longest window plus the current hour
+		pipe.ExpireAt(ctx, hourKey(hour), time.Unix((hour+1)*3600, 0).Add(windows[Window7d]))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record view: %w", err)
+	}
+	metrics.PostViews.WithLabelValues("counted").Inc()
+	return nil
+}
+
+// Trending returns the top posts of a window by time-decayed views. Each
+// hour's views count half as much every quarter of the window, so a 24h
+// window halves every 6 hours. Results are reused for a minute.
+func (t *Tracker) Trending(ctx context.Context, window string, limit int) ([]Score, error) {
+	length, err := ParseWindow(window)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := trendingKey(window)
+	exists, err := t.client.Exists(ctx, dest).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trending posts: %w", err)
+	}
+	if exists == 0 {
+		hours := int(length / time.Hour)
+		halfLife := float64(hours) / 4
+		current := time.Now().Unix() / 3600
+
+		store := redis.ZStore{
+			Keys:    make([]string, hours),
+			Weights: make([]float64, hours),
+		}
+		for age := 0; age < hours; age++ {
+			store.Keys[age] = hourKey(current - int64(age))
+			store.Weights[age] = math.Pow(0.5, float64(age)/halfLife)
+		}
+
+		_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZUnionStore(ctx, dest, &store)
+			pipe.Expire(ctx, dest, trendingCache)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute trending posts: %w", err)
+		}
+	}
+
+	top, err := t.client.ZRevRangeWithScores(ctx, dest, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trending posts: %w", err)
+	}
+
+	scores := make([]Score, 0, len(top))
+	for _, z := range top {
+		member, _ := z.Member.(string)
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			continue
+		}
+		scores = append(scores, Score{PostID: id, Score: z.Score})
+	}
+	return scores, nil
+}
+
+// Start launches the background flush loop
+func (t *Tracker) Start() {
+	t.wg.Add(1)
+	go t.flushLoop()
+}
+
+// Shutdown stops the flush loop and flushes once more, so views counted
+// since the last tick reach Postgres before the service exits
+func (t *Tracker) Shutdown(ctx context.Context) error {
+	t.once.Do(func() { close(t.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return t.Flush(ctx)
+}
+
+func (t *Tracker) flushLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), t.cfg.FlushInterval)
+			if err := t.Flush(ctx); err != nil {
+				t.logger.ErrorContext(ctx, "failed to flush view counts", slog.Any("error", err))
+			}
+			cancel()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// Flush moves the views counted since the last flush into post_stats.
+// Counts that cannot be saved are added back so the next flush retries them.
+func (t *Tracker) Flush(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "stats.Flush")
+	defer tracing.End(span, &err)
+
+	var flushed int
+	defer func() {
+		if flushed > 0 {
+			t.logger.DebugContext(ctx, "flushed view counts", slog.Int("posts", flushed))
+		}
+	}()
+
+	for {
+		members, err := t.client.SPopN(ctx, dirtyKey, int64(t.cfg.FlushBatchSize)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to take posts to flush: %w", err)
+		}
+		if len(members) == 0 {
+			return nil
+		}
+
+		batch, err := t.takeCounts(ctx, members)
+		if err != nil {
+			return err
+		}
+		if err := t.repo.AddPostStats(ctx, batch); err != nil {
+			t.restore(context.WithoutCancel(ctx), batch)
+			return err
+		}
+		flushed += len(batch)
+
+		if len(members) < t.cfg.FlushBatchSize {
+			return nil
+		}
+	}
+}
+
+// takeCounts reads and resets the pending views of each post
+func (t *Tracker) takeCounts(ctx context.Context, members []string) ([]models.PostStats, error) {
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.Atoi(member); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	views := make([]*redis.StringCmd, len(ids))
+	visitors := make([]*redis.IntCmd, len(ids))
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			views[i] = pipe.GetDel(ctx, viewsKey(id))
+			visitors[i] = pipe.PFCount(ctx, visitorsKey(id))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		// The counters may or may not have been reset; mark the posts again
+		// so nothing is lost, at worst views are flushed late
+		t.client.SAdd(context.WithoutCancel(ctx), dirtyKey, members)
+		return nil, fmt.Errorf("failed to read view counters: %w", err)
+	}
+
+	batch := make([]models.PostStats, 0, len(ids))
+	for i, id := range ids {
+		n, err := views[i].Int64()
+		if err != nil || n <= 0 {
+			continue
+		}
+		batch = append(batch, models.PostStats{PostID: id, Views: n, UniqueVisitors: visitors[i].Val()})
+	}
+	return batch, nil
+}
+
+// restore puts views that could not be saved back into Redis
+func (t *Tracker) restore(ctx context.Context, batch []models.PostStats) {
+	_, err := t.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, s := range batch {
+			pipe.IncrBy(ctx, viewsKey(s.PostID), s.Views)
+			pipe.SAdd(ctx, dirtyKey, s.PostID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.logger.ErrorContext(ctx, "failed to restore view counters, views were lost",
+			slog.Int("posts", len(batch)), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,246 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Error codes reported in FieldError.Code
+const (
+	CodeRequired      = "required"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeTooFew        = "too_few"
+	CodeTooMany       = "too_many"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidValue  = "invalid_value"
+	CodeDuplicate     = "duplicate"
+	CodeUnknownField  = "unknown_field"
+	CodeInvalidType   = "invalid_type"
+	CodeNotPermitted  = "not_permitted"
+)
+
+// FieldError describes one invalid field. Field is the JSON path, e.g. "tags[2]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors holds every field error found in a value
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// pattern is a named format that can be referenced from a validate tag
+type pattern struct {
+	re          *regexp.Regexp
+	description string
+}
+
+var patterns = map[string]pattern{
+	// tag: letters and digits, optionally joined by - _ . + or #, e.g. "c++", "go-1.21"
+	"tag": {
+		re:          regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}+#._-]*$`),
+		description: "must start with a letter or digit and contain only letters, digits and + # . _ -",
+	},
+}
+
+// Rules are the constraints parsed from one validate tag. Lengths are counted
+// in runes for strings and in items for slices; the Item* rules and Pattern
+// apply to each element of a []string. Unset limits are -1.
+//
+//	Title string   `json:"title" validate:"required,max=255"`
+//	Tags  []string `json:"tags" validate:"max=10,unique,itemmin=1,itemmax=50,pattern=tag"`
+type Rules struct {
+	Required         bool
+	Min, Max         int
+	ItemMin, ItemMax int
+	Unique           bool
+	Pattern          string
+}
+
+type field struct {
+	index int
+	name  string
+	kind  reflect.Kind
+	rules Rules
+}
+
+var cache sync.Map // reflect.Type -> []field
+
+// Struct validates v, a struct or pointer to struct, against the validate
+// tags on its fields and returns every violation, or nil if there are none.
+// Malformed tags panic, like a bad regexp.MustCompile.
+func Struct(v any) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+
+	var errs Errors
+	for _, f := range fieldsOf(rv.Type()) {
+		fv := rv.Field(f.index)
+		switch f.kind {
+		case reflect.String:
+			errs = checkString(errs, f.name, fv.String(), f.rules.Required, f.rules.Min, f.rules.Max, f.rules.Pattern)
+		case reflect.Slice:
+			errs = checkStrings(errs, f.name, fv.Interface().([]string), f.rules)
+		}
+	}
+	return errs
+}
+
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := cache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		kind := sf.Type.Kind()
+		if kind != reflect.String && sf.Type != reflect.TypeOf([]string(nil)) {
+			panic(fmt.Sprintf("validation: %s.%s: only string and []string fields can be validated", t.Name(), sf.Name))
+		}
+
+		rules, err := ParseTag(tag)
+		if err != nil {
+			panic(fmt.Sprintf("validation: %s.%s: %v", t.Name(), sf.Name, err))
+		}
+
+		fields = append(fields, field{
+			index: i,
+			name:  name,
+			kind:  kind,
+			rules: rules,
+		})
+	}
+
+	cache.Store(t, fields)
+	return fields
+}
+
+// ParseTag parses a validate tag
+func ParseTag(tag string) (Rules, error) {
+	r := Rules{Min: -1, Max: -1, ItemMin: -1, ItemMax: -1}
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+
+		var target *int
+		switch name {
+		case "":
+			continue
+		case "required":
+			r.Required = true
+			continue
+		case "unique":
+			r.Unique = true
+			continue
+		case "pattern":
+			if _, ok := patterns[arg]; !ok {
+				return Rules{}, fmt.Errorf("unknown pattern %q", arg)
+			}
+			r.Pattern = arg
+			continue
+		case "min":
+			target = &r.Min
+		case "max":
+			target = &r.Max
+		case "itemmin":
+			target = &r.ItemMin
+		case "itemmax":
+			target = &r.ItemMax
+		default:
+			return Rules{}, fmt.Errorf("unknown rule %q", name)
+		}
+
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n < 0 {
+			return Rules{}, fmt.Errorf("%s needs a non-negative integer", name)
+		}
+		*target = n
+	}
+	return r, nil
+}
+
+// PatternExpr returns the regular expression behind a named pattern
+func PatternExpr(name string) string {
+	if p, ok := patterns[name]; ok {
+		return p.re.String()
+	}
+	return ""
+}
+
+func checkString(errs Errors, name, s string, required bool, min, max int, patternName string) Errors {
+	if strings.TrimSpace(s) == "" {
+		if required {
+			return append(errs, FieldError{Field: name, Code: CodeRequired, Message: "is required"})
+		}
+		if s == "" {
+			return errs
+		}
+	}
+
+	n := utf8.RuneCountInString(s)
+	if min >= 0 && n < min {
+		errs = append(errs, FieldError{Field: name, Code: CodeTooShort, Message: fmt.Sprintf("must be at least %d characters", min)})
+	}
+	if max >= 0 && n > max {
+		errs = append(errs, FieldError{Field: name, Code: CodeTooLong, Message: fmt.Sprintf("must be at most %d characters", max)})
+	}
+	if patternName != "" {
+		if p := patterns[patternName]; !p.re.MatchString(s) {
+			errs = append(errs, FieldError{Field: name, Code: CodeInvalidFormat, Message: p.description})
+		}
+	}
+	return errs
+}
+
+func checkStrings(errs Errors, name string, items []string, r Rules) Errors {
+	if len(items) == 0 && r.Required {
+		return append(errs, FieldError{Field: name, Code: CodeRequired, Message: "is required"})
+	}
+	if r.Min >= 0 && len(items) < r.Min {
+		errs = append(errs, FieldError{Field: name, Code: CodeTooFew, Message: fmt.Sprintf("must have at least %d items", r.Min)})
+	}
+	if r.Max >= 0 && len(items) > r.Max {
+		errs = append(errs, FieldError{Field: name, Code: CodeTooMany, Message: fmt.Sprintf("must have at most %d items", r.Max)})
+	}
+
+	seen := make(map[string]int, len(items))
+	for i, item := range items {
+		itemName := fmt.Sprintf("%s[%d]", name, i)
+		errs = checkString(errs, itemName, item, r.ItemMin > 0, r.ItemMin, r.ItemMax, r.Pattern)
+
+		if r.Unique {
+			if first, ok := seen[item]; ok {
+				errs = append(errs, FieldError{Field: itemName, Code: CodeDuplicate, Message: fmt.Sprintf("duplicates %s[%d]", name, first)})
+			} else {
+				seen[item] = i
+			}
+		}
+	}
+	return errs
+}
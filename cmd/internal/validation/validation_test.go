@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+type post struct {
+	Title   string   `json:"title" validate:"required,min=3,max=5"`
+	Summary string   `json:"summary,omitempty" validate:"max=4"`
+	Tags    []string `json:"tags" validate:"max=3,unique,itemmin=1,itemmax=4,pattern=tag"`
+	Author  string   `validate:"required"`
+	Notes   string   `json:"notes"`
+}
+
+// codes renders errs as "field=code" pairs for comparison
+func codes(errs Errors) string {
+	parts := make([]string, len(errs))
+	for i, fe := range errs {
+		parts[i] = fe.Field + "=" + fe.Code
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		v    post
+		want string
+	}{
+		{name: "valid", v: post{Title: "hello", Tags: []string{"go", "c++"}, Author: "a"}},
+		{name: "required", v: post{Title: "  "}, want: "title=required,Author=required"},
+		{name: "too short", v: post{Title: "hi", Author: "a"}, want: "title=too_short"},
+		{name: "too long", v: post{Title: "hello!", Summary: "abcde", Author: "a"}, want: "title=too_long,summary=too_long"},
+		// Five runes but eleven bytes
+		{name: "lengths count runes", v: post{Title: "phở ộ", Summary: "tiến", Author: "a"}},
+		{name: "optional blank", v: post{Title: "hello", Summary: "", Author: "a"}},
+		{name: "too many items", v: post{Title: "hello", Tags: []string{"a", "b", "c", "d"}, Author: "a"}, want: "tags=too_many"},
+		{name: "empty item", v: post{Title: "hello", Tags: []string{"go", ""}, Author: "a"}, want: "tags[1]=required"},
+		{name: "long item", v: post{Title: "hello", Tags: []string{"golang"}, Author: "a"}, want: "tags[0]=too_long"},
+		{name: "duplicate item", v: post{Title: "hello", Tags: []string{"go", "rust", "go"}, Author: "a"}, want: "tags[2]=duplicate"},
+		{name: "pattern", v: post{Title: "hello", Tags: []string{"-go", "a b", "phở"}, Author: "a"}, want: "tags[0]=invalid_format,tags[1]=invalid_format"},
+		{name: "untagged field", v: post{Title: "hello", Author: "a", Notes: strings.Repeat("x", 1000)}},
+	}
+	for _, tt := range tests {
+		if got := codes(Struct(tt.v)); got != tt.want {
+			t.Errorf("%s: Struct() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStructDuplicateMessage(t *testing.T) {
+	errs := Struct(&post{Title: "hello", Tags: []string{"go", "go"}, Author: "a"})
+	if len(errs) != 1 || errs[0].Message != "duplicates tags[0]" {
+		t.Errorf("Struct() = %v, want tags[1] reported as a duplicate of tags[0]", errs)
+	}
+}
+
+func TestStructItemCounts(t *testing.T) {
+	type list struct {
+		Items []string `json:"items" validate:"required,min=2"`
+	}
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{items: nil, want: "items=required"},
+		{items: []string{"a"}, want: "items=too_few"},
+		{items: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := codes(Struct(list{Items: tt.items})); got != tt.want {
+			t.Errorf("Struct(%q) = %s, want %s", tt.items, got, tt.want)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	r, err := ParseTag("required, min=1,max=10,itemmin=2,itemmax=3,unique,pattern=tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rules{Required: true, Min: 1, Max: 10, ItemMin: 2, ItemMax: 3, Unique: true, Pattern: "tag"}
+	if r != want {
+		t.Errorf("ParseTag() = %+v, want %+v", r, want)
+	}
+
+	if r, err := ParseTag(""); err != nil || r != (Rules{Min: -1, Max: -1, ItemMin: -1, ItemMax: -1}) {
+		t.Errorf("ParseTag(\"\") = %+v, %v; want every limit unset", r, err)
+	}
+}
+
+func TestParseTagErrors(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "requird", want: `unknown rule "requird"`},
+		{tag: "pattern=email", want: `unknown pattern "email"`},
+		{tag: "max", want: "max needs a non-negative integer"},
+		{tag: "min=-1", want: "min needs a non-negative integer"},
+		{tag: "itemmax=ten", want: "itemmax needs a non-negative integer"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseTag(tt.tag); err == nil || err.Error() != tt.want {
+			t.Errorf("ParseTag(%q) error = %v, want %q", tt.tag, err, tt.want)
+		}
+	}
+}
+
+func TestStructPanicsOnBadTags(t *testing.T) {
+	type unknownRule struct {
+		Name string `validate:"required,shiny"`
+	}
+	type wrongType struct {
+		Count int `validate:"required"`
+	}
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "unknown rule", v: unknownRule{}, want: `unknownRule.Name: unknown rule "shiny"`},
+		{name: "unsupported type", v: wrongType{}, want: "only string and []string fields"},
+		{name: "not a struct", v: "title", want: "is not a struct"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, tt.want) {
+					t.Errorf("%s: Struct() panicked with %q, want %q", tt.name, msg, tt.want)
+				}
+			}()
+			Struct(tt.v)
+		}()
+	}
+}
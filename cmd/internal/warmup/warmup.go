@@ -0,0 +1,174 @@
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+)
+
+// Supported strategies for choosing which posts to preload
+const (
+	StrategyRecent = "recent"
+	// StrategyViewed preloads the posts with the most views in post_stats
+	StrategyViewed = "viewed"
+)
+
+// Config controls how many posts are preloaded and how hard the database is hit
+type Config struct {
+	Enabled  bool
+	Strategy string
+	// Limit is the number of posts to preload
+	Limit int
+	// BatchSize is the number of posts loaded and written per pipeline
+	BatchSize int
+	// Concurrency caps the number of batches in flight
+	Concurrency int
+	// Timeout bounds the whole warm-up; the service becomes ready when it expires
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the warm-up settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		Enabled:     true,
+		Strategy:    StrategyRecent,
+		Limit:       500,
+		BatchSize:   50,
+		Concurrency: 4,
+		Timeout:     30 * time.Second,
+	}
+}
+
+// Warmer preloads posts into the cache and reports readiness once done
+type Warmer struct {
+	repo   *repository.PostRepository
+	cache  cache.Cache
+	cfg    Config
+	logger *slog.Logger
+	ready  atomic.Bool
+}
+
+func NewWarmer(repo *repository.PostRepository, cache cache.Cache, cfg Config, logger *slog.Logger) *Warmer {
+	return &Warmer{
+		repo:   repo,
+		cache:  cache,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Ready reports whether warm-up has completed or timed out
+func (w *Warmer) Ready() bool {
+	return w.ready.Load()
+}
+
+// Run preloads the configured posts. It always marks the warmer ready when it
+// returns, so a failed or slow warm-up only delays traffic up to the timeout.
+func (w *Warmer) Run(ctx context.Context) error {
+	defer w.ready.Store(true)
+
+	if !w.cfg.Enabled {
+		return nil
+	}
+
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	ids, err := w.selectPostIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	batchSize := w.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(ids)
+	}
+	concurrency := w.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		warmed   atomic.Int64
+		firstErr error
+		errOnce  sync.Once
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for i := 0; i < len(ids); i += batchSize {
+		batch := ids[i:min(i+batchSize, len(ids))]
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return fmt.Errorf("cache warm-up stopped after %d posts: %w", warmed.Load(), ctx.Err())
+		}
+
+		wg.Add(1)
+		go func(batch []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			n, err := w.warmBatch(ctx, batch)
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				return
+			}
+			warmed.Add(int64(n))
+		}(batch)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("cache warm-up stopped after %d posts: %w", warmed.Load(), ctx.Err())
+	}
+
+	w.logger.InfoContext(ctx, "cache warm-up finished",
+		slog.Int64("posts", warmed.Load()),
+		slog.Duration("elapsed", time.Since(start)),
+	)
+	return firstErr
+}
+
+func (w *Warmer) selectPostIDs(ctx context.Context) ([]int, error) {
+	switch w.cfg.Strategy {
+	case StrategyRecent, "":
+		return w.repo.ListRecentPostIDs(ctx, w.cfg.Limit)
+	case StrategyViewed:
+		return w.repo.ListMostViewedPostIDs(ctx, w.cfg.Limit)
+	default:
+		return nil, fmt.Errorf("unknown warm-up strategy %q", w.cfg.Strategy)
+	}
+}
+
+func (w *Warmer) warmBatch(ctx context.Context, ids []int) (int, error) {
+	posts, err := w.repo.GetPostsByIDs(ctx, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load posts for warm-up: %w", err)
+	}
+
+	if err := w.cache.SetPosts(ctx, posts); err != nil {
+		return 0, fmt.Errorf("failed to write posts to cache: %w", err)
+	}
+
+	return len(posts), nil
+}
@@ -0,0 +1,224 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/hungpv1995/golang_training_2025/internal/webhooks")
+
+// maxErrorLength bounds the error recorded for a failed attempt
+const maxErrorLength = 500
+
+// Dispatcher sends queued webhook deliveries in the background. Every replica
+// dispatches; SKIP LOCKED hands each delivery to one of them.
+type Dispatcher struct {
+	repo   *repository.WebhookRepository
+	client *http.Client
+	cfg    Config
+	logger *slog.Logger
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewDispatcher(repo *repository.WebhookRepository, cfg Config, logger *slog.Logger) *Dispatcher {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !cfg.AllowPrivateAddresses {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be dialled instead of the webhook and defeat the check
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.RequestTimeout,
+			// A redirect is reported as a failed attempt rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg:    cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start polls for due deliveries every PollInterval until Shutdown
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.pollLoop()
+}
+
+// Shutdown stops polling and waits for deliveries being sent. Deliveries
+// claimed but not sent become due again once their lease ends.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.once.Do(func() { close(d.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) pollLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.dispatchDue()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// dispatchDue sends batches of due deliveries until none are left
+func (d *Dispatcher) dispatchDue() {
+	for {
+		n, err := d.Dispatch(context.Background())
+		if err != nil {
+			d.logger.Error("failed to dispatch webhook deliveries", slog.Any("error", err))
+			return
+		}
+		if n < d.cfg.BatchSize {
+			return
+		}
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+	}
+}
+
+// Dispatch claims one batch of due deliveries, sends them with up to Workers
+// requests at a time and returns how many were claimed
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	claimCtx, cancel := context.WithTimeout(ctx, d.cfg.RequestTimeout)
+	defer cancel()
+	// Long enough for every batch member to be sent one wave after another
+	waves := (d.cfg.BatchSize + d.cfg.Workers - 1) / d.cfg.Workers
+	lease := 2 * time.Duration(waves) * d.cfg.RequestTimeout
+	claimed, err := d.repo.ClaimDeliveries(claimCtx, d.cfg.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, d.cfg.Workers)
+	var wg sync.WaitGroup
+	for _, delivery := range claimed {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(delivery repository.PendingDelivery) {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(claimed), nil
+}
+
+// deliver makes one attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery repository.PendingDelivery) {
+	ctx, span := tracer.Start(ctx, "webhooks.Deliver", trace.WithAttributes(
+		attribute.Int("webhook.delivery.id", delivery.ID),
+		attribute.String("webhook.event", delivery.Event),
+	))
+	var err error
+	defer tracing.End(span, &err)
+
+	status, err := d.send(ctx, delivery)
+	attempts := delivery.Attempts + 1
+
+	// Outcomes are recorded with a fresh deadline; the request may have used up its own
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.cfg.RequestTimeout)
+	defer cancel()
+	if err == nil {
+		metrics.WebhookDeliveries.WithLabelValues("succeeded").Inc()
+		if err := d.repo.CompleteDelivery(recordCtx, delivery.ID, status); err != nil {
+			d.logger.ErrorContext(ctx, "failed to record webhook delivery", slog.Int("delivery_id", delivery.ID), slog.Any("error", err))
+		}
+		return
+	}
+
+	var retryIn time.Duration
+	if attempts < d.cfg.MaxAttempts {
+		retryIn = d.cfg.Backoff(attempts)
+		metrics.WebhookDeliveries.WithLabelValues("retried").Inc()
+	} else {
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+	}
+	d.logger.WarnContext(ctx, "webhook delivery failed",
+		slog.Int("delivery_id", delivery.ID),
+		slog.Int("attempts", attempts),
+		slog.Duration("retry_in", retryIn),
+		slog.Any("error", err),
+	)
+
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	if err := d.repo.FailDelivery(recordCtx, delivery.ID, status, message, retryIn); err != nil {
+		d.logger.ErrorContext(ctx, "failed to record webhook delivery", slog.Int("delivery_id", delivery.ID), slog.Any("error", err))
+	}
+}
+
+// send posts the signed payload; any status other than 2xx is an error
+func (d *Dispatcher) send(ctx context.Context, delivery repository.PendingDelivery) (status int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-api-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, time.Now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config controls how webhook deliveries are sent and retried
+type Config struct {
+	// PollInterval is how often due deliveries are looked for
+	PollInterval time.Duration
+	// BatchSize is the number of deliveries claimed per query
+	BatchSize int
+	// Workers is the number of deliveries sent at once by each replica
+	Workers        int
+	RequestTimeout time.Duration
+	// MaxAttempts is the number of requests made before a delivery is marked failed
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles with
+	// every attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AllowPrivateAddresses lets deliveries reach loopback, private and
+	// link-local addresses, for local development
+	AllowPrivateAddresses bool
+}
+
+// DefaultConfig returns the delivery settings used when nothing is configured.
+// Ten attempts span about four hours.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:   5 * time.Second,
+		BatchSize:      20,
+		Workers:        4,
+		RequestTimeout: 10 * time.Second,
+		MaxAttempts:    10,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     12 * time.Hour,
+	}
+}
+
+// Backoff returns the delay before retrying after the given number of attempts
+func (c Config) Backoff(attempts int) time.Duration {
+	d := c.InitialBackoff
+	for i := 1; i < attempts && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+// Sign returns the X-Webhook-Signature header for a body sent at timestamp:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed with secret>".
+// Receivers recompute the HMAC and reject old timestamps to stop replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
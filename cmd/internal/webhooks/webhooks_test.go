@@ -0,0 +1,115 @@
+package webhooks
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"post.created","post_id":1}`)
+	at := time.Unix(1710498600, 0)
+
+	// Computed independently with Python's hmac module
+	want := "t=1710498600,v1=8eced6033e6ca2d9c495275330de0715c21fcfe8a683b5ec02f9b461f964feda"
+	if got := Sign("whsec_test", at, body); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+
+	if Sign("other", at, body) == want {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("whsec_test", at.Add(time.Second), body) == want {
+		t.Error("signature does not depend on the timestamp")
+	}
+	if Sign("whsec_test", at, []byte(`{"type":"post.created","post_id":2}`)) == want {
+		t.Error("signature does not depend on the body")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := Config{InitialBackoff: 30 * time.Second, MaxBackoff: 10 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 30 * time.Second},
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 5, want: 8 * time.Minute},
+		{attempts: 6, want: 10 * time.Minute},
+		{attempts: 1000, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := cfg.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+
+	// The defaults give up after about four hours
+	def := DefaultConfig()
+	var total time.Duration
+	for attempt := 1; attempt < def.MaxAttempts; attempt++ {
+		total += def.Backoff(attempt)
+	}
+	if total < 3*time.Hour || total > 6*time.Hour {
+		t.Errorf("default retries span %v, want about four hours", total)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "93.184.216.34", allowed: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", allowed: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.0.0.5"},
+		{ip: "172.16.3.4"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "::"},
+		{ip: "224.0.0.1"},
+		{ip: "::ffff:127.0.0.1"},
+		{ip: "::ffff:169.254.169.254"},
+	}
+	for _, tt := range tests {
+		err := checkAddress(netip.MustParseAddr(tt.ip))
+		if tt.allowed && err != nil {
+			t.Errorf("checkAddress(%s) = %v, want nil", tt.ip, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("checkAddress(%s) = %v, want ErrForbiddenAddress", tt.ip, err)
+		}
+	}
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cfg := DefaultConfig()
+	if _, err := NewDispatcher(nil, cfg, logger).client.Post(srv.URL, "application/json", nil); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("delivery to %s = %v, want ErrForbiddenAddress", srv.URL, err)
+	}
+
+	cfg.AllowPrivateAddresses = true
+	resp, err := NewDispatcher(nil, cfg, logger).client.Post(srv.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("delivery to %s with private addresses allowed = %v", srv.URL, err)
+	}
+	resp.Body.Close()
+}
@@ -1,163 +1,430 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 
+	"github.com/hungpv1995/golang_training_2025/internal/activity"
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
 	"github.com/hungpv1995/golang_training_2025/internal/cache"
+	"github.com/hungpv1995/golang_training_2025/internal/cdc"
+	"github.com/hungpv1995/golang_training_2025/internal/clients"
+	"github.com/hungpv1995/golang_training_2025/internal/config"
+	"github.com/hungpv1995/golang_training_2025/internal/consistency"
+	"github.com/hungpv1995/golang_training_2025/internal/events"
 	"github.com/hungpv1995/golang_training_2025/internal/handlers"
+	"github.com/hungpv1995/golang_training_2025/internal/health"
+	"github.com/hungpv1995/golang_training_2025/internal/indexing"
+	"github.com/hungpv1995/golang_training_2025/internal/logging"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/openapi"
+	"github.com/hungpv1995/golang_training_2025/internal/ratelimit"
 	"github.com/hungpv1995/golang_training_2025/internal/repository"
+	"github.com/hungpv1995/golang_training_2025/internal/retry"
 	"github.com/hungpv1995/golang_training_2025/internal/search"
+	"github.com/hungpv1995/golang_training_2025/internal/stats"
+	"github.com/hungpv1995/golang_training_2025/internal/tracing"
+	"github.com/hungpv1995/golang_training_2025/internal/warmup"
+	"github.com/hungpv1995/golang_training_2025/internal/webhooks"
 )
 
 func main() {
-	// Initialize database
-	db, err := initDB()
+	// "warmup" runs a one-off cache warm-up and exits, e.g. right after a deploy
+	args := os.Args[1:]
+	warmupOnly := len(args) > 0 && args[0] == "warmup"
+	if warmupOnly {
+		args = args[1:]
+	}
+	// "reindex" rebuilds the search index with the current mapping and exits
+	reindexOnly := len(args) > 0 && args[0] == "reindex"
+	if reindexOnly {
+		args = args[1:]
+	}
+	// "migrate up|down|status|to N" changes the database schema and exits
+	var migration *migrateCommand
+	if len(args) > 0 && args[0] == "migrate" {
+		cmd, rest, err := parseMigrateCommand(args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		migration, args = &cmd, rest
+	}
+
+	// Load configuration
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	checkSpec := fs.Bool("check-openapi", false, "check that the OpenAPI document describes exactly the registered routes and exit")
+	cfg, err := config.Load(fs, args)
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		log.Fatal("Failed to load configuration: ", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if *checkSpec {
+		if err := checkOpenAPI(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("OpenAPI document matches the registered routes")
+		return
 	}
-	defer db.Close()
 
-	// Initialize Redis
-	redisClient, err := initRedis()
+	// Structured logging; the level can be changed at runtime via /admin/log-level
+	logger, logLevel, err := logging.New(cfg.Logging.Policy(), os.Stdout)
 	if err != nil {
-		log.Fatal("Failed to initialize Redis:", err)
+		log.Fatal("Failed to initialize logging: ", err)
 	}
-	defer redisClient.Close()
+	slog.SetDefault(logger)
 
-	// Initialize Elasticsearch
-	esClient, err := initElasticsearch()
+	// Stop on SIGINT (Ctrl+C) or SIGTERM (docker stop), also while still connecting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	retryPolicy := cfg.Startup.RetryPolicy()
+
+	// Tracing is set up first so startup work is traced too
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Policy())
 	if err != nil {
-		log.Fatal("Failed to initialize Elasticsearch:", err)
+		fatal(logger, "failed to initialize tracing", err)
 	}
 
-	// Initialize repositories and services
-	postRepo := repository.NewPostRepository(db)
-	cacheService := cache.NewRedisCache(redisClient)
-	searchService := search.NewElasticSearch(esClient)
+	// Initialize database
+	var db *sql.DB
+	err = retry.Do(ctx, "PostgreSQL", retryPolicy, func() (err error) {
+		db, err = clients.OpenPostgres(cfg.Database)
+		return err
+	})
+	if err != nil {
+		fatal(logger, "failed to initialize database", err)
+	}
 
-	// Wait for Elasticsearch to be ready and create index
-	time.Sleep(5 * time.Second)
-	if err := searchService.CreateIndex(); err != nil {
-		log.Printf("Failed to create Elasticsearch index: %v", err)
+	if migration != nil {
+		err := runMigrate(ctx, *migration, db, os.Stdout, logger)
+		clients.Close(logger, db, nil, nil)
+		shutdownTracing(context.Background())
+		if err != nil {
+			fatal(logger, "migration failed", err)
+		}
+		return
+	}
+	// Replicas starting together wait on an advisory lock; one applies the migrations
+	if cfg.Startup.Migrate {
+		migrator, err := newMigrator(db, logger)
+		if err == nil {
+			err = migrator.Up(ctx)
+		}
+		if err != nil {
+			fatal(logger, "failed to migrate database", err)
+		}
 	}
 
-	// Initialize handlers
-	postHandler := handlers.NewPostHandler(postRepo, cacheService, searchService)
+	rateLimitPolicy, err := cfg.RateLimit.Policy()
+	if err != nil {
+		fatal(logger, "invalid rate limit configuration", err)
+	}
 
-	// Setup routes
-	r := mux.NewRouter()
-	r.HandleFunc("/posts", postHandler.CreatePost).Methods("POST")
-	r.HandleFunc("/posts/{id}", postHandler.GetPost).Methods("GET")
-	r.HandleFunc("/posts/{id}", postHandler.UpdatePost).Methods("PUT")
-	r.HandleFunc("/posts/search-by-tag", postHandler.SearchByTag).Methods("GET")
-	r.HandleFunc("/posts/search", postHandler.SearchPosts).Methods("GET")
+	// Initialize Redis, only needed when it backs the cache, the rate limiter,
+	// view counting or the event stream
+	var redisClient *redis.Client
+	if cfg.Cache.Backend == cache.BackendRedis ||
+		(rateLimitPolicy.Enabled && rateLimitPolicy.Backend == ratelimit.BackendRedis) ||
+		cfg.Stats.Enabled || cfg.Events.Enabled {
+		err = retry.Do(ctx, "Redis", retryPolicy, func() (err error) {
+			redisClient, err = clients.OpenRedis(cfg.Redis)
+			return err
+		})
+		if err != nil {
+			fatal(logger, "failed to initialize redis", err)
+		}
+	}
 
-	// Health check endpoint
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
+	metrics.RegisterDB(db, "postgres")
 
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+	// Initialize repositories and services
+	postRepo := repository.NewPostRepository(db, logger)
+	cacheService, err := cache.New(cfg.Cache.Policy(), redisClient, logger)
+	if err != nil {
+		fatal(logger, "failed to initialize cache", err)
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
-}
+	if warmupOnly {
+		warmupConfig := cfg.Warmup.Policy()
+		warmupConfig.Enabled = true
+		err := warmup.NewWarmer(postRepo, cacheService, warmupConfig, logger).Run(ctx)
+		clients.Close(logger, db, redisClient, nil)
+		shutdownTracing(context.Background())
+		if err != nil {
+			fatal(logger, "cache warm-up failed", err)
+		}
+		return
+	}
 
-func initDB() (*sql.DB, error) {
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbUser := getEnv("DB_USER", "bloguser")
-	dbPassword := getEnv("DB_PASSWORD", "blogpass")
-	dbName := getEnv("DB_NAME", "blogdb")
+	// Initialize Elasticsearch
+	var esClient *elasticsearch.Client
+	var esTransport *http.Transport
+	err = retry.Do(ctx, "Elasticsearch", retryPolicy, func() (err error) {
+		esClient, esTransport, err = clients.OpenElasticsearch(cfg.Elasticsearch)
+		return err
+	})
+	if err != nil {
+		fatal(logger, "failed to initialize elasticsearch", err)
+	}
+	searchService := search.NewElasticSearch(esClient, cfg.Elasticsearch.IndexPolicy(), logger)
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
+	if reindexOnly {
+		result, err := indexing.Rebuild(ctx, postRepo, repository.NewChangeRepository(db), searchService, logger)
+		clients.Close(logger, db, redisClient, esTransport)
+		shutdownTracing(context.Background())
+		if err != nil {
+			fatal(logger, "search index rebuild failed", err)
+		}
+		fmt.Printf("%s now serves %d posts (%d caught up); previous: %v\n", result.Index, result.Posts, result.CaughtUp, result.Previous)
+		return
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	// Install the index template and create the index once the cluster accepts it
+	var indexStatus search.IndexStatus
+	err = retry.Do(ctx, "Elasticsearch index", retryPolicy, func() (err error) {
+		indexStatus, err = searchService.EnsureIndex(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		logger.Error("failed to create elasticsearch index", slog.Any("error", err))
+	} else if !indexStatus.Current() {
+		logger.Warn("search index mapping is out of date; run the reindex command to migrate it",
+			slog.String("index", indexStatus.Index),
+			slog.Int("version", indexStatus.Version),
+			slog.Int("expected_version", search.MappingVersion),
+			slog.Any("differences", indexStatus.Differences),
+		)
 	}
 
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(10)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// Start background indexing, replaying jobs left over from the last run
+	indexQueue := indexing.NewQueue(postRepo, searchService, cfg.Indexing.Policy(), logger)
+	indexQueue.Start()
+	metrics.RegisterIndexQueue(indexQueue.Len)
 
-	// Test connection
-	if err := db.Ping(); err != nil {
-		return nil, err
+	// Invalidate and reindex posts however they change, e.g. fixes made in psql
+	var changeListener *cdc.Listener
+	if cfg.CDC.Enabled {
+		changeListener = cdc.NewListener(cfg.Database.DSN(), repository.NewChangeRepository(db), cacheService, indexQueue, cfg.CDC.Policy(), logger)
+		if err := changeListener.Start(ctx); err != nil {
+			logger.Error("failed to start change data capture", slog.Any("error", err))
+			changeListener = nil
+		}
 	}
 
-	return db, nil
-}
+	// Count post views in Redis, flushing them to post_stats in the background
+	var viewStats *stats.Tracker
+	if cfg.Stats.Enabled {
+		viewStats = stats.NewTracker(redisClient, repository.NewStatsRepository(db), cfg.Stats.Policy(cfg.RateLimit.TrustProxy), logger)
+		viewStats.Start()
+	}
 
-func initRedis() (*redis.Client, error) {
-	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
+	// Prune the activity log past its retention period
+	activityRepo := repository.NewActivityRepository(db)
+	activityPruner := activity.NewPruner(activityRepo, cfg.Activity.Policy(), logger)
+	activityPruner.Start()
 
-	client := redis.NewClient(&redis.Options{
-		Addr:         redisAddr,
-		Password:     "", // no password set
-		DB:           0,  // use default DB
-		PoolSize:     10,
-		MinIdleConns: 5,
-	})
+	// Send queued webhook deliveries, retrying failures with backoff
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookDispatcher := webhooks.NewDispatcher(webhookRepo, cfg.Webhooks.Policy(), logger)
+	webhookDispatcher.Start()
 
-	// Test connection
-	ctx := client.Context()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, err
+	// Fan post changes from every replica out to /events clients
+	var eventBroker *events.Broker
+	if cfg.Events.Enabled {
+		eventBroker = events.NewBroker(redisClient, cfg.Events.Policy(), logger)
+		eventBroker.Start()
+		metrics.RegisterEventStream(eventBroker.Clients)
 	}
 
-	return client, nil
-}
+	// Preload popular posts into the cache before reporting ready
+	warmer := warmup.NewWarmer(postRepo, cacheService, cfg.Warmup.Policy(), logger)
+	go func() {
+		if err := warmer.Run(ctx); err != nil {
+			logger.Warn("cache warm-up incomplete", slog.Any("error", err))
+		}
+	}()
 
-func initElasticsearch() (*elasticsearch.Client, error) {
-	esURL := getEnv("ELASTICSEARCH_URL", "http://localhost:9200")
+	// Initialize handlers
+	timeouts := handlers.Timeouts{
+		Database: cfg.Timeouts.Database,
+		Cache:    cfg.Timeouts.Cache,
+		Search:   cfg.Timeouts.Search,
+	}
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	reactionRepo := repository.NewReactionRepository(db)
+	var rateLimiter ratelimit.Limiter
+	var authFailures *handlers.AuthFailureLimit
+	if rateLimitPolicy.Enabled {
+		rateLimiter = newRateLimiter(rateLimitPolicy, redisClient, logger)
+		if rateLimitPolicy.AuthFailures != nil {
+			authFailures = &handlers.AuthFailureLimit{Limiter: rateLimiter, Limit: *rateLimitPolicy.AuthFailures, TrustProxy: rateLimitPolicy.TrustProxy}
+		}
+	}
+	authenticator := auth.NewAuthenticator(apiKeyRepo, cfg.Auth.AdminToken, timeouts.Database, logger)
+	authorizer := handlers.NewAuthorizer(authenticator, cfg.Auth.Enabled, authFailures, timeouts, logger)
+	// The change listener reindexes every post change once, on whichever
+	// replica holds the indexer lock, so post handlers need not
+	postIndexer := indexQueue
+	if changeListener != nil {
+		postIndexer = nil
+	}
+	postHandler := handlers.NewPostHandler(postRepo, cacheService, searchService, postIndexer, viewStats, eventBroker, cfg.RateLimit.TrustProxy, timeouts, logger)
+	commentHandler := handlers.NewCommentHandler(commentRepo, cacheService, cfg.RateLimit.TrustProxy, timeouts, logger)
+	reactionHandler := handlers.NewReactionHandler(reactionRepo, cacheService, indexQueue, cfg.RateLimit.TrustProxy, timeouts, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo, timeouts, logger)
+	activityHandler := handlers.NewActivityHandler(activityRepo, timeouts, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, timeouts, logger)
+	eventHandler := handlers.NewEventHandler(eventBroker, cfg.Events.Heartbeat, timeouts, logger)
+	adminHandler := handlers.NewAdminHandler(consistency.NewChecker(postRepo, searchService, cacheService, logger), logger, logLevel)
+
+	// Readiness checks every dependency plus the cache warm-up
+	checker := health.NewChecker(cfg.Health.CheckTimeout, cfg.Health.CacheTTL)
+	checker.Register("postgres", postRepo.Ping)
+	checker.Register("cache", cacheService.Ping)
+	checker.Register("elasticsearch", searchService.Health)
+	checker.Register("cache_warmup", func(ctx context.Context) error {
+		if !warmer.Ready() {
+			return errors.New("cache warm-up in progress")
+		}
+		return nil
+	})
 
-	cfg := elasticsearch.Config{
-		Addresses: []string{esURL},
-		// Retry on 429 Too Many Requests
-		RetryOnStatus: []int{502, 503, 504, 429},
-		RetryBackoff:  func(i int) time.Duration { return time.Duration(i) * 100 * time.Millisecond },
-		MaxRetries:    3,
+	// Setup routes
+	r := mux.NewRouter()
+	// Continue traces from W3C traceparent headers; spans are named by route template
+	r.Use(otelmux.Middleware(cfg.Tracing.ServiceName))
+	r.Use(logging.RequestIDMiddleware)
+	r.Use(logging.AccessLog(logger))
+	r.Use(metrics.Middleware)
+	// Authenticate before rate limiting so API clients are limited per key;
+	// failed attempts are limited per IP inside Authenticate
+	r.Use(authorizer.Authenticate)
+	if rateLimitPolicy.Enabled {
+		r.Use(handlers.WithRateLimit(rateLimiter, rateLimitPolicy, logger))
 	}
+	// Event streams stay open until the client leaves
+	r.Use(handlers.WithRequestTimeout(cfg.Timeouts.Request, "GET /events"))
 
-	client, err := elasticsearch.NewClient(cfg)
-	if err != nil {
-		return nil, err
+	doc := openapi.Spec()
+	registerRoutes(r, routeHandlers{
+		authorizer: authorizer,
+		posts:      postHandler,
+		comments:   commentHandler,
+		reactions:  reactionHandler,
+		activity:   activityHandler,
+		webhooks:   webhookHandler,
+		events:     eventHandler,
+		apiKeys:    apiKeyHandler,
+		admin:      adminHandler,
+		checker:    checker,
+	}, doc)
+	if err := openapi.Verify(r, doc); err != nil {
+		logger.Warn("openapi document is out of date", slog.Any("error", err))
 	}
 
-	// Test connection
-	res, err := client.Info()
-	if err != nil {
-		return nil, err
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           r,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		return nil, fmt.Errorf("elasticsearch connection error: %s", res.String())
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server starting", slog.Int("port", cfg.Server.Port))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed", slog.Any("error", err))
+		}
+	case <-ctx.Done():
+		logger.Info("shutdown signal received, draining connections")
 	}
+	stop()
 
-	return client, nil
+	// Shut down in dependency order: stop taking requests, finish or persist
+	// indexing work and view counts, then close the clients everything else depends on
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Open event streams would keep the server from draining; end them first
+	// so their clients reconnect to another replica
+	if eventBroker != nil {
+		if err := eventBroker.Shutdown(shutdownCtx); err != nil {
+			logger.Error("event stream did not stop", slog.Any("error", err))
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("http server did not shut down cleanly", slog.Any("error", err))
+	}
+	if err := authenticator.Shutdown(shutdownCtx); err != nil {
+		logger.Error("api key usage was not recorded", slog.Any("error", err))
+	}
+	if changeListener != nil {
+		if err := changeListener.Shutdown(shutdownCtx); err != nil {
+			logger.Error("change listener did not stop", slog.Any("error", err))
+		}
+	}
+	if err := indexQueue.Shutdown(shutdownCtx); err != nil {
+		logger.Error("indexing queue did not drain", slog.Any("error", err))
+	}
+	if viewStats != nil {
+		if err := viewStats.Shutdown(shutdownCtx); err != nil {
+			logger.Error("view counts were not flushed", slog.Any("error", err))
+		}
+	}
+	if err := activityPruner.Shutdown(shutdownCtx); err != nil {
+		logger.Error("activity log pruning did not stop", slog.Any("error", err))
+	}
+	if err := webhookDispatcher.Shutdown(shutdownCtx); err != nil {
+		logger.Error("webhook deliveries did not finish", slog.Any("error", err))
+	}
+	clients.Close(logger, db, redisClient, esTransport)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", slog.Any("error", err))
+	}
+	logger.Info("server stopped")
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// newRateLimiter shares limits through Redis when configured, falling back to
+// per-replica limits while Redis is unavailable
+func newRateLimiter(policy ratelimit.Config, redisClient *redis.Client, logger *slog.Logger) ratelimit.Limiter {
+	memory := ratelimit.NewMemoryLimiter()
+	if policy.Backend != ratelimit.BackendRedis {
+		return memory
 	}
-	return defaultValue
+	return ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(redisClient, "ratelimit:"), memory, logger)
+}
+
+// fatal logs a startup failure and exits
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
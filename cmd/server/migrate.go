@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hungpv1995/golang_training_2025/internal/migrate"
+	"github.com/hungpv1995/golang_training_2025/migrations"
+)
+
+// migrateCommand is "migrate up", "migrate down", "migrate status" or "migrate to N"
+type migrateCommand struct {
+	action  string
+	version int
+}
+
+// parseMigrateCommand splits the words after "migrate" from the flags that follow them
+func parseMigrateCommand(args []string) (migrateCommand, []string, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, nil, errors.New("usage: migrate up|down|status|to <version>")
+	}
+	cmd := migrateCommand{action: args[0]}
+	switch cmd.action {
+	case "up", "down", "status":
+		return cmd, args[1:], nil
+	case "to":
+		if len(args) < 2 {
+			return cmd, nil, errors.New("usage: migrate to <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return cmd, nil, fmt.Errorf("invalid migration version %q", args[1])
+		}
+		cmd.version = version
+		return cmd, args[2:], nil
+	default:
+		return cmd, nil, fmt.Errorf("unknown migrate command %q; use up, down, status or to <version>", cmd.action)
+	}
+}
+
+func newMigrator(db *sql.DB, logger *slog.Logger) (*migrate.Migrator, error) {
+	return migrate.New(db, migrations.FS, logger)
+}
+
+// runMigrate carries out cmd, writing the status table to out
+func runMigrate(ctx context.Context, cmd migrateCommand, db *sql.DB, out io.Writer, logger *slog.Logger) error {
+	migrator, err := newMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.action {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		return migrator.To(ctx, cmd.version)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"github.com/hungpv1995/golang_training_2025/internal/auth"
+	"github.com/hungpv1995/golang_training_2025/internal/handlers"
+	"github.com/hungpv1995/golang_training_2025/internal/health"
+	"github.com/hungpv1995/golang_training_2025/internal/metrics"
+	"github.com/hungpv1995/golang_training_2025/internal/openapi"
+)
+
+// routeHandlers are the handlers mounted by registerRoutes
+type routeHandlers struct {
+	authorizer *handlers.Authorizer
+	posts      *handlers.PostHandler
+	comments   *handlers.CommentHandler
+	reactions  *handlers.ReactionHandler
+	activity   *handlers.ActivityHandler
+	webhooks   *handlers.WebhookHandler
+	events     *handlers.EventHandler
+	apiKeys    *handlers.APIKeyHandler
+	admin      *handlers.AdminHandler
+	checker    *health.Checker
+}
+
+// registerRoutes mounts every endpoint. Each one must also be described in
+// the openapi package; --check-openapi and startup verify that they match.
+func registerRoutes(r *mux.Router, h routeHandlers, doc *openapi.Document) {
+	// Scopes are only enforced on the posts API when auth is enabled
+	r.Handle("/posts", h.authorizer.Require(auth.ScopePostsWrite, h.posts.CreatePost)).Methods("POST")
+	// Numeric IDs only, so /posts/search and /posts/search-by-tag are not taken as IDs
+	r.Handle("/posts/{id:[0-9]+}", h.authorizer.Require(auth.ScopePostsRead, h.posts.GetPost)).Methods("GET")
+	r.Handle("/posts/{id:[0-9]+}", h.authorizer.Require(auth.ScopePostsWrite, h.posts.UpdatePost)).Methods("PUT")
+	r.Handle("/posts/{id:[0-9]+}", h.authorizer.Require(auth.ScopePostsWrite, h.posts.DeletePost)).Methods("DELETE")
+	r.Handle("/posts/search-by-tag", h.authorizer.Require(auth.ScopePostsRead, h.posts.SearchByTag)).Methods("GET")
+	r.Handle("/posts/search", h.authorizer.Require(auth.ScopeSearchRead, h.posts.SearchPosts)).Methods("GET")
+	r.Handle("/posts/trending", h.authorizer.Require(auth.ScopePostsRead, h.posts.TrendingPosts)).Methods("GET")
+
+	// Comments are written on behalf of the X-User-ID user; moderation always
+	// requires a key with comments:moderate
+	r.Handle("/posts/{id:[0-9]+}/comments", h.authorizer.Require(auth.ScopePostsRead, h.comments.ListComments)).Methods("GET")
+	r.Handle("/posts/{id:[0-9]+}/comments", h.authorizer.Require(auth.ScopeCommentsWrite, h.comments.CreateComment)).Methods("POST")
+	r.Handle("/comments/{id:[0-9]+}", h.authorizer.Require(auth.ScopeCommentsWrite, h.comments.UpdateComment)).Methods("PUT")
+	r.Handle("/comments/{id:[0-9]+}", h.authorizer.Require(auth.ScopeCommentsWrite, h.comments.DeleteComment)).Methods("DELETE")
+	r.Handle("/comments/moderation", h.authorizer.RequireAlways(auth.ScopeCommentsModerate, h.comments.ListModerationQueue)).Methods("GET")
+	r.Handle("/comments/{id:[0-9]+}/status", h.authorizer.RequireAlways(auth.ScopeCommentsModerate, h.comments.ModerateComment)).Methods("PUT")
+	// The audit trail holds client IPs, so it always requires a key
+	r.Handle("/activity", h.authorizer.RequireAlways(auth.ScopeActivityRead, h.activity.ListActivity)).Methods("GET")
+	r.Handle("/posts/{id:[0-9]+}/activity", h.authorizer.RequireAlways(auth.ScopeActivityRead, h.activity.ListPostActivity)).Methods("GET")
+	// Live post changes as Server-Sent Events; WithRequestTimeout leaves this route open
+	r.Handle("/events", h.authorizer.Require(auth.ScopePostsRead, h.events.StreamEvents)).Methods("GET")
+	// Reactions are left on behalf of the X-User-ID user, once per type
+	r.Handle("/posts/{id:[0-9]+}/reactions", h.authorizer.Require(auth.ScopeReactionsWrite, h.reactions.AddReaction)).Methods("POST")
+	r.Handle("/posts/{id:[0-9]+}/reactions", h.authorizer.Require(auth.ScopeReactionsWrite, h.reactions.RemoveReaction)).Methods("DELETE")
+
+	// Webhooks carry signing secrets, so they always require a key with webhooks:manage
+	r.Handle("/webhooks", h.authorizer.RequireAlways(auth.ScopeWebhooksManage, h.webhooks.CreateWebhook)).Methods("POST")
+	r.Handle("/webhooks", h.authorizer.RequireAlways(auth.ScopeWebhooksManage, h.webhooks.ListWebhooks)).Methods("GET")
+	r.Handle("/webhooks/{id:[0-9]+}", h.authorizer.RequireAlways(auth.ScopeWebhooksManage, h.webhooks.DeleteWebhook)).Methods("DELETE")
+	r.Handle("/webhooks/{id:[0-9]+}/deliveries", h.authorizer.RequireAlways(auth.ScopeWebhooksManage, h.webhooks.ListDeliveries)).Methods("GET")
+	r.Handle("/webhook-deliveries/{id:[0-9]+}/redeliver", h.authorizer.RequireAlways(auth.ScopeWebhooksManage, h.webhooks.Redeliver)).Methods("POST")
+
+	// API key management always requires a key with keys:manage
+	r.Handle("/api-keys", h.authorizer.RequireAlways(auth.ScopeKeysManage, h.apiKeys.CreateAPIKey)).Methods("POST")
+	r.Handle("/api-keys", h.authorizer.RequireAlways(auth.ScopeKeysManage, h.apiKeys.ListAPIKeys)).Methods("GET")
+	r.Handle("/api-keys/{id:[0-9]+}/rotate", h.authorizer.RequireAlways(auth.ScopeKeysManage, h.apiKeys.RotateAPIKey)).Methods("POST")
+	r.Handle("/api-keys/{id:[0-9]+}", h.authorizer.RequireAlways(auth.ScopeKeysManage, h.apiKeys.RevokeAPIKey)).Methods("DELETE")
+
+	// Health check endpoints; /health is kept for existing probes
+	r.HandleFunc("/livez", health.LiveHandler).Methods("GET")
+	r.HandleFunc("/health", health.LiveHandler).Methods("GET")
+	r.HandleFunc("/readyz", h.checker.ReadyHandler).Methods("GET")
+
+	// Prometheus scrape endpoint
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Runtime log level
+	r.Handle("/admin/log-level", h.authorizer.RequireAlways(auth.ScopeAdmin, h.admin.GetLogLevel)).Methods("GET")
+	r.Handle("/admin/log-level", h.authorizer.RequireAlways(auth.ScopeAdmin, h.admin.SetLogLevel)).Methods("PUT")
+	// Drift between Postgres, the search index and the cache
+	r.Handle("/admin/consistency", h.authorizer.RequireAlways(auth.ScopeAdmin, h.admin.CheckConsistency)).Methods("POST")
+
+	// API documentation
+	r.Handle("/openapi.json", openapi.Handler(doc)).Methods("GET")
+	r.Handle("/docs", openapi.UIHandler("/openapi.json")).Methods("GET")
+}
+
+// checkOpenAPI verifies the document against the routes without connecting
+// to any dependency
+func checkOpenAPI() error {
+	r := mux.NewRouter()
+	doc := openapi.Spec()
+	registerRoutes(r, routeHandlers{
+		authorizer: handlers.NewAuthorizer(nil, true, nil, handlers.Timeouts{}, nil),
+		posts:      &handlers.PostHandler{},
+		comments:   &handlers.CommentHandler{},
+		reactions:  &handlers.ReactionHandler{},
+		activity:   &handlers.ActivityHandler{},
+		webhooks:   &handlers.WebhookHandler{},
+		events:     &handlers.EventHandler{},
+		apiKeys:    &handlers.APIKeyHandler{},
+		admin:      &handlers.AdminHandler{},
+		checker:    health.NewChecker(0, 0),
+	}, doc)
+	return openapi.Verify(r, doc)
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	if err := checkOpenAPI(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -22,11 +22,14 @@ CREATE TABLE IF NOT EXISTS activity_logs (
 CREATE INDEX IF NOT EXISTS idx_activity_logs_post_id ON activity_logs(post_id);
 CREATE INDEX IF NOT EXISTS idx_activity_logs_logged_at ON activity_logs(logged_at);
 
--- Insert some sample data (optional)
-INSERT INTO posts (title, content, tags) VALUES
+-- Insert some sample data (optional), only into an empty table so databases
+-- created before migrations were tracked can run this file again
+INSERT INTO posts (title, content, tags)
+SELECT * FROM (VALUES
     ('Getting Started with Go', 'Go is a statically typed, compiled programming language designed at Google.', ARRAY['golang', 'programming', 'backend']),
     ('Understanding Redis', 'Redis is an in-memory data structure store, used as a database, cache, and message broker.', ARRAY['redis', 'cache', 'database']),
     ('Elasticsearch Guide', 'Elasticsearch is a distributed, RESTful search and analytics engine.', ARRAY['elasticsearch', 'search', 'database']),
     ('PostgreSQL Best Practices', 'PostgreSQL is a powerful, open source object-relational database system.', ARRAY['postgresql', 'database', 'sql']),
     ('Docker for Development', 'Docker is a platform for developing, shipping, and running applications in containers.', ARRAY['docker', 'devops', 'containers'])
-ON CONFLICT DO NOTHING;
+) AS sample (title, content, tags)
+WHERE NOT EXISTS (SELECT 1 FROM posts);
@@ -0,0 +1,5 @@
+-- Posts whose Elasticsearch indexing did not complete, replayed by the API
+CREATE TABLE IF NOT EXISTS pending_index_jobs (
+    post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    queued_at TIMESTAMP DEFAULT NOW()
+);
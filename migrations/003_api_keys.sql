@@ -0,0 +1,17 @@
+-- Credentials for machine clients. Only a SHA-256 hash of each key is stored;
+-- the prefix identifies the key without revealing it.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    -- Set on keys created by rotating another key
+    rotated_from INTEGER REFERENCES api_keys(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_active ON api_keys(prefix) WHERE revoked_at IS NULL;
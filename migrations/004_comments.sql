@@ -0,0 +1,22 @@
+-- Threaded comments. New comments wait in the moderation queue as 'pending';
+-- only 'approved' ones are shown and counted in posts.comment_count.
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    author_id VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'spam')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP,
+    -- Deleted comments keep their row so replies stay attached to the thread
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_thread ON comments(post_id, parent_id, created_at) WHERE status = 'approved';
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_moderation ON comments(status, created_at) WHERE deleted_at IS NULL;
+
+-- Approved, undeleted comments per post, kept in step by the API
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comment_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- View statistics flushed periodically from the Redis counters
+CREATE TABLE IF NOT EXISTS post_stats (
+    post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    views BIGINT NOT NULL DEFAULT 0,
+    -- HyperLogLog estimate, so it is approximate (about 1% error)
+    unique_visitors BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_stats_views ON post_stats(views DESC);
@@ -0,0 +1,13 @@
+-- One reaction of each type per user per post
+CREATE TABLE IF NOT EXISTS post_reactions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL,
+    reaction VARCHAR(20) NOT NULL
+        CHECK (reaction IN ('like', 'love', 'laugh', 'wow', 'sad', 'angry')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT post_reactions_unique UNIQUE (post_id, user_id, reaction)
+);
+
+-- Counting a post's reactions by type reads only this index
+CREATE INDEX IF NOT EXISTS idx_post_reactions_counts ON post_reactions(post_id, reaction);
@@ -0,0 +1,22 @@
+-- Restoring the foreign keys drops the audit entries and indexing jobs of
+-- deleted posts
+DROP TABLE IF EXISTS activity_logs_archive;
+
+DROP INDEX IF EXISTS idx_activity_logs_action;
+DROP INDEX IF EXISTS idx_activity_logs_actor;
+
+ALTER TABLE activity_logs
+    DROP COLUMN IF EXISTS actor,
+    DROP COLUMN IF EXISTS client_ip,
+    DROP COLUMN IF EXISTS before_data,
+    DROP COLUMN IF EXISTS after_data;
+
+DELETE FROM activity_logs WHERE post_id IS NOT NULL AND post_id NOT IN (SELECT id FROM posts);
+ALTER TABLE activity_logs DROP CONSTRAINT IF EXISTS activity_logs_post_id_fkey;
+ALTER TABLE activity_logs ADD CONSTRAINT activity_logs_post_id_fkey
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+
+DELETE FROM pending_index_jobs WHERE post_id NOT IN (SELECT id FROM posts);
+ALTER TABLE pending_index_jobs DROP CONSTRAINT IF EXISTS pending_index_jobs_post_id_fkey;
+ALTER TABLE pending_index_jobs ADD CONSTRAINT pending_index_jobs_post_id_fkey
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
@@ -0,0 +1,20 @@
+-- Audit trail: who changed a post, from where, and what it looked like
+-- before and after. Entries outlive the posts they describe, so the foreign
+-- key that deleted them with the post is dropped.
+ALTER TABLE activity_logs DROP CONSTRAINT IF EXISTS activity_logs_post_id_fkey;
+
+ALTER TABLE activity_logs
+    ADD COLUMN IF NOT EXISTS actor VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS before_data JSONB,
+    ADD COLUMN IF NOT EXISTS after_data JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_activity_logs_action ON activity_logs(action, logged_at);
+CREATE INDEX IF NOT EXISTS idx_activity_logs_actor ON activity_logs(actor, logged_at);
+
+-- Entries past the retention period are moved here when archiving is enabled
+CREATE TABLE IF NOT EXISTS activity_logs_archive (LIKE activity_logs INCLUDING INDEXES);
+
+-- Jobs of deleted posts are kept too: replaying one removes the post from
+-- Elasticsearch
+ALTER TABLE pending_index_jobs DROP CONSTRAINT IF EXISTS pending_index_jobs_post_id_fkey;
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS posts_notify_update ON posts;
+DROP TRIGGER IF EXISTS posts_notify_insert_delete ON posts;
+DROP TRIGGER IF EXISTS posts_touch ON posts;
+DROP FUNCTION IF EXISTS posts_notify();
+DROP FUNCTION IF EXISTS posts_touch();
+
+DROP TABLE IF EXISTS post_deletions;
+DROP INDEX IF EXISTS idx_posts_updated_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,65 @@
+-- Change data capture: posts changed outside the API, e.g. fixes made in
+-- psql, still reach the cache and Elasticsearch. Every change to a post's
+-- title, content or tags is announced on the posts_changed channel as
+-- {"op": "INSERT|UPDATE|DELETE", "id": 1, "at": "<updated_at or deleted_at>"}.
+-- updated_at and post_deletions let listeners catch up after a disconnect.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+UPDATE posts SET updated_at = COALESCE(created_at, NOW()) WHERE updated_at IS NULL;
+ALTER TABLE posts
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts(updated_at, id);
+
+-- Tombstones of deleted posts, pruned after a few days
+CREATE TABLE IF NOT EXISTS post_deletions (
+    post_id INTEGER PRIMARY KEY,
+    deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_deletions_deleted_at ON post_deletions(deleted_at, post_id);
+
+-- Only changes to what is cached and indexed count; comment counts do not
+CREATE OR REPLACE FUNCTION posts_touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION posts_notify() RETURNS trigger AS $$
+DECLARE
+    changed_id INTEGER;
+    changed_at TIMESTAMP;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO post_deletions (post_id, deleted_at) VALUES (OLD.id, NOW())
+        ON CONFLICT (post_id) DO UPDATE SET deleted_at = EXCLUDED.deleted_at
+        RETURNING deleted_at INTO changed_at;
+        changed_id := OLD.id;
+    ELSE
+        changed_id := NEW.id;
+        changed_at := NEW.updated_at;
+    END IF;
+    PERFORM pg_notify('posts_changed', json_build_object('op', TG_OP, 'id', changed_id, 'at', changed_at)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_touch ON posts;
+CREATE TRIGGER posts_touch BEFORE UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags)
+    EXECUTE FUNCTION posts_touch();
+
+DROP TRIGGER IF EXISTS posts_notify_insert_delete ON posts;
+CREATE TRIGGER posts_notify_insert_delete AFTER INSERT OR DELETE ON posts
+    FOR EACH ROW EXECUTE FUNCTION posts_notify();
+
+DROP TRIGGER IF EXISTS posts_notify_update ON posts;
+CREATE TRIGGER posts_notify_update AFTER UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags)
+    EXECUTE FUNCTION posts_notify();
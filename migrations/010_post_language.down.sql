@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS posts_notify_update ON posts;
+CREATE TRIGGER posts_notify_update AFTER UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags)
+    EXECUTE FUNCTION posts_notify();
+
+DROP TRIGGER IF EXISTS posts_touch ON posts;
+CREATE TRIGGER posts_touch BEFORE UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags)
+    EXECUTE FUNCTION posts_touch();
+
+DROP INDEX IF EXISTS idx_posts_language;
+ALTER TABLE posts DROP COLUMN IF EXISTS language;
@@ -0,0 +1,28 @@
+-- The language a post is written in selects the analyzers it is searched
+-- with. Posts written before languages were tracked are marked Vietnamese
+-- when they use letters only Vietnamese has; the API detects the language
+-- the same way for new posts that do not name one.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS language VARCHAR(8) NOT NULL DEFAULT 'en';
+
+UPDATE posts SET language = 'vi'
+WHERE language = 'en'
+  AND (title || ' ' || content) ~* '[ăắằẳẵặâấầẩẫậđêếềểễệôốồổỗộơớờởỡợưứừửữựạảẹẻẽịỉĩọỏụủũỳỵỷỹ]';
+
+CREATE INDEX IF NOT EXISTS idx_posts_language ON posts(language);
+
+-- A new language is reindexed like any other change
+DROP TRIGGER IF EXISTS posts_touch ON posts;
+CREATE TRIGGER posts_touch BEFORE UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags
+       OR OLD.language IS DISTINCT FROM NEW.language)
+    EXECUTE FUNCTION posts_touch();
+
+DROP TRIGGER IF EXISTS posts_notify_update ON posts;
+CREATE TRIGGER posts_notify_update AFTER UPDATE ON posts FOR EACH ROW
+    WHEN (OLD.title IS DISTINCT FROM NEW.title
+       OR OLD.content IS DISTINCT FROM NEW.content
+       OR OLD.tags IS DISTINCT FROM NEW.tags
+       OR OLD.language IS DISTINCT FROM NEW.language)
+    EXECUTE FUNCTION posts_notify();
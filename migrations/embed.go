@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations in the binary. Each
+// NNN_name.sql file has an optional NNN_name.down.sql that reverts it.
+package migrations
+
+import "embed"
+
+// FS holds every migration file
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,145 @@
+// Package client is a typed Go client for the blog API.
+//
+// The types and operations in client_gen.go are generated from the OpenAPI
+// document; run go generate ./pkg/client after changing routes or models.
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(os.Getenv("BLOG_API_KEY")))
+//	post, err := c.GetPost(ctx, 1)
+package client
+
+//go:generate go run ../../cmd/clientgen -o client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the blog API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	userID     string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests, e.g. to add timeouts
+// or tracing; http.DefaultClient is used otherwise
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey sends key in the Authorization header of every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithUserID sends userID in the X-User-ID header of every request. It names
+// the author of comments and reactions, and is only honoured when the server
+// trusts the caller as its proxy; otherwise the API key is the user.
+func WithUserID(userID string) Option {
+	return func(c *Client) {
+		c.userID = userID
+	}
+}
+
+// New returns a client for the API at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned for responses outside 2xx. Response holds the decoded
+// error body, including per-field errors for 422.
+type APIError struct {
+	StatusCode int
+	Response   ErrorResponse
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("blog api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Response.Error != "" {
+		msg += ": " + e.Response.Error
+	}
+	if e.Response.Position > 0 {
+		msg += fmt.Sprintf(" at position %d", e.Response.Position)
+	}
+	if e.Response.RequestID != "" {
+		msg += " (request " + e.Response.RequestID + ")"
+	}
+	return msg
+}
+
+// do sends a request with an optional JSON body and decodes a 2xx JSON
+// response into out when out is not nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("blog api: failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("blog api: failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	}
+	if c.userID != "" {
+		req.Header.Set("X-User-ID", c.userID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("blog api: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if json.Unmarshal(data, &apiErr.Response) != nil {
+			apiErr.Response.Error = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("blog api: failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,762 @@
+// Code generated by clientgen from the OpenAPI document. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Operations without a JSON response are not generated: GET /docs, GET /metrics, GET /openapi.json, GET /events
+
+// APIKey is the APIKey schema
+type APIKey struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ID          int        `json:"id"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedFrom *int       `json:"rotated_from,omitempty"`
+	Scopes      []string   `json:"scopes"`
+}
+
+// ActivityLog is the ActivityLog schema
+type ActivityLog struct {
+	Action   string        `json:"action"`
+	Actor    string        `json:"actor,omitempty"`
+	After    *PostSnapshot `json:"after,omitempty"`
+	Before   *PostSnapshot `json:"before,omitempty"`
+	ClientIp string        `json:"client_ip,omitempty"`
+	ID       int           `json:"id"`
+	LoggedAt time.Time     `json:"logged_at"`
+	PostID   int           `json:"post_id"`
+}
+
+// ActivityPage is the ActivityPage schema
+type ActivityPage struct {
+	Entries []ActivityLog `json:"entries"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+}
+
+// Comment is the Comment schema
+type Comment struct {
+	AuthorID  string     `json:"author_id,omitempty"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	ID        int        `json:"id"`
+	ParentID  *int       `json:"parent_id,omitempty"`
+	PostID    int        `json:"post_id"`
+	Replies   []Comment  `json:"replies,omitempty"`
+	Status    string     `json:"status"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// CommentPage is the CommentPage schema
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Page     int       `json:"page"`
+	PerPage  int       `json:"per_page"`
+	Total    int       `json:"total"`
+}
+
+// ComponentHealth is the ComponentHealth schema
+type ComponentHealth struct {
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+	Status    string  `json:"status"`
+}
+
+// ConsistencyReport is the ConsistencyReport schema
+type ConsistencyReport struct {
+	CacheChecked bool        `json:"cache_checked"`
+	CacheDrift   int         `json:"cache_drift"`
+	Checked      int         `json:"checked"`
+	Drift        []PostDrift `json:"drift"`
+	FinishedAt   time.Time   `json:"finished_at"`
+	NextAfterID  int         `json:"next_after_id,omitempty"`
+	Repair       bool        `json:"repair"`
+	Repaired     int         `json:"repaired"`
+	SearchDrift  int         `json:"search_drift"`
+	StartedAt    time.Time   `json:"started_at"`
+}
+
+// CreateAPIKeyRequest is the CreateAPIKeyRequest schema
+type CreateAPIKeyRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+}
+
+// CreateCommentRequest is the CreateCommentRequest schema
+type CreateCommentRequest struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+// CreatePostRequest is the CreatePostRequest schema
+type CreatePostRequest struct {
+	Content  string   `json:"content"`
+	Language string   `json:"language,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Title    string   `json:"title"`
+}
+
+// CreateWebhookRequest is the CreateWebhookRequest schema
+type CreateWebhookRequest struct {
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	URL    string   `json:"url"`
+}
+
+// CreatedAPIKey is the CreatedAPIKey schema
+type CreatedAPIKey struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ID          int        `json:"id"`
+	Key         string     `json:"key"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedFrom *int       `json:"rotated_from,omitempty"`
+	Scopes      []string   `json:"scopes"`
+}
+
+// ErrorResponse is the ErrorResponse schema
+type ErrorResponse struct {
+	Error     string       `json:"error"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	Position  int          `json:"position,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError is the FieldError schema
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// HealthReport is the HealthReport schema
+type HealthReport struct {
+	CheckedAt  time.Time                  `json:"checked_at"`
+	Components map[string]ComponentHealth `json:"components"`
+	Status     string                     `json:"status"`
+}
+
+// Liveness is the Liveness schema
+type Liveness struct {
+	Status string `json:"status"`
+}
+
+// LogLevel is the LogLevel schema
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// MessageResponse is the MessageResponse schema
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ModerateCommentRequest is the ModerateCommentRequest schema
+type ModerateCommentRequest struct {
+	Status string `json:"status"`
+}
+
+// Post is the Post schema
+type Post struct {
+	CommentCount int            `json:"comment_count"`
+	Content      string         `json:"content"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ID           int            `json:"id"`
+	Language     string         `json:"language"`
+	Reactions    map[string]int `json:"reactions"`
+	RelatedPosts []Related      `json:"related_posts,omitempty"`
+	Tags         []string       `json:"tags"`
+	Title        string         `json:"title"`
+}
+
+// PostDrift is the PostDrift schema
+type PostDrift struct {
+	Cache      string `json:"cache,omitempty"`
+	CacheHash  string `json:"cache_hash,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	PostID     int    `json:"post_id"`
+	Repaired   bool   `json:"repaired"`
+	Search     string `json:"search,omitempty"`
+	SearchHash string `json:"search_hash,omitempty"`
+}
+
+// PostEvent is the PostEvent schema
+type PostEvent struct {
+	Actor      string        `json:"actor"`
+	ID         string        `json:"id,omitempty"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Post       *PostSnapshot `json:"post,omitempty"`
+	PostID     int           `json:"post_id"`
+	Type       string        `json:"type"`
+}
+
+// PostReactions is the PostReactions schema
+type PostReactions struct {
+	PostID    int            `json:"post_id"`
+	Reactions map[string]int `json:"reactions"`
+}
+
+// PostSnapshot is the PostSnapshot schema
+type PostSnapshot struct {
+	Content       string   `json:"content"`
+	Language      string   `json:"language,omitempty"`
+	ReactionCount int      `json:"reaction_count,omitempty"`
+	Tags          []string `json:"tags"`
+	Title         string   `json:"title"`
+}
+
+// ReactionRequest is the ReactionRequest schema
+type ReactionRequest struct {
+	Type string `json:"type"`
+}
+
+// Related is the Related schema
+type Related struct {
+	ID    int      `json:"id"`
+	Tags  []string `json:"tags"`
+	Title string   `json:"title"`
+}
+
+// RotateAPIKeyRequest is the RotateAPIKeyRequest schema
+type RotateAPIKeyRequest struct {
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// SearchHit is the SearchHit schema
+type SearchHit struct {
+	Content       string     `json:"content,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	ID            int        `json:"id"`
+	Language      string     `json:"language,omitempty"`
+	ReactionCount int        `json:"reaction_count,omitempty"`
+	Score         float64    `json:"score,omitempty"`
+	Tags          []string   `json:"tags"`
+	Title         string     `json:"title"`
+}
+
+// SearchResponse is the SearchResponse schema
+type SearchResponse struct {
+	Posts []SearchHit `json:"posts"`
+	Total int         `json:"total"`
+}
+
+// TrendingPost is the TrendingPost schema
+type TrendingPost struct {
+	ID    int      `json:"id"`
+	Score float64  `json:"score"`
+	Tags  []string `json:"tags"`
+	Title string   `json:"title"`
+}
+
+// TrendingResponse is the TrendingResponse schema
+type TrendingResponse struct {
+	Posts  []TrendingPost `json:"posts"`
+	Window string         `json:"window"`
+}
+
+// UpdateCommentRequest is the UpdateCommentRequest schema
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// UpdatePostRequest is the UpdatePostRequest schema
+type UpdatePostRequest struct {
+	Content  string   `json:"content"`
+	Language string   `json:"language,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Title    string   `json:"title"`
+}
+
+// Webhook is the Webhook schema
+type Webhook struct {
+	CreatedAt time.Time `json:"created_at"`
+	Events    []string  `json:"events"`
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+}
+
+// WebhookDelivery is the WebhookDelivery schema
+type WebhookDelivery struct {
+	Attempts       int          `json:"attempts"`
+	CreatedAt      time.Time    `json:"created_at"`
+	DeliveredAt    *time.Time   `json:"delivered_at,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	Event          string       `json:"event"`
+	ID             int          `json:"id"`
+	NextAttemptAt  *time.Time   `json:"next_attempt_at,omitempty"`
+	Payload        WebhookEvent `json:"payload"`
+	ResponseStatus int          `json:"response_status,omitempty"`
+	Status         string       `json:"status"`
+	WebhookID      int          `json:"webhook_id"`
+}
+
+// WebhookDeliveryPage is the WebhookDeliveryPage schema
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Page       int               `json:"page"`
+	PerPage    int               `json:"per_page"`
+	Total      int               `json:"total"`
+}
+
+// WebhookEvent is the WebhookEvent schema
+type WebhookEvent struct {
+	OccurredAt time.Time     `json:"occurred_at"`
+	Post       *PostSnapshot `json:"post,omitempty"`
+	PostID     int           `json:"post_id"`
+	Type       string        `json:"type"`
+}
+
+// AddReaction calls POST /posts/{id}/reactions
+//
+// React to a post; reacting twice with the same type has no effect
+func (c *Client) AddReaction(ctx context.Context, id int, body ReactionRequest) (*PostReactions, error) {
+	var out PostReactions
+	if err := c.do(ctx, "POST", fmt.Sprintf("/posts/%d/reactions", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CheckConsistency calls POST /admin/consistency
+//
+// Compare posts with their search documents and cached entries, optionally repairing drift
+// Optional query parameters are omitted when zero.
+func (c *Client) CheckConsistency(ctx context.Context, afterID int, limit int, repair string) (*ConsistencyReport, error) {
+	query := url.Values{}
+	if afterID != 0 {
+		query.Set("after_id", strconv.Itoa(afterID))
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if repair != "" {
+		query.Set("repair", repair)
+	}
+	var out ConsistencyReport
+	if err := c.do(ctx, "POST", "/admin/consistency", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAPIKey calls POST /api-keys
+//
+// Create an API key; the key is only returned here
+func (c *Client) CreateAPIKey(ctx context.Context, body CreateAPIKeyRequest) (*CreatedAPIKey, error) {
+	var out CreatedAPIKey
+	if err := c.do(ctx, "POST", "/api-keys", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateComment calls POST /posts/{id}/comments
+//
+// Comment on a post or reply to a comment; the comment waits for moderation
+func (c *Client) CreateComment(ctx context.Context, id int, body CreateCommentRequest) (*Comment, error) {
+	var out Comment
+	if err := c.do(ctx, "POST", fmt.Sprintf("/posts/%d/comments", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePost calls POST /posts
+//
+// Create a post
+func (c *Client) CreatePost(ctx context.Context, body CreatePostRequest) (*Post, error) {
+	var out Post
+	if err := c.do(ctx, "POST", "/posts", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWebhook calls POST /webhooks
+//
+// Subscribe a URL to post events; deliveries are signed with the secret
+func (c *Client) CreateWebhook(ctx context.Context, body CreateWebhookRequest) (*Webhook, error) {
+	var out Webhook
+	if err := c.do(ctx, "POST", "/webhooks", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteComment calls DELETE /comments/{id}
+//
+// Delete your own comment, keeping a placeholder for its replies
+func (c *Client) DeleteComment(ctx context.Context, id int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/comments/%d", id), nil, nil, nil)
+}
+
+// DeletePost calls DELETE /posts/{id}
+//
+// Delete a post with its comments and reactions; its activity log is kept
+func (c *Client) DeletePost(ctx context.Context, id int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/posts/%d", id), nil, nil, nil)
+}
+
+// DeleteWebhook calls DELETE /webhooks/{id}
+//
+// Delete a webhook with its delivery history
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/webhooks/%d", id), nil, nil, nil)
+}
+
+// GetHealth calls GET /health
+//
+// Alias of /livez kept for existing probes
+func (c *Client) GetHealth(ctx context.Context) (*Liveness, error) {
+	var out Liveness
+	if err := c.do(ctx, "GET", "/health", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLiveness calls GET /livez
+//
+// Liveness probe; checks no dependencies
+func (c *Client) GetLiveness(ctx context.Context) (*Liveness, error) {
+	var out Liveness
+	if err := c.do(ctx, "GET", "/livez", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLogLevel calls GET /admin/log-level
+//
+// Get the current log level
+func (c *Client) GetLogLevel(ctx context.Context) (*LogLevel, error) {
+	var out LogLevel
+	if err := c.do(ctx, "GET", "/admin/log-level", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPost calls GET /posts/{id}
+//
+// Get a post with related posts, served from the cache when possible
+func (c *Client) GetPost(ctx context.Context, id int) (*Post, error) {
+	var out Post
+	if err := c.do(ctx, "GET", fmt.Sprintf("/posts/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReadiness calls GET /readyz
+//
+// Readiness probe; every dependency is up and the cache is warm
+func (c *Client) GetReadiness(ctx context.Context) (*HealthReport, error) {
+	var out HealthReport
+	if err := c.do(ctx, "GET", "/readyz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAPIKeys calls GET /api-keys
+//
+// List API keys without their secrets
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var out []APIKey
+	if err := c.do(ctx, "GET", "/api-keys", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListActivity calls GET /activity
+//
+// List the audit trail of changes to posts, newest first
+// Optional query parameters are omitted when zero.
+func (c *Client) ListActivity(ctx context.Context, action string, actor string, since string, until string, page int, perPage int) (*ActivityPage, error) {
+	query := url.Values{}
+	if action != "" {
+		query.Set("action", action)
+	}
+	if actor != "" {
+		query.Set("actor", actor)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if until != "" {
+		query.Set("until", until)
+	}
+	if page != 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage != 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	var out ActivityPage
+	if err := c.do(ctx, "GET", "/activity", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListComments calls GET /posts/{id}/comments
+//
+// List a post's approved top-level comments with their replies nested
+// Optional query parameters are omitted when zero.
+func (c *Client) ListComments(ctx context.Context, id int, page int, perPage int) (*CommentPage, error) {
+	query := url.Values{}
+	if page != 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage != 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	var out CommentPage
+	if err := c.do(ctx, "GET", fmt.Sprintf("/posts/%d/comments", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListModerationQueue calls GET /comments/moderation
+//
+// List comments with a moderation status, oldest first
+// Optional query parameters are omitted when zero.
+func (c *Client) ListModerationQueue(ctx context.Context, status string, page int, perPage int) (*CommentPage, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if page != 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage != 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	var out CommentPage
+	if err := c.do(ctx, "GET", "/comments/moderation", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPostActivity calls GET /posts/{id}/activity
+//
+// List the audit trail of one post, deleted posts included
+// Optional query parameters are omitted when zero.
+func (c *Client) ListPostActivity(ctx context.Context, id int, action string, actor string, since string, until string, page int, perPage int) (*ActivityPage, error) {
+	query := url.Values{}
+	if action != "" {
+		query.Set("action", action)
+	}
+	if actor != "" {
+		query.Set("actor", actor)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if until != "" {
+		query.Set("until", until)
+	}
+	if page != 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage != 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	var out ActivityPage
+	if err := c.do(ctx, "GET", fmt.Sprintf("/posts/%d/activity", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhookDeliveries calls GET /webhooks/{id}/deliveries
+//
+// List a webhook's deliveries, newest first
+// Optional query parameters are omitted when zero.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int, status string, page int, perPage int) (*WebhookDeliveryPage, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if page != 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if perPage != 0 {
+		query.Set("per_page", strconv.Itoa(perPage))
+	}
+	var out WebhookDeliveryPage
+	if err := c.do(ctx, "GET", fmt.Sprintf("/webhooks/%d/deliveries", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWebhooks calls GET /webhooks
+//
+// List webhooks without their secrets
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var out []Webhook
+	if err := c.do(ctx, "GET", "/webhooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ModerateComment calls PUT /comments/{id}/status
+//
+// Approve, reject or mark a comment as spam
+func (c *Client) ModerateComment(ctx context.Context, id int, body ModerateCommentRequest) (*Comment, error) {
+	var out Comment
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/comments/%d/status", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RedeliverWebhook calls POST /webhook-deliveries/{id}/redeliver
+//
+// Queue a delivery's event again as a new delivery
+func (c *Client) RedeliverWebhook(ctx context.Context, id int) (*WebhookDelivery, error) {
+	var out WebhookDelivery
+	if err := c.do(ctx, "POST", fmt.Sprintf("/webhook-deliveries/%d/redeliver", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveReaction calls DELETE /posts/{id}/reactions
+//
+// Take back your reaction to a post
+func (c *Client) RemoveReaction(ctx context.Context, id int, type_ string) error {
+	query := url.Values{}
+	query.Set("type", type_)
+	return c.do(ctx, "DELETE", fmt.Sprintf("/posts/%d/reactions", id), query, nil, nil)
+}
+
+// RevokeAPIKey calls DELETE /api-keys/{id}
+//
+// Revoke an API key immediately
+func (c *Client) RevokeAPIKey(ctx context.Context, id int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api-keys/%d", id), nil, nil, nil)
+}
+
+// RotateAPIKey calls POST /api-keys/{id}/rotate
+//
+// Replace an API key, optionally keeping the old one valid for a grace period
+func (c *Client) RotateAPIKey(ctx context.Context, id int, body *RotateAPIKeyRequest) (*CreatedAPIKey, error) {
+	var payload any
+	if body != nil {
+		payload = body
+	}
+	var out CreatedAPIKey
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api-keys/%d/rotate", id), nil, payload, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchPosts calls GET /posts/search
+//
+// Full-text search over titles and content
+// Optional query parameters are omitted when zero.
+func (c *Client) SearchPosts(ctx context.Context, q string, lang string, language string) (*SearchResponse, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	if lang != "" {
+		query.Set("lang", lang)
+	}
+	if language != "" {
+		query.Set("language", language)
+	}
+	var out SearchResponse
+	if err := c.do(ctx, "GET", "/posts/search", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchPostsByTag calls GET /posts/search-by-tag
+//
+// List posts with a tag
+func (c *Client) SearchPostsByTag(ctx context.Context, tag string) (*SearchResponse, error) {
+	query := url.Values{}
+	query.Set("tag", tag)
+	var out SearchResponse
+	if err := c.do(ctx, "GET", "/posts/search-by-tag", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetLogLevel calls PUT /admin/log-level
+//
+// Change the log level without a restart
+func (c *Client) SetLogLevel(ctx context.Context, body LogLevel) (*LogLevel, error) {
+	var out LogLevel
+	if err := c.do(ctx, "PUT", "/admin/log-level", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TrendingPosts calls GET /posts/trending
+//
+// Posts with the most recent views; newer views weigh more
+// Optional query parameters are omitted when zero.
+func (c *Client) TrendingPosts(ctx context.Context, window string, limit int) (*TrendingResponse, error) {
+	query := url.Values{}
+	if window != "" {
+		query.Set("window", window)
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out TrendingResponse
+	if err := c.do(ctx, "GET", "/posts/trending", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateComment calls PUT /comments/{id}
+//
+// Edit your own comment; it goes back to moderation
+func (c *Client) UpdateComment(ctx context.Context, id int, body UpdateCommentRequest) (*Comment, error) {
+	var out Comment
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/comments/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePost calls PUT /posts/{id}
+//
+// Update a post and invalidate its cache entry
+func (c *Client) UpdatePost(ctx context.Context, id int, body UpdatePostRequest) (*MessageResponse, error) {
+	var out MessageResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/posts/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}